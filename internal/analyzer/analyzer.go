@@ -68,6 +68,11 @@ func (a *StreamAnalyzer) ExtractMessagesFromReader(r io.Reader, headerType strin
 		if err != nil {
 			break
 		}
+		if th, ok := hdr.(utils.TrailerHeader); ok {
+			if err := th.ReadTrailer(r, payload); err != nil {
+				break
+			}
+		}
 
 		msg := iso8583.NewMessage(a.spec)
 		if err := msg.Unpack(payload); err == nil {
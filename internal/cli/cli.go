@@ -98,6 +98,7 @@ func (cli *CLI) registerAllCommands() {
 	cli.commands["scenarios"] = scenarioCmd
 
 	_ = cli.AddCommand(cli.factory.CreateRunScenarioCommand())
+	_ = cli.AddCommand(cli.factory.CreateRunSuiteCommand())
 	_ = cli.AddCommand(cli.factory.CreateInitSpecCommand())
 	_ = cli.AddCommand(cli.factory.CreateInitTxCommand())
 
@@ -291,8 +292,8 @@ func (cli *CLI) RunDirectCommand(subcommand string, args []string) error {
 		cmdObj := cmd.NewServerCommand(spec, routes, tcRepo)
 
 		subCmd := "start"
-		port := "9999"
-		headerType := "binary2"
+		port := "" // Mock listeners from the transaction file, or 9999
+		headerType := cfg.GetConfig().HeaderType("binary2")
 
 		if len(args) > 0 {
 			subCmd = strings.ToLower(args[0])
@@ -305,6 +306,10 @@ func (cli *CLI) RunDirectCommand(subcommand string, args []string) error {
 			cmdObj.ListRoutes()
 			return nil
 		}
+		if subCmd == "listeners" {
+			cmdObj.ListListeners()
+			return nil
+		}
 
 		if subCmd == "start" {
 			if len(args) > 1 {
@@ -317,6 +322,7 @@ func (cli *CLI) RunDirectCommand(subcommand string, args []string) error {
 				if s, err := utils.CreateSpecFromFile(args[3]); err == nil {
 					spec = s
 					cmdObj = cmd.NewServerCommand(spec, routes, tcRepo)
+					cmdObj.SetSpecPath(args[3])
 				}
 			}
 		} else {
@@ -329,10 +335,14 @@ func (cli *CLI) RunDirectCommand(subcommand string, args []string) error {
 				if s, err := utils.CreateSpecFromFile(args[2]); err == nil {
 					spec = s
 					cmdObj = cmd.NewServerCommand(spec, routes, tcRepo)
+					cmdObj.SetSpecPath(args[2])
 				}
 			}
 		}
 
+		if err := cmdObj.SetTLS(cfg.GetConfig().GetTLS()); err != nil {
+			return err
+		}
 		return cmdObj.RunDirectServer(port, headerType)
 	}
 
@@ -347,7 +357,7 @@ func (cli *CLI) RunDirectCommand(subcommand string, args []string) error {
 	if subcommand == "run-scenario" {
 		fs := flag.NewFlagSet("run-scenario", flag.ContinueOnError)
 		reportPath := fs.String("report", "", "Path to export the test report JSON")
-		lengthType := fs.String("length", "ascii4", "Connection length type (ascii4, binary2, bcd2, NAPS, visa)")
+		lengthType := fs.String("length", cfg.GetConfig().HeaderType("ascii4"), "Connection length type (ascii4, ebcdic4, binary2, bcd2, NAPS, visa)")
 		if err := fs.Parse(args); err != nil {
 			return err
 		}
@@ -367,6 +377,9 @@ func (cli *CLI) RunDirectCommand(subcommand string, args []string) error {
 			return fmt.Errorf("invalid length type '%s': %w", *lengthType, err)
 		}
 		naps := (*lengthType == "NAPS")
+		if err := cli.svc.ConfigureTLS(cfg.GetConfig().GetTLS()); err != nil {
+			return err
+		}
 		fmt.Printf("Connecting to server at %s...\n", cli.svc.Address)
 		if err := cli.svc.Connect(naps, header); err != nil {
 			return fmt.Errorf("failed to connect to server: %w", err)
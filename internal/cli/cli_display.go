@@ -43,7 +43,7 @@ func (cli *CLI) printHelp() {
 		},
 		{
 			category: "🧪 Scenario & Test Automation",
-			commands: []string{"scenario", "run-scenario"},
+			commands: []string{"scenario", "run-scenario", "run-suite"},
 		},
 		{
 			category: "⚙️ Embedded Mock Server Subsystem",
@@ -35,6 +35,20 @@ func (cli *CLI) InitService() error {
 	if err != nil {
 		return err
 	}
+	if err := svc.ConfigureCorrelation(cfg.GetConfig().GetCorrelationKey(), cfg.GetConfig().GetDuplicateKeyPolicy()); err != nil {
+		return err
+	}
+	if err := svc.ConfigurePool(cfg.GetConfig().GetPoolSize(), cfg.GetConfig().GetPoolStrategy()); err != nil {
+		return err
+	}
+	if err := svc.ConfigureFrames(cfg.GetConfig().GetMinMessageSize(), cfg.GetConfig().GetMaxMessageSize(), cfg.GetConfig().GetOnInvalidFrame()); err != nil {
+		return err
+	}
+	if err := svc.ConfigureReversal(cfg.GetConfig().GetAutoReversal(), cfg.GetConfig().GetReversalRepeats()); err != nil {
+		return err
+	}
+	svc.SetSecondaryTargets(cfg.GetConfig().GetSecondaryTargets())
+	svc.SetFailbackInterval(cfg.GetConfig().GetFailbackInterval())
 
 	cli.setService(svc)
 
@@ -54,6 +68,17 @@ func (cli *CLI) InitService() error {
 
 	cli.tc = tcInstance
 
+	// Session messages are composed from whichever collection is loaded at connect time
+	if err := svc.ConfigureSession(
+		cfg.GetConfig().GetSignOn(),
+		cfg.GetConfig().GetEchoTx(),
+		cfg.GetConfig().GetSignOff(),
+		cfg.GetConfig().GetEchoInterval(),
+		func(name string) (*iso8583.Message, error) { return cli.tc.Compose(name) },
+	); err != nil {
+		return err
+	}
+
 	// Initialize database
 	dbPath := cfg.GetConfig().GetDbPath()
 	if dbPath != "" {
@@ -88,6 +113,9 @@ func (cli *CLI) Connect(lengthType string) error {
 	}
 
 	naps := (lengthType == "NAPS")
+	if err := cli.svc.ConfigureTLS(cfg.GetConfig().GetTLS()); err != nil {
+		return err
+	}
 	return cli.svc.Connect(naps, header)
 }
 
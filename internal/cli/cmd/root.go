@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	cfg "jiso/internal/config"
@@ -73,6 +74,77 @@ func NewRootCmd() *cobra.Command {
 			if visaID, _ := cmd.Flags().GetString("visa-station-id"); visaID != "" {
 				c.SetVisaStationId(visaID)
 			}
+			tlsCfg := c.GetTLS()
+			if enabled, _ := cmd.Flags().GetBool("tls"); enabled {
+				tlsCfg.Enabled = true
+			}
+			if cert, _ := cmd.Flags().GetString("tls-cert"); cert != "" {
+				tlsCfg.CertFile = cert
+			}
+			if key, _ := cmd.Flags().GetString("tls-key"); key != "" {
+				tlsCfg.KeyFile = key
+			}
+			if ca, _ := cmd.Flags().GetString("tls-ca"); ca != "" {
+				tlsCfg.CAFile = ca
+			}
+			if serverName, _ := cmd.Flags().GetString("tls-server-name"); serverName != "" {
+				tlsCfg.ServerName = serverName
+			}
+			if insecure, _ := cmd.Flags().GetBool("tls-insecure"); insecure {
+				tlsCfg.InsecureSkipVerify = true
+			}
+			c.SetTLS(tlsCfg)
+			if key, _ := cmd.Flags().GetString("correlation-key"); key != "" {
+				c.SetCorrelationKey(key)
+			}
+			if policy, _ := cmd.Flags().GetString("on-duplicate-key"); policy != "" {
+				c.SetDuplicateKeyPolicy(policy)
+			}
+			if size, err := cmd.Flags().GetInt("pool-size"); err == nil && cmd.Flags().Changed("pool-size") {
+				c.SetPoolSize(size)
+			}
+			if strategy, _ := cmd.Flags().GetString("pool-strategy"); strategy != "" {
+				c.SetPoolStrategy(strategy)
+			}
+			if targets, _ := cmd.Flags().GetString("secondary-targets"); targets != "" {
+				c.SetSecondaryTargets(cfg.SplitTargets(targets))
+			}
+			if interval, err := cmd.Flags().GetDuration("failback-interval"); err == nil && cmd.Flags().Changed("failback-interval") {
+				c.SetFailbackInterval(interval)
+			}
+			if signOn, _ := cmd.Flags().GetString("sign-on"); signOn != "" {
+				c.SetSignOn(signOn)
+			}
+			if signOff, _ := cmd.Flags().GetString("sign-off"); signOff != "" {
+				c.SetSignOff(signOff)
+			}
+			if echoTx, _ := cmd.Flags().GetString("echo-tx"); echoTx != "" {
+				c.SetEchoTx(echoTx)
+			}
+			if interval, err := cmd.Flags().GetDuration("echo-interval"); err == nil && cmd.Flags().Changed("echo-interval") {
+				c.SetEchoInterval(interval)
+			}
+			if size, err := cmd.Flags().GetInt("min-message-size"); err == nil && cmd.Flags().Changed("min-message-size") {
+				c.SetMinMessageSize(size)
+			}
+			if size, err := cmd.Flags().GetInt("max-message-size"); err == nil && cmd.Flags().Changed("max-message-size") {
+				c.SetMaxMessageSize(size)
+			}
+			if policy, _ := cmd.Flags().GetString("on-invalid-frame"); policy != "" {
+				c.SetOnInvalidFrame(policy)
+			}
+			if policy, _ := cmd.Flags().GetString("auto-reversal"); policy != "" {
+				c.SetAutoReversal(policy)
+			}
+			if repeats, err := cmd.Flags().GetInt("reversal-repeats"); err == nil && cmd.Flags().Changed("reversal-repeats") {
+				c.SetReversalRepeats(repeats)
+			}
+			// The profile only fills what the other flags left unset
+			if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+				if err := c.SetProfile(profile); err != nil {
+					return err
+				}
+			}
 
 			return c.Validate()
 		},
@@ -93,6 +165,28 @@ func NewRootCmd() *cobra.Command {
 	pflags.Duration("total-connect-timeout", 10*time.Second, "Total timeout for connection establishment")
 	pflags.Duration("response-timeout", 5*time.Second, "Timeout waiting for async message responses")
 	pflags.String("visa-station-id", "", "VISA Local Station ID (6-digit hex or decimal)")
+	pflags.Bool("tls", false, "Use TLS for client connections")
+	pflags.String("tls-cert", "", "TLS certificate file (client certificate, or the mock server's certificate)")
+	pflags.String("tls-key", "", "TLS private key file for --tls-cert")
+	pflags.String("tls-ca", "", "CA bundle verifying the server (or, for the mock server, client certificates)")
+	pflags.String("tls-server-name", "", "TLS server name (SNI) sent on connect")
+	pflags.Bool("tls-insecure", false, "Skip TLS server certificate verification")
+	pflags.String("correlation-key", "", "Fields pairing responses with requests: stan, stan_terminal, stan_terminal_date, rrn or a list like 11+41+7")
+	pflags.String("on-duplicate-key", "", "What a send does when its correlation key is already pending: fail or queue")
+	pflags.Int("pool-size", 1, "Number of sockets opened to the target")
+	pflags.String("pool-strategy", "", "How requests are spread over pooled sockets: round_robin or least_pending")
+	pflags.String("secondary-targets", "", "Comma-separated standby host:port targets used when the primary fails")
+	pflags.Duration("failback-interval", 30*time.Second, "How often the primary is echo tested while on a secondary target (0 disables failback)")
+	pflags.String("sign-on", "", "Sign-on sent after every connect: auto for a built-in 0800 or a transaction name")
+	pflags.String("sign-off", "", "Sign-off sent before disconnect: auto for a built-in 0800 or a transaction name")
+	pflags.String("echo-tx", "", "Transaction sent as echo test (default: built-in 0800 with DE70 301)")
+	pflags.Duration("echo-interval", 0, "Idle time before an echo test keeps the link alive (0 disables echo tests)")
+	pflags.Int("min-message-size", 0, "Smallest message accepted from the target in bytes (default 20)")
+	pflags.Int("max-message-size", 0, "Largest message accepted from the target in bytes (default 8192)")
+	pflags.String("on-invalid-frame", "", "What happens to a frame outside the size limits: close (default) or skip, which hex-dumps and discards it")
+	pflags.String("auto-reversal", "", "Reversal sent for a request that gets no response: 0400, 0420 or off (default)")
+	pflags.Int("reversal-repeats", 0, "Repeats (0401/0421) sent while a reversal gets no response")
+	pflags.String("profile", "", "Network profile bundling header, spec, sign-on/echo and correlation key: "+strings.Join(cfg.ProfileNames(), ", "))
 
 	// Register subcommands
 	rootCmd.AddCommand(newSpecCmd())
@@ -22,6 +22,7 @@ func newScenarioCmd() *cobra.Command {
 
 	scenarioCmd.AddCommand(newScenarioListCmd())
 	scenarioCmd.AddCommand(newScenarioRunCmd())
+	scenarioCmd.AddCommand(newScenarioSuiteCmd())
 	return scenarioCmd
 }
 
@@ -67,6 +68,9 @@ func newScenarioRunCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			reportPath, _ := cmd.Flags().GetString("report")
 			lengthType, _ := cmd.Flags().GetString("length")
+			if !cmd.Flags().Changed("length") {
+				lengthType = cfg.GetConfig().HeaderType(lengthType)
+			}
 			scenarioName := ""
 			if len(args) > 0 {
 				scenarioName = args[0]
@@ -76,28 +80,98 @@ func newScenarioRunCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringP("report", "R", "", "Path to export the test report JSON")
-	cmd.Flags().StringP("length", "l", "ascii4", "Connection length type (ascii4, binary2, bcd2, NAPS, visa)")
+	cmd.Flags().StringP("length", "l", "ascii4", "Connection length type (ascii4, ebcdic4, binary2, bcd2, NAPS, visa; default from --profile)")
 	return cmd
 }
 
 func executeScenarioRun(scenarioName, reportPath, lengthType string) error {
+	var patterns []string
+	if scenarioName != "" {
+		patterns = []string{scenarioName}
+	}
+	tc, svc, err := connectScenarioService(lengthType, patterns, nil, 1)
+	if err != nil {
+		return err
+	}
+	defer disconnectScenarioService(svc)
+
+	runCmd := &cmdpkg.RunScenarioCommand{
+		Tc:           tc,
+		Svc:          svc,
+		ScenarioName: scenarioName,
+		ReportPath:   reportPath,
+	}
+
+	return runCmd.Execute()
+}
+
+func newScenarioSuiteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "run-suite [name or glob...]",
+		Aliases: []string{"suite"},
+		Short:   "Run the scenarios matching names, globs or tags, optionally in parallel",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tags, _ := cmd.Flags().GetStringSlice("tag")
+			parallel, _ := cmd.Flags().GetInt("parallel")
+			reportDir, _ := cmd.Flags().GetString("report-dir")
+			junitPath, _ := cmd.Flags().GetString("junit")
+			lengthType, _ := cmd.Flags().GetString("length")
+			if !cmd.Flags().Changed("length") {
+				lengthType = cfg.GetConfig().HeaderType(lengthType)
+			}
+			if parallel < 1 {
+				return fmt.Errorf("--parallel must be at least 1, got %d", parallel)
+			}
+
+			tc, svc, err := connectScenarioService(lengthType, args, tags, parallel)
+			if err != nil {
+				return err
+			}
+			defer disconnectScenarioService(svc)
+
+			suiteCmd := &cmdpkg.RunSuiteCommand{
+				Tc:        tc,
+				Svc:       svc,
+				Patterns:  args,
+				Tags:      tags,
+				Parallel:  parallel,
+				ReportDir: reportDir,
+				JUnitPath: junitPath,
+			}
+			return suiteCmd.Execute()
+		},
+	}
+
+	cmd.Flags().StringSlice("tag", nil, "Run only scenarios carrying one of these tags (repeatable or comma separated)")
+	cmd.Flags().IntP("parallel", "P", 1, "Number of scenarios run at a time")
+	cmd.Flags().String("report-dir", "", "Directory to export a JSON test report per scenario")
+	cmd.Flags().String("junit", "", "Path to export the suite as JUnit XML")
+	cmd.Flags().StringP("length", "l", "ascii4", "Connection length type (ascii4, ebcdic4, binary2, bcd2, NAPS, visa; default from --profile)")
+	return cmd
+}
+
+// connectScenarioService loads the configured spec and transaction file and connects
+// to the target with the configured session, pool and reversal settings, opening at
+// least sockets sockets so that scenarios run in parallel each get one. It does not
+// connect when every scenario the patterns and tags select runs against its own mock.
+func connectScenarioService(lengthType string, patterns, tags []string, sockets int) (*transactions.TransactionCollection, *service.Service, error) {
 	specPath := cfg.GetConfig().GetSpec()
 	txPath := cfg.GetConfig().GetFile()
 	if specPath == "" {
-		return errors.New("spec file is required (use -s or --spec)")
+		return nil, nil, errors.New("spec file is required (use -s or --spec)")
 	}
 	if txPath == "" {
-		return errors.New("transaction file is required (use -f or --file)")
+		return nil, nil, errors.New("transaction file is required (use -f or --file)")
 	}
 
 	spec, err := utils.CreateSpecFromFile(specPath)
 	if err != nil {
-		return fmt.Errorf("failed to load spec: %w", err)
+		return nil, nil, fmt.Errorf("failed to load spec: %w", err)
 	}
 
 	tc, err := transactions.NewTransactionCollection(txPath, spec)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	svc, err := service.NewService(
@@ -111,7 +185,30 @@ func executeScenarioRun(scenarioName, reportPath, lengthType string) error {
 		cfg.GetConfig().GetResponseTimeout(),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create service: %w", err)
+		return nil, nil, fmt.Errorf("failed to create service: %w", err)
+	}
+	if err := svc.ConfigureCorrelation(cfg.GetConfig().GetCorrelationKey(), cfg.GetConfig().GetDuplicateKeyPolicy()); err != nil {
+		return nil, nil, err
+	}
+	if err := svc.ConfigurePool(max(cfg.GetConfig().GetPoolSize(), sockets), cfg.GetConfig().GetPoolStrategy()); err != nil {
+		return nil, nil, err
+	}
+	if err := svc.ConfigureFrames(cfg.GetConfig().GetMinMessageSize(), cfg.GetConfig().GetMaxMessageSize(), cfg.GetConfig().GetOnInvalidFrame()); err != nil {
+		return nil, nil, err
+	}
+	if err := svc.ConfigureReversal(cfg.GetConfig().GetAutoReversal(), cfg.GetConfig().GetReversalRepeats()); err != nil {
+		return nil, nil, err
+	}
+	svc.SetSecondaryTargets(cfg.GetConfig().GetSecondaryTargets())
+	svc.SetFailbackInterval(cfg.GetConfig().GetFailbackInterval())
+	if err := svc.ConfigureSession(
+		cfg.GetConfig().GetSignOn(),
+		cfg.GetConfig().GetEchoTx(),
+		cfg.GetConfig().GetSignOff(),
+		cfg.GetConfig().GetEchoInterval(),
+		tc.Compose,
+	); err != nil {
+		return nil, nil, err
 	}
 
 	host := cfg.GetConfig().GetHost()
@@ -128,26 +225,30 @@ func executeScenarioRun(scenarioName, reportPath, lengthType string) error {
 
 	header, err := utils.SelectLength(lengthType)
 	if err != nil {
-		return fmt.Errorf("invalid length type '%s': %w", lengthType, err)
+		return nil, nil, fmt.Errorf("invalid length type '%s': %w", lengthType, err)
 	}
 	naps := (lengthType == "NAPS")
+	if err := svc.ConfigureTLS(cfg.GetConfig().GetTLS()); err != nil {
+		return nil, nil, err
+	}
+
+	if names, err := tc.SelectScenarios(patterns, tags); err == nil && tc.ScenariosMocked(names) {
+		return tc, svc, nil
+	}
 
 	fmt.Printf("Connecting to server at %s...\n", svc.Address)
 	if err := svc.Connect(naps, header); err != nil {
-		return fmt.Errorf("failed to connect to server: %w", err)
+		return nil, nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
-	defer func() {
-		if err := svc.Disconnect(); err != nil {
-			fmt.Printf("Warning: Disconnect error: %v\n", err)
-		}
-	}()
+	return tc, svc, nil
+}
 
-	runCmd := &cmdpkg.RunScenarioCommand{
-		Tc:           tc,
-		Svc:          svc,
-		ScenarioName: scenarioName,
-		ReportPath:   reportPath,
+// disconnectScenarioService disconnects from the target, if connectScenarioService connected
+func disconnectScenarioService(svc *service.Service) {
+	if !svc.IsConnected() {
+		return
+	}
+	if err := svc.Disconnect(); err != nil {
+		fmt.Printf("Warning: Disconnect error: %v\n", err)
 	}
-
-	return runCmd.Execute()
 }
@@ -23,6 +23,7 @@ func newServerCmd() *cobra.Command {
 
 	serverCmd.AddCommand(newServerStartCmd())
 	serverCmd.AddCommand(newServerRoutesCmd())
+	serverCmd.AddCommand(newServerListenersCmd())
 	return serverCmd
 }
 
@@ -33,14 +34,18 @@ func newServerStartCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			portFlag, _ := cmd.Flags().GetString("port")
 			headerFlag, _ := cmd.Flags().GetString("header")
+			watch, _ := cmd.Flags().GetBool("watch")
+			adminAddr, _ := cmd.Flags().GetString("admin")
+			clientAuth, _ := cmd.Flags().GetBool("tls-client-auth")
 
-			port := "9999"
-			headerType := "binary2"
+			// An empty port starts the mock listeners of the transaction file, if any
+			port := ""
+			headerType := cfg.GetConfig().HeaderType("binary2")
 
-			if portFlag != "" {
+			if cmd.Flags().Changed("port") {
 				port = portFlag
 			}
-			if headerFlag != "" {
+			if cmd.Flags().Changed("header") {
 				headerType = headerFlag
 			}
 
@@ -67,12 +72,15 @@ func newServerStartCmd() *cobra.Command {
 				}
 			}
 
-			return executeServerStart(port, headerType)
+			return executeServerStart(port, headerType, watch, adminAddr, clientAuth)
 		},
 	}
 
-	cmd.Flags().StringP("port", "p", "9999", "Port number to listen on")
-	cmd.Flags().StringP("header", "m", "binary2", "TCP header length type (binary2, ascii4, bcd2, NAPS, visa)")
+	cmd.Flags().StringP("port", "p", "9999", "Port number to listen on (without it, the file's mock listeners are started if defined)")
+	cmd.Flags().StringP("header", "m", "binary2", "TCP header length type (binary2, ascii4, ebcdic4, bcd2, NAPS, visa; default from --profile)")
+	cmd.Flags().String("admin", "", "Serve the HTTP admin API on this address (e.g. 127.0.0.1:8081)")
+	cmd.Flags().Bool("watch", true, "Reload routes and spec when the transaction or spec file changes")
+	cmd.Flags().Bool("tls-client-auth", false, "Require TLS clients to present a certificate signed by --tls-ca")
 	return cmd
 }
 
@@ -87,9 +95,17 @@ func newServerRoutesCmd() *cobra.Command {
 	}
 }
 
+func newServerListenersCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "listeners",
+		Short: "List mock listeners defined in the transaction file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return executeServerListeners()
+		},
+	}
+}
 
-
-func executeServerStart(port, headerType string) error {
+func executeServerStart(port, headerType string, watch bool, adminAddr string, clientAuth bool) error {
 	specPath := cfg.GetConfig().GetSpec()
 	txPath := cfg.GetConfig().GetFile()
 
@@ -114,6 +130,13 @@ func executeServerStart(port, headerType string) error {
 	}
 
 	cmdObj := cmdpkg.NewServerCommand(spec, routes, tcRepo)
+	cmdObj.SetWatch(watch)
+	cmdObj.SetAdminAddr(adminAddr)
+	tlsCfg := cfg.GetConfig().GetTLS()
+	tlsCfg.ClientAuth = clientAuth
+	if err := cmdObj.SetTLS(tlsCfg); err != nil {
+		return err
+	}
 	return cmdObj.RunDirectServer(port, headerType)
 }
 
@@ -146,6 +169,32 @@ func executeServerRoutes() error {
 	return nil
 }
 
+func executeServerListeners() error {
+	specPath := cfg.GetConfig().GetSpec()
+	txPath := cfg.GetConfig().GetFile()
+
+	spec := utils.GetDefaultSpec()
+	if specPath != "" {
+		if s, err := utils.CreateSpecFromFile(specPath); err == nil {
+			spec = s
+		}
+	}
+
+	var routes []cfg.MockRouteConfig
+	var tcRepo transactions.Repository
+	if txPath != "" {
+		tc, err := transactions.NewTransactionCollection(txPath, spec)
+		if err != nil {
+			return err
+		}
+		routes = tc.GetMockRoutes()
+		tcRepo = tc
+	}
+
+	cmdpkg.NewServerCommand(spec, routes, tcRepo).ListListeners()
+	return nil
+}
+
 func isNumeric(s string) bool {
 	if len(s) == 0 {
 		return false
@@ -100,6 +100,24 @@ func (cli *CLI) processCommand(line string) bool {
 			c.SessionID = ""
 		}
 	case *cmd.TargetCommand:
+		if len(args) > 1 && strings.EqualFold(args[1], "secondary") {
+			if err := c.SetSecondaries(args[2:]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			return false
+		}
+		if len(args) > 1 && strings.EqualFold(args[1], "failover") {
+			if err := c.Failover(); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			return false
+		}
+		if len(args) > 1 && strings.EqualFold(args[1], "tls") {
+			if err := c.SetTLS(args[2:]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			return false
+		}
 		if len(args) > 1 {
 			if err := c.SetTarget(args[1]); err != nil {
 				fmt.Printf("Error: %v\n", err)
@@ -179,4 +197,15 @@ func (cli *CLI) PrintWorkerStats() {
 	} else {
 		fmt.Println("  Networking stats not available")
 	}
+	if cli.svc != nil {
+		fmt.Printf("  %-30s: %v (key %s)\n", "duplicate_correlation_keys", cli.svc.DuplicateKeys(), cli.svc.GetCorrelationKey())
+	}
+
+	if cli.svc != nil && cli.svc.PoolSize() > 1 {
+		fmt.Printf("\nSockets (%d, %s):\n", cli.svc.PoolSize(), cli.svc.PoolStrategy())
+		for _, socket := range cli.svc.GetSocketStats() {
+			fmt.Printf("  #%-3d %-22s %-10s pending=%d sent=%d failed=%d reconnects=%d\n",
+				socket.Index, socket.Address, socket.Status, socket.Pending, socket.Sent, socket.Failed, socket.Reconnects)
+		}
+	}
 }
@@ -260,17 +260,27 @@ func (w *stressTestWorker) runStressTest(cli *CLI) {
 						}
 					}
 
-					// Circuit breaker: record trip if activated
-					if w.consecutiveFailures >= 10 {
+					// Circuit breaker: fail over and keep the load running on the next target,
+					// or stop the test when there is none
+					if w.consecutiveFailures >= circuitBreakerThreshold {
 						if w.networkStats != nil {
 							w.networkStats.RecordCircuitBreakerTrip()
 						}
-						fmt.Printf(
-							"\nStress test worker %s stopped due to %d consecutive failures\n",
-							w.id,
-							w.consecutiveFailures,
-						)
-						w.cancel() // Stop all other workers by canceling the context
+						if cli.failoverOnTrip() {
+							fmt.Printf(
+								"\nStress test worker %s failing over after %d consecutive failures\n",
+								w.id,
+								w.consecutiveFailures,
+							)
+							w.consecutiveFailures = 0
+						} else {
+							fmt.Printf(
+								"\nStress test worker %s stopped due to %d consecutive failures\n",
+								w.id,
+								w.consecutiveFailures,
+							)
+							w.cancel() // Stop all other workers by canceling the context
+						}
 					}
 					w.mu.Unlock()
 				}(name)
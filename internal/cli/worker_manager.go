@@ -13,6 +13,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// circuitBreakerThreshold is the number of consecutive failures that trips a worker's circuit breaker
+const circuitBreakerThreshold = 10
+
 // workerInfo holds the state of a background worker
 // Use a different name to avoid conflict with existing workerState
 type workerInfo struct {
@@ -85,18 +88,27 @@ func (cli *CLI) StartWorker(name string, count int, interval time.Duration) (str
 						worker.consecutiveFailures++
 					}
 
-					// Circuit breaker: record trip if activated
-					if worker.consecutiveFailures >= 10 {
+					// Circuit breaker: fail over to the next target if there is one, otherwise stop
+					if worker.consecutiveFailures >= circuitBreakerThreshold {
 						if worker.networkStats != nil {
 							worker.networkStats.RecordCircuitBreakerTrip()
 						}
-						fmt.Printf(
-							"Worker %s stopped due to %d consecutive failures\n",
-							worker.id,
-							worker.consecutiveFailures,
-						)
-						worker.mu.Unlock()
-						return
+						if cli.failoverOnTrip() {
+							fmt.Printf(
+								"Worker %s failing over after %d consecutive failures\n",
+								worker.id,
+								worker.consecutiveFailures,
+							)
+							worker.consecutiveFailures = 0
+						} else {
+							fmt.Printf(
+								"Worker %s stopped due to %d consecutive failures\n",
+								worker.id,
+								worker.consecutiveFailures,
+							)
+							worker.mu.Unlock()
+							return
+						}
 					}
 					worker.mu.Unlock()
 				}
@@ -109,6 +121,21 @@ func (cli *CLI) StartWorker(name string, count int, interval time.Duration) (str
 	return workerID, nil
 }
 
+// failoverOnTrip moves the connection to the next target of the group after a circuit breaker
+// trip. It reports false when there is no target to fail over to.
+func (cli *CLI) failoverOnTrip() bool {
+	if cli == nil || cli.svc == nil || !cli.svc.HasSecondaryTargets() {
+		return false
+	}
+	svc := cli.svc
+	go func() {
+		if err := svc.Failover("circuit breaker tripped"); err != nil {
+			fmt.Printf("Failover after circuit breaker trip failed: %v\n", err)
+		}
+	}()
+	return true
+}
+
 // StartStressTestWorker starts a stress test worker with TPS ramp-up
 func (cli *CLI) StartStressTestWorker(
 	names []string,
@@ -321,6 +348,12 @@ func (cli *CLI) GetWorkerStats() map[string]any {
 	defer cli.mu.Unlock()
 
 	stats := make(map[string]any)
+	if cli.svc != nil {
+		stats["sockets"] = cli.svc.GetSocketStats()
+		if netStats := cli.svc.GetNetworkingStats(); netStats != nil {
+			stats["target_switches"] = netStats.TargetSwitches()
+		}
+	}
 
 	totalWorkers := len(cli.workers) + len(cli.stressWorkers)
 	if totalWorkers == 0 {
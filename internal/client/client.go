@@ -19,6 +19,7 @@ type ClientConfig struct {
 	mu          sync.RWMutex
 	host        string
 	port        string
+	secondaries []string // Standby "host:port" targets, in failover order
 	reconnector ReconnectNotifier
 	lastSwapped time.Time
 }
@@ -69,6 +70,30 @@ func (c *ClientConfig) SetTarget(target string) error {
 	return nil
 }
 
+// GetSecondaries returns the standby targets in failover order
+func (c *ClientConfig) GetSecondaries() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.secondaries...)
+}
+
+// SetSecondaries replaces the standby targets; each entry is parsed like SetTarget
+func (c *ClientConfig) SetSecondaries(targets []string) error {
+	secondaries := make([]string, 0, len(targets))
+	for _, target := range targets {
+		host, port, err := parseTargetAddress(target)
+		if err != nil {
+			return fmt.Errorf("secondary target '%s': %w", target, err)
+		}
+		secondaries = append(secondaries, fmt.Sprintf("%s:%s", host, port))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.secondaries = secondaries
+	return nil
+}
+
 // SetReconnector binds a ReconnectNotifier callback
 func (c *ClientConfig) SetReconnector(r ReconnectNotifier) {
 	c.mu.Lock()
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -79,7 +80,7 @@ func (ac *AnalyzeCommand) promptAnalyze() error {
 	var headerType string
 	headerPrompt := &survey.Select{
 		Message: "3. Select TCP Header Length Type:",
-		Options: []string{"ascii4", "binary2", "binary4", "bcd2", "NAPS", "visa"},
+		Options: append(slices.Clone(config.HeaderTypes), utils.FramingNames()...),
 		Default: "binary2",
 	}
 	if err := survey.AskOne(headerPrompt, &headerType); err != nil {
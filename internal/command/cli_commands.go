@@ -6,6 +6,9 @@ import (
 	"os"
 	"strings"
 
+	"jiso/internal/connection"
+	"jiso/internal/metrics"
+
 	"github.com/olekukonko/tablewriter"
 )
 
@@ -85,6 +88,11 @@ func (c *StatsCommand) Execute() error {
 	stats := c.Ctrl.GetWorkerStats()
 	fmt.Printf("Active workers: %v\n", stats["active"])
 
+	sockets, _ := stats["sockets"].([]connection.SocketStats)
+	switches, _ := stats["target_switches"].([]metrics.TargetSwitch)
+	defer printTargetSwitches(switches)
+	defer printSocketStats(sockets)
+
 	workers, ok := stats["workers"].([]map[string]interface{})
 	if !ok || len(workers) == 0 {
 		fmt.Println("No active workers")
@@ -122,6 +130,42 @@ func (c *StatsCommand) Execute() error {
 	return nil
 }
 
+// printSocketStats renders one row per pooled socket
+func printSocketStats(sockets []connection.SocketStats) {
+	if len(sockets) == 0 {
+		return
+	}
+	fmt.Println("\nSockets:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header("#", "Address", "Status", "Pending", "Sent / Failed", "Reconnects")
+	for _, socket := range sockets {
+		table.Append([]string{
+			fmt.Sprintf("%d", socket.Index),
+			socket.Address,
+			socket.Status,
+			fmt.Sprintf("%d", socket.Pending),
+			fmt.Sprintf("%d / %d", socket.Sent, socket.Failed),
+			fmt.Sprintf("%d", socket.Reconnects),
+		})
+	}
+	table.Render()
+}
+
+// printTargetSwitches lists failovers and failbacks between targets of the group
+func printTargetSwitches(switches []metrics.TargetSwitch) {
+	if len(switches) == 0 {
+		return
+	}
+	fmt.Println("\nTarget switches:")
+	for _, sw := range switches {
+		kind := "failover"
+		if sw.Failback {
+			kind = "failback"
+		}
+		fmt.Printf("  %s %-8s %s -> %s (%s)\n", sw.Time.Format("15:04:05"), kind, sw.From, sw.To, sw.Reason)
+	}
+}
+
 // StopAllCommand stops all running background worker threads
 type StopAllCommand struct {
 	Ctrl CLIController
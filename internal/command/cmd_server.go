@@ -2,10 +2,14 @@ package command
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"jiso/internal/config"
 	"jiso/internal/server"
@@ -18,28 +22,56 @@ import (
 
 // ServerCommand manages the embedded ISO8583 mock server from REPL or CLI
 type ServerCommand struct {
-	srv    *server.Server
-	spec   *iso8583.MessageSpec
-	routes []config.MockRouteConfig
-	tc     transactions.Repository
-	args   []string
+	listeners []*mockListener // Running ports: one unnamed server, or the configured mock listeners
+	admin     *http.Server    // Admin API shared by the mock listeners
+	spec      *iso8583.MessageSpec
+	routes    []config.MockRouteConfig
+	tc        transactions.Repository
+	args      []string
+	profile   string // Profile given as "serve start --profile <name>"
+	specPath  string // Spec file reloaded by "serve reload" and the file watcher
+	txPath    string // Transaction file holding the mock routes
+	watch     bool
+	watcher   *server.FileWatcher
+	adminAddr string // HTTP control API address, empty to disable
+	tls       config.TLSConfig
+	reloadMu  sync.Mutex
 }
 
+// mockListener is one running port of the mock server
+type mockListener struct {
+	name     string // Empty for a single-port server started with an explicit port
+	specPath string // Listener spec file, empty to use the server-wide spec
+	srv      *server.Server
+}
+
+// reloadPollInterval is how often watched spec and route files are checked for changes
+const reloadPollInterval = time.Second
+
 func (sc *ServerCommand) Name() string { return "serve" }
 func (sc *ServerCommand) Synopsis() string {
-	return "Manage embedded ISO8583 mock server (serve start [port] [headerType], serve stop, routes list)"
+	return "Manage embedded ISO8583 mock server (serve start [port] [headerType] [--profile <name>], serve stop, serve reload, routes list, listeners)"
 }
 
 func (sc *ServerCommand) SetArgs(args []string) {
-	sc.args = args
+	sc.profile, sc.args = splitProfileArg(args)
 }
 
 func (sc *ServerCommand) Execute() error {
+	if sc.profile != "" {
+		if err := sc.useProfile(sc.profile); err != nil {
+			return err
+		}
+	}
+
 	if len(sc.args) == 0 {
 		var action string
 		options := []string{"Start Server", "List Routes"}
-		if sc.srv != nil && sc.srv.IsRunning() {
-			options = []string{"Stop Server", "Server Statistics", "List Routes", "Restart Server"}
+		if len(sc.listenerConfigs()) > 0 {
+			options = []string{"Start Listeners", "Start Server", "List Listeners", "List Routes"}
+		}
+		if sc.isRunning() {
+			options = []string{"Stop Server", "Server Statistics", "List Routes", "Reload Routes", "Restart Server"}
 		}
 
 		prompt := &survey.Select{
@@ -52,10 +84,15 @@ func (sc *ServerCommand) Execute() error {
 
 		switch action {
 		case "Start Server", "Restart Server":
-			if sc.srv != nil && sc.srv.IsRunning() {
+			if sc.isRunning() {
 				_ = sc.StopServer()
 			}
 			return sc.promptStartServer()
+		case "Start Listeners":
+			return sc.StartListeners()
+		case "List Listeners":
+			sc.ListListeners()
+			return nil
 		case "Stop Server":
 			return sc.StopServer()
 		case "Server Statistics":
@@ -64,6 +101,8 @@ func (sc *ServerCommand) Execute() error {
 		case "List Routes":
 			sc.ListRoutes()
 			return nil
+		case "Reload Routes":
+			return sc.Reload()
 		}
 		return nil
 	}
@@ -83,8 +122,15 @@ func (sc *ServerCommand) Execute() error {
 			specPath := sc.args[3]
 			if loadedSpec, err := utils.CreateSpecFromFile(specPath); err == nil {
 				sc.spec = loadedSpec
+				sc.specPath = specPath
 			}
 		}
+		if port == "" && len(sc.listenerConfigs()) > 0 {
+			return sc.StartListeners()
+		}
+		if headerType == "" {
+			headerType = config.GetConfig().HeaderType("")
+		}
 		if port == "" || headerType == "" {
 			return sc.promptStartServer()
 		}
@@ -101,49 +147,107 @@ func (sc *ServerCommand) Execute() error {
 		sc.ListRoutes()
 		return nil
 
+	case "reload":
+		return sc.Reload()
+
+	case "push":
+		if len(sc.args) < 2 {
+			return fmt.Errorf("usage: serve push <outbound-name> [connection-id]")
+		}
+		connID := ""
+		if len(sc.args) > 2 {
+			connID = sc.args[2]
+		}
+		return sc.Push(sc.args[1], connID)
+
+	case "outbound":
+		sc.ListOutbound()
+		return nil
+
+	case "listeners":
+		sc.ListListeners()
+		return nil
+
+	case "tls":
+		tlsCfg, err := ParseTLSOptions(sc.args[1:], sc.tls)
+		if err != nil {
+			return err
+		}
+		if err := sc.SetTLS(tlsCfg); err != nil {
+			return err
+		}
+		fmt.Println(describeTLS(tlsCfg) + " (applies from the next start)")
+		return nil
+
+	case "admin":
+		if len(sc.args) < 2 {
+			return fmt.Errorf("usage: serve admin <host:port>")
+		}
+		return sc.StartAdmin(sc.args[1])
+
+	case "balance":
+		if len(sc.args) < 2 {
+			return fmt.Errorf("usage: serve balance <account>")
+		}
+		return sc.PrintBalance(sc.args[1])
+
 	default:
-		return fmt.Errorf("unknown server command '%s'. Available: start [port] [headerType] [specPath], stop, stats, routes", subCmd)
+		return fmt.Errorf("unknown server command '%s'. Available: start [port] [headerType] [specPath], stop, stats, routes, listeners, tls [cert=..] [key=..] [ca=..] [client_auth] | off, reload, push <name> [conn], outbound, admin <host:port>, balance <account>", subCmd)
 	}
 }
 
 func (sc *ServerCommand) PrintStats() {
-	if sc.srv == nil || !sc.srv.IsRunning() {
+	if !sc.isRunning() {
 		fmt.Println("Mock server is currently stopped")
 		return
 	}
-	stats := sc.srv.GetStats()
-	stats.PrintSummary(sc.srv.GetPort(), sc.srv.GetHeaderType(), sc.srv.ActiveConnections())
+	for _, l := range sc.listeners {
+		if l.name != "" {
+			fmt.Printf("\nListener: %s\n", l.name)
+		}
+		l.srv.GetStats().PrintSummary(l.srv.GetPort(), l.srv.GetHeaderType(), l.srv.ActiveConnections())
+	}
 }
 
-func (sc *ServerCommand) promptStartServer() error {
-	// 1. Select Specification File
-	specFiles := utils.FindAvailableSpecFiles()
-	var selectedSpec string
-	specPrompt := &survey.Select{
-		Message: "Select ISO8583 Specification File:",
-		Options: specFiles,
-		Default: specFiles[0],
+// PrintBalance displays the mock ledger balance of an account
+func (sc *ServerCommand) PrintBalance(account string) error {
+	if !sc.isRunning() {
+		return fmt.Errorf("mock server is not running")
 	}
-	if err := survey.AskOne(specPrompt, &selectedSpec); err != nil {
-		return err
+	available, held, ok := sc.listeners[0].srv.GetStateStore().Balance(account)
+	if !ok {
+		return fmt.Errorf("account %s not found in mock ledger", account)
 	}
+	fmt.Printf("Account %s: Available %d | Held %d\n", account, available, held)
+	return nil
+}
 
-	if selectedSpec == "Custom Path..." {
-		inputPrompt := &survey.Input{
-			Message: "Enter path to specification JSON file:",
-		}
-		if err := survey.AskOne(inputPrompt, &selectedSpec); err != nil {
-			return err
+// useProfile selects a network profile. Its spec replaces the server spec unless a spec
+// was given explicitly.
+func (sc *ServerCommand) useProfile(name string) error {
+	c := config.GetConfig()
+	specBefore := c.GetSpec()
+	if err := c.SetProfile(name); err != nil {
+		return err
+	}
+	if specPath := c.GetSpec(); specPath != specBefore {
+		loadedSpec, err := utils.CreateSpecFromFile(specPath)
+		if err != nil {
+			return fmt.Errorf("failed to load %s profile spec '%s': %w", name, specPath, err)
 		}
+		sc.spec = loadedSpec
+		sc.specPath = specPath
 	}
+	return nil
+}
 
-	if selectedSpec != "" && !strings.HasPrefix(selectedSpec, "[Default") {
-		if loadedSpec, err := utils.CreateSpecFromFile(selectedSpec); err == nil {
-			sc.spec = loadedSpec
-			fmt.Printf("Loaded spec from: %s\n", selectedSpec)
-		} else {
-			fmt.Printf("Warning: Failed to load spec from '%s' (%v), using default spec\n", selectedSpec, err)
-		}
+func (sc *ServerCommand) promptStartServer() error {
+	// A network profile fixes the spec and header type
+	headerType := config.GetConfig().HeaderType("")
+	if headerType != "" {
+		fmt.Printf("Using %s profile with %s header\n", config.GetConfig().GetProfile(), headerType)
+	} else if err := sc.promptSpec(); err != nil {
+		return err
 	}
 
 	// 1b. Select Transaction File (containing Mock Routes)
@@ -152,6 +256,7 @@ func (sc *ServerCommand) promptStartServer() error {
 		if tcLoaded, err := transactions.NewTransactionCollection(txPath, sc.spec); err == nil && tcLoaded != nil {
 			sc.routes = tcLoaded.GetMockRoutes()
 			sc.tc = tcLoaded
+			sc.txPath = txPath
 			fmt.Printf("   ✓ Loaded %d mock route(s) from: %s\n", len(sc.routes), txPath)
 		} else {
 			fmt.Printf("   ⚠️ Warning: Failed to load mock routes from '%s': %v\n", txPath, err)
@@ -169,22 +274,62 @@ func (sc *ServerCommand) promptStartServer() error {
 	}
 
 	// 3. Select TCP Header Type
-	var headerType string
-	headerPrompt := &survey.Select{
-		Message: "Select TCP header type:",
-		Options: []string{"ascii4", "binary2", "binary4", "bcd2", "NAPS", "visa"},
-		Default: "binary2",
+	if headerType == "" {
+		headerPrompt := &survey.Select{
+			Message: "Select TCP header type:",
+			Options: append(slices.Clone(config.HeaderTypes), utils.FramingNames()...),
+			Default: "binary2",
+		}
+		if err := survey.AskOne(headerPrompt, &headerType); err != nil {
+			return err
+		}
 	}
-	if err := survey.AskOne(headerPrompt, &headerType); err != nil {
+
+	return sc.StartServer(port, headerType)
+}
+
+// promptSpec asks for the specification file requests are parsed with
+func (sc *ServerCommand) promptSpec() error {
+	specFiles := utils.FindAvailableSpecFiles()
+	var selectedSpec string
+	specPrompt := &survey.Select{
+		Message: "Select ISO8583 Specification File:",
+		Options: specFiles,
+		Default: specFiles[0],
+	}
+	if err := survey.AskOne(specPrompt, &selectedSpec); err != nil {
 		return err
 	}
 
-	return sc.StartServer(port, headerType)
+	if selectedSpec == "Custom Path..." {
+		inputPrompt := &survey.Input{
+			Message: "Enter path to specification JSON file:",
+		}
+		if err := survey.AskOne(inputPrompt, &selectedSpec); err != nil {
+			return err
+		}
+	}
+
+	if selectedSpec != "" && !strings.HasPrefix(selectedSpec, "[Default") {
+		if loadedSpec, err := utils.CreateSpecFromFile(selectedSpec); err == nil {
+			sc.spec = loadedSpec
+			sc.specPath = selectedSpec
+			fmt.Printf("Loaded spec from: %s\n", selectedSpec)
+		} else {
+			fmt.Printf("Warning: Failed to load spec from '%s' (%v), using default spec\n", selectedSpec, err)
+		}
+	}
+	return nil
 }
 
-// RunDirectServer blocks in direct CLI mode until Ctrl+C (SIGINT/SIGTERM)
+// RunDirectServer blocks in direct CLI mode until Ctrl+C (SIGINT/SIGTERM). Without a port the
+// mock listeners of the transaction file are started, falling back to a single server on 9999.
 func (sc *ServerCommand) RunDirectServer(port string, headerType string) error {
-	if err := sc.StartServer(port, headerType); err != nil {
+	start := func() error { return sc.StartServer(port, headerType) }
+	if strings.TrimSpace(port) == "" && len(sc.listenerConfigs()) > 0 {
+		start = sc.StartListeners
+	}
+	if err := start(); err != nil {
 		return err
 	}
 
@@ -201,12 +346,39 @@ func (sc *ServerCommand) RunDirectServer(port string, headerType string) error {
 // NewServerCommand creates a new ServerCommand instance
 func NewServerCommand(spec *iso8583.MessageSpec, routes []config.MockRouteConfig, tc transactions.Repository) *ServerCommand {
 	return &ServerCommand{
-		spec:   spec,
-		routes: routes,
-		tc:     tc,
+		spec:     spec,
+		routes:   routes,
+		tc:       tc,
+		specPath: config.GetConfig().GetSpec(),
+		txPath:   config.GetConfig().GetFile(),
+		watch:    true,
 	}
 }
 
+// SetSpecPath overrides the spec file used when reloading
+func (sc *ServerCommand) SetSpecPath(path string) {
+	sc.specPath = path
+}
+
+// SetAdminAddr sets the address of the HTTP control API started with the server
+func (sc *ServerCommand) SetAdminAddr(addr string) {
+	sc.adminAddr = addr
+}
+
+// SetTLS sets the certificate, key and client CA the server listens with. Inactive settings select plain TCP.
+func (sc *ServerCommand) SetTLS(tlsCfg config.TLSConfig) error {
+	if _, err := utils.ServerTLSConfig(tlsCfg); err != nil {
+		return err
+	}
+	sc.tls = tlsCfg
+	return nil
+}
+
+// SetWatch enables or disables automatic reload when the spec or transaction file changes
+func (sc *ServerCommand) SetWatch(watch bool) {
+	sc.watch = watch
+}
+
 // StartServer starts the embedded mock server on the requested port with chosen header format
 func (sc *ServerCommand) StartServer(port string, headerType string) error {
 	port = strings.TrimSpace(port)
@@ -215,51 +387,498 @@ func (sc *ServerCommand) StartServer(port string, headerType string) error {
 	}
 	headerType = strings.TrimSpace(headerType)
 	if headerType == "" {
-		headerType = "binary2"
+		headerType = config.GetConfig().HeaderType("binary2")
+	}
+
+	if sc.isRunning() {
+		return fmt.Errorf("mock server is already running on port %s", sc.listeners[0].srv.GetPort())
+	}
+
+	if _, err := utils.SelectServerHeader(headerType); err != nil {
+		return err
 	}
 
-	if sc.srv != nil && sc.srv.IsRunning() {
-		return fmt.Errorf("mock server is already running on port %s", sc.srv.GetPort())
+	tlsConfig, err := utils.ServerTLSConfig(sc.tls)
+	if err != nil {
+		return err
+	}
+	srv := server.NewServer(sc.spec, sc.routes, headerType)
+	srv.SetOutbound(sc.outbound())
+	srv.SetTLSConfig(tlsConfig)
+	if err := srv.Start(port); err != nil {
+		return err
+	}
+	sc.listeners = []*mockListener{{srv: srv}}
+	sc.seedState()
+
+	fmt.Printf("Embedded ISO8583 Mock Server started on port %s (Header: %s%s) 🟢\n", port, headerType, tlsSuffix(tlsConfig != nil))
+	return sc.afterStart()
+}
+
+// StartListeners starts every mock listener defined in the transaction file, each on its own
+// port with its own header format, spec, routes and statistics. The account ledger is shared.
+func (sc *ServerCommand) StartListeners() error {
+	if sc.isRunning() {
+		return fmt.Errorf("mock server is already running")
+	}
+	defs := sc.listenerConfigs()
+	if len(defs) == 0 {
+		return fmt.Errorf("no mock listeners defined in the transaction file")
 	}
 
-	sc.srv = server.NewServer(sc.spec, sc.routes, headerType)
-	if err := sc.srv.Start(port); err != nil {
+	tlsConfig, err := utils.ServerTLSConfig(sc.tls)
+	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Embedded ISO8583 Mock Server started on port %s (Header: %s) 🟢\n", port, headerType)
+	var store server.StateStore
+	for _, d := range defs {
+		spec, err := sc.listenerSpec(d.Spec)
+		if err != nil {
+			sc.stopListeners()
+			return fmt.Errorf("listener '%s': %w", d.Name, err)
+		}
+		frames, err := server.ListenerFrameLimits(d)
+		if err != nil {
+			sc.stopListeners()
+			return fmt.Errorf("listener '%s': %w", d.Name, err)
+		}
+		srv := server.NewServer(spec, sc.routesFor(d.Name), d.Header)
+		srv.SetOutbound(sc.outboundFor(d.Name))
+		srv.SetTLSConfig(tlsConfig)
+		srv.SetFrameLimits(frames)
+		if store == nil {
+			store = srv.GetStateStore()
+		} else {
+			srv.SetStateStore(store)
+		}
+		if err := srv.Start(d.Port); err != nil {
+			sc.stopListeners()
+			return fmt.Errorf("listener '%s': %w", d.Name, err)
+		}
+		sc.listeners = append(sc.listeners, &mockListener{name: d.Name, specPath: d.Spec, srv: srv})
+		fmt.Printf("Mock listener '%s' started on port %s (Header: %s%s) 🟢\n", d.Name, d.Port, srv.GetHeaderType(), tlsSuffix(tlsConfig != nil))
+	}
+	sc.seedState()
+	return sc.afterStart()
+}
+
+func tlsSuffix(enabled bool) string {
+	if enabled {
+		return ", TLS"
+	}
+	return ""
+}
+
+// afterStart starts the file watcher and admin API once the listeners are up
+func (sc *ServerCommand) afterStart() error {
+	sc.startWatcher()
+	if sc.adminAddr != "" {
+		if err := sc.StartAdmin(sc.adminAddr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sc *ServerCommand) isRunning() bool {
+	return len(sc.listeners) > 0 && sc.listeners[0].srv.IsRunning()
+}
+
+// listenerConfigs returns the mock listeners defined in the transaction file
+func (sc *ServerCommand) listenerConfigs() []config.MockListenerConfig {
+	tcImpl, ok := sc.tc.(*transactions.TransactionCollection)
+	if !ok || tcImpl == nil {
+		return nil
+	}
+	return tcImpl.GetMockListeners()
+}
+
+// listenerSpec loads a listener's own spec file, or returns the server-wide spec when path is empty
+func (sc *ServerCommand) listenerSpec(path string) (*iso8583.MessageSpec, error) {
+	if path == "" {
+		return sc.spec, nil
+	}
+	spec, err := utils.CreateSpecFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("spec '%s': %w", path, err)
+	}
+	return spec, nil
+}
+
+// routesFor returns the routes served by the named listener; the unnamed server serves them all
+func (sc *ServerCommand) routesFor(name string) []config.MockRouteConfig {
+	if name == "" {
+		return sc.routes
+	}
+	var routes []config.MockRouteConfig
+	for _, r := range sc.routes {
+		if config.ForListener(r.Listener, name) {
+			routes = append(routes, r)
+		}
+	}
+	return routes
+}
+
+// outboundFor returns the server-initiated messages sent by the named listener
+func (sc *ServerCommand) outboundFor(name string) []config.MockOutboundConfig {
+	if name == "" {
+		return sc.outbound()
+	}
+	var msgs []config.MockOutboundConfig
+	for _, o := range sc.outbound() {
+		if config.ForListener(o.Listener, name) {
+			msgs = append(msgs, o)
+		}
+	}
+	return msgs
+}
+
+// StartAdmin exposes the HTTP control API of the running mock server on addr
+func (sc *ServerCommand) StartAdmin(addr string) error {
+	if !sc.isRunning() {
+		return fmt.Errorf("mock server is not running")
+	}
+	if len(sc.listeners) == 1 && sc.listeners[0].name == "" {
+		if err := sc.listeners[0].srv.StartAdmin(addr); err != nil {
+			return fmt.Errorf("failed to start admin API: %w", err)
+		}
+	} else {
+		servers := make(map[string]*server.Server, len(sc.listeners))
+		for _, l := range sc.listeners {
+			servers[l.name] = l.srv
+		}
+		srv, err := server.ServeAdmin(addr, server.ListenerAdminHandler(servers))
+		if err != nil {
+			return fmt.Errorf("failed to start admin API: %w", err)
+		}
+		server.StopAdmin(sc.admin)
+		sc.admin = srv
+	}
+	fmt.Printf("Mock server admin API listening on http://%s 🛠️\n", addr)
+	return nil
+}
+
+// Reload re-reads the spec and transaction files and swaps the running server's routes
+// without closing the listener or open connections. On error the previous routes stay active.
+func (sc *ServerCommand) Reload() error {
+	sc.reloadMu.Lock()
+	defer sc.reloadMu.Unlock()
+
+	spec := sc.spec
+	if sc.specPath != "" {
+		loaded, err := utils.CreateSpecFromFile(sc.specPath)
+		if err != nil {
+			return fmt.Errorf("reload failed, keeping current routes: spec '%s': %w", sc.specPath, err)
+		}
+		spec = loaded
+	}
+	if spec == nil {
+		spec = utils.GetDefaultSpec()
+	}
+
+	routes := sc.routes
+	tc := sc.tc
+	if sc.txPath != "" {
+		loaded, err := transactions.NewTransactionCollection(sc.txPath, spec)
+		if err != nil {
+			return fmt.Errorf("reload failed, keeping current routes: %w", err)
+		}
+		routes = loaded.GetMockRoutes()
+		tc = loaded
+	}
+
+	// Load every listener spec before swapping anything so a bad file leaves all listeners untouched
+	specs := make([]*iso8583.MessageSpec, len(sc.listeners))
+	for i, l := range sc.listeners {
+		if l.specPath == "" {
+			specs[i] = spec
+			continue
+		}
+		loaded, err := utils.CreateSpecFromFile(l.specPath)
+		if err != nil {
+			return fmt.Errorf("reload failed, keeping current routes: listener '%s' spec '%s': %w", l.name, l.specPath, err)
+		}
+		specs[i] = loaded
+	}
+
+	sc.spec = spec
+	sc.routes = routes
+	sc.tc = tc
+	if sc.isRunning() {
+		for i, l := range sc.listeners {
+			l.srv.Reload(specs[i], sc.routesFor(l.name))
+			l.srv.SetOutbound(sc.outboundFor(l.name))
+		}
+	}
+
+	fmt.Printf("🔄 Reloaded %d mock route(s)", len(routes))
+	if sc.txPath != "" {
+		fmt.Printf(" from %s", sc.txPath)
+	}
+	fmt.Println()
+	return nil
+}
+
+// startWatcher reloads the server automatically when the spec or transaction file changes
+func (sc *ServerCommand) startWatcher() {
+	if !sc.watch || (sc.specPath == "" && sc.txPath == "") {
+		return
+	}
+	sc.stopWatcher()
+	paths := []string{sc.specPath, sc.txPath}
+	for _, l := range sc.listeners {
+		if l.specPath != "" {
+			paths = append(paths, l.specPath)
+		}
+	}
+	sc.watcher = server.NewFileWatcher(paths, reloadPollInterval, func(path string) {
+		fmt.Printf("\n[SERVER] 🔄 Detected change in %s\n", path)
+		if err := sc.Reload(); err != nil {
+			fmt.Printf("[SERVER] ⚠️ %v\n", err)
+		}
+	})
+	sc.watcher.Start()
+}
+
+func (sc *ServerCommand) stopWatcher() {
+	if sc.watcher != nil {
+		sc.watcher.Stop()
+		sc.watcher = nil
+	}
+}
+
+// outbound returns the server-initiated messages defined in the transaction file
+func (sc *ServerCommand) outbound() []config.MockOutboundConfig {
+	tcImpl, ok := sc.tc.(*transactions.TransactionCollection)
+	if !ok || tcImpl == nil {
+		return nil
+	}
+	return tcImpl.GetMockOutbound()
+}
+
+// Push sends a defined server-initiated message to one client, or all clients when connID is empty.
+// With several listeners connID takes the form <listener>/<connection-id>.
+func (sc *ServerCommand) Push(name string, connID string) error {
+	if !sc.isRunning() {
+		return fmt.Errorf("mock server is not running")
+	}
+	listener := ""
+	if l, id, ok := strings.Cut(connID, "/"); ok {
+		listener, connID = l, id
+	}
+
+	sent, defined := 0, false
+	var lastErr error
+	for _, l := range sc.listeners {
+		if listener != "" && l.name != listener {
+			continue
+		}
+		if !slices.ContainsFunc(l.srv.Outbound(), func(o config.MockOutboundConfig) bool { return o.Name == name }) {
+			continue
+		}
+		defined = true
+		n, err := l.srv.SendOutbound(name, connID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		sent += n
+	}
+	if !defined {
+		return fmt.Errorf("outbound message '%s' not found", name)
+	}
+	if sent == 0 && lastErr != nil {
+		return lastErr
+	}
+	if sent == 0 {
+		fmt.Println("No connected clients to send to")
+	}
 	return nil
 }
 
+// ListOutbound displays the server-initiated message definitions and recent client responses
+func (sc *ServerCommand) ListOutbound() {
+	defs := sc.outbound()
+	if len(defs) == 0 {
+		fmt.Println("No outbound messages configured")
+		return
+	}
+
+	fmt.Println("================================================================================")
+	fmt.Println(" SERVER-INITIATED MESSAGES")
+	fmt.Println("================================================================================")
+	for _, o := range defs {
+		schedule := "on demand"
+		if o.IntervalMs > 0 {
+			schedule = fmt.Sprintf("every %dms", o.IntervalMs)
+			if o.Count > 0 {
+				schedule += fmt.Sprintf(" x%d", o.Count)
+			}
+		}
+		if o.Listener != "" {
+			schedule += fmt.Sprintf(" | Listener: %s", o.Listener)
+		}
+		fmt.Printf(" %-30s | MTI: %v | %s\n", o.Name, o.Fields["0"], schedule)
+	}
+
+	if !sc.isRunning() {
+		fmt.Println("================================================================================")
+		return
+	}
+	fmt.Println("--------------------------------------------------------------------------------")
+	fmt.Println(" RECENT SENDS")
+	fmt.Println("--------------------------------------------------------------------------------")
+	empty := true
+	for _, l := range sc.listeners {
+		for _, r := range l.srv.OutboundLog() {
+			empty = false
+			answer := "no response"
+			if r.Responded {
+				answer = fmt.Sprintf("%s RC %s in %dms", r.RespMTI, r.RespCode, r.LatencyMs)
+			}
+			connID := r.ConnID
+			if l.name != "" {
+				connID = l.name + "/" + connID
+			}
+			fmt.Printf("  %s %-25s -> %-4s STAN %s | %s\n", r.SentAt.Format("15:04:05"), r.Name, connID, r.STAN, answer)
+		}
+	}
+	if empty {
+		fmt.Println("  (Nothing sent yet)")
+	}
+	fmt.Println("================================================================================")
+}
+
+// seedState loads account balances for stateful routes from their referenced datasets
+func (sc *ServerCommand) seedState() {
+	tcImpl, ok := sc.tc.(*transactions.TransactionCollection)
+	if !ok || tcImpl == nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, r := range sc.routes {
+		if r.State == nil || r.State.DatasetName == "" || seen[r.State.DatasetName] {
+			continue
+		}
+		seen[r.State.DatasetName] = true
+
+		ds, err := tcImpl.GetDataset(r.State.DatasetName)
+		if err != nil {
+			fmt.Printf("   ⚠️ Warning: State dataset for route '%s': %v\n", r.Name, err)
+			continue
+		}
+		accountKey := r.State.AccountKey
+		if accountKey == "" {
+			accountKey = r.State.AccountField
+		}
+		n := server.SeedStateFromDataset(sc.listeners[0].srv.GetStateStore(), ds.Data, accountKey, r.State.BalanceKey)
+		fmt.Printf("   ✓ Seeded %d account balance(s) from dataset '%s'\n", n, r.State.DatasetName)
+	}
+}
+
 // StopServer stops the embedded mock server
 func (sc *ServerCommand) StopServer() error {
-	if sc.srv == nil || !sc.srv.IsRunning() {
+	if !sc.isRunning() {
 		fmt.Println("Mock server is not running")
 		return nil
 	}
 
-	port := sc.srv.GetPort()
-	if err := sc.srv.Stop(); err != nil {
-		return fmt.Errorf("failed to stop mock server: %w", err)
+	sc.stopWatcher()
+	server.StopAdmin(sc.admin)
+	sc.admin = nil
+	return sc.stopListeners()
+}
+
+// stopListeners stops every running listener, reporting the first failure
+func (sc *ServerCommand) stopListeners() error {
+	var firstErr error
+	for _, l := range sc.listeners {
+		port := l.srv.GetPort()
+		if err := l.srv.Stop(); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to stop mock server: %w", err)
+			}
+			continue
+		}
+		if l.name != "" {
+			fmt.Printf("Mock listener '%s' on port %s stopped 🔴\n", l.name, port)
+		} else {
+			fmt.Printf("Embedded ISO8583 Mock Server on port %s stopped 🔴\n", port)
+		}
 	}
+	sc.listeners = nil
+	return firstErr
+}
 
-	fmt.Printf("Embedded ISO8583 Mock Server on port %s stopped 🔴\n", port)
-	return nil
+// ListListeners displays the configured mock listeners and, when running, their traffic
+func (sc *ServerCommand) ListListeners() {
+	defs := sc.listenerConfigs()
+	if len(defs) == 0 {
+		fmt.Println("No mock listeners configured")
+		return
+	}
+
+	running := make(map[string]*server.Server)
+	for _, l := range sc.listeners {
+		running[l.name] = l.srv
+	}
+
+	fmt.Println("================================================================================")
+	fmt.Println(" MOCK LISTENERS")
+	fmt.Println("================================================================================")
+	for _, d := range defs {
+		header := d.Header
+		if header == "" {
+			header = "binary2"
+		}
+		spec := d.Spec
+		if spec == "" {
+			spec = "(default)"
+		}
+		status := "stopped"
+		if srv, ok := running[d.Name]; ok && srv.IsRunning() {
+			snap := srv.GetStats().Snapshot()
+			status = fmt.Sprintf("running, %d conn(s), %d served", srv.ActiveConnections(), snap.TotalServed)
+		}
+		fmt.Printf(" %-20s | Port: %-5s | Header: %-7s | Routes: %-3d | Spec: %s | %s\n",
+			d.Name, d.Port, header, len(sc.routesFor(d.Name)), spec, status)
+	}
+	fmt.Println("================================================================================")
 }
 
 // ListRoutes displays all active mock routes
 func (sc *ServerCommand) ListRoutes() {
-	if len(sc.routes) == 0 {
+	if sc.isRunning() && sc.listeners[0].name != "" {
+		// Reflect changes made through the admin API
+		for _, l := range sc.listeners {
+			printRoutes(fmt.Sprintf("LISTENER %s (port %s, %s)", l.name, l.srv.GetPort(), l.srv.GetHeaderType()), l.srv.Routes())
+		}
+		return
+	}
+
+	routes := sc.routes
+	if sc.isRunning() {
+		routes = sc.listeners[0].srv.Routes()
+	}
+	if len(routes) == 0 {
 		fmt.Println("No mock routes configured")
 		return
 	}
+	printRoutes("CONFIGURED MOCK ROUTES", routes)
+}
 
+func printRoutes(title string, routes []config.MockRouteConfig) {
 	fmt.Println("================================================================================")
-	fmt.Println(" CONFIGURED MOCK ROUTES")
+	fmt.Println(" " + title)
 	fmt.Println("================================================================================")
-	for i, r := range sc.routes {
+	if len(routes) == 0 {
+		fmt.Println("  (No routes)")
+	}
+	for i, r := range routes {
 		var matchDesc strings.Builder
-		if len(r.MatchFields) == 0 {
+		if len(r.MatchFields) == 0 && len(r.MatchConn) == 0 {
 			matchDesc.WriteString("ANY")
 		} else {
 			first := true
@@ -270,6 +889,13 @@ func (sc *ServerCommand) ListRoutes() {
 				matchDesc.WriteString(fmt.Sprintf("%s=%v", k, v))
 				first = false
 			}
+			for k, v := range r.MatchConn {
+				if !first {
+					matchDesc.WriteString(", ")
+				}
+				matchDesc.WriteString(fmt.Sprintf("conn.%s=%v", k, v))
+				first = false
+			}
 		}
 		delayStr := ""
 		delayMs := r.DelayMs
@@ -279,8 +905,30 @@ func (sc *ServerCommand) ListRoutes() {
 		if delayMs > 0 || r.JitterMs > 0 {
 			delayStr = fmt.Sprintf(" | Latency: %dms (Jitter: ±%dms)", delayMs, r.JitterMs)
 		}
-		fmt.Printf(" Route %d: %-25s | Match: %s | Resp MTI: %s%s\n",
-			i+1, r.Name, matchDesc.String(), r.ResponseMTI, delayStr)
+		stateStr := ""
+		if r.State != nil {
+			stateStr = fmt.Sprintf(" | State: %s", r.State.Action)
+		}
+		if r.Priority != 0 {
+			stateStr += fmt.Sprintf(" | Priority: %d", r.Priority)
+		}
+		if len(r.Responses) > 0 {
+			stateStr += fmt.Sprintf(" | Variants: %d", len(r.Responses))
+		}
+		if r.NoResponse {
+			stateStr += " | No Response"
+		}
+		if r.Fault != "" {
+			stateStr += fmt.Sprintf(" | Fault: %s", r.Fault)
+		}
+		if r.Disabled {
+			stateStr += " | Disabled"
+		}
+		if r.Listener != "" {
+			stateStr += fmt.Sprintf(" | Listener: %s", r.Listener)
+		}
+		fmt.Printf(" Route %d: %-25s | Match: %s | Resp MTI: %s%s%s\n",
+			i+1, r.Name, matchDesc.String(), r.ResponseMTI, delayStr, stateStr)
 	}
 	fmt.Println("================================================================================")
 }
@@ -6,6 +6,7 @@ import (
 
 	"jiso/internal/client"
 	"jiso/internal/config"
+	"jiso/internal/connection"
 	"jiso/internal/service"
 )
 
@@ -15,8 +16,10 @@ type TargetCommand struct {
 	Svc       *service.Service
 }
 
-func (tc *TargetCommand) Name() string     { return "target" }
-func (tc *TargetCommand) Synopsis() string { return "Set network target address (target <host:port>)" }
+func (tc *TargetCommand) Name() string { return "target" }
+func (tc *TargetCommand) Synopsis() string {
+	return "Set network target address (target <host:port>, target secondary <host:port>... | off, target failover, target tls [cert=..] [key=..] [ca=..] [sni=..] [insecure] | off)"
+}
 
 func (tc *TargetCommand) Execute() error {
 	fmt.Println(tc.GetStatus())
@@ -77,6 +80,133 @@ func (tc *TargetCommand) SetPort(port string) error {
 	return tc.SetTarget(fmt.Sprintf("%s:%s", currentHost, port))
 }
 
+// SetSecondaries replaces the standby targets of the target group; "off" removes them
+func (tc *TargetCommand) SetSecondaries(args []string) error {
+	if tc.ClientCfg == nil {
+		return fmt.Errorf("client configuration unavailable")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: target secondary <host:port> [host:port...] | off")
+	}
+
+	var targets []string
+	if len(args) != 1 || !strings.EqualFold(args[0], "off") {
+		targets = config.SplitTargets(strings.Join(args, ","))
+	}
+	if err := tc.ClientCfg.SetSecondaries(targets); err != nil {
+		return err
+	}
+
+	secondaries := tc.ClientCfg.GetSecondaries()
+	config.GetConfig().SetSecondaryTargets(secondaries)
+	if tc.Svc != nil {
+		tc.Svc.SetSecondaryTargets(secondaries)
+	}
+
+	if len(secondaries) == 0 {
+		fmt.Println("Secondary targets cleared")
+	} else {
+		fmt.Printf("Secondary targets: %s\n", strings.Join(secondaries, ", "))
+	}
+	return nil
+}
+
+// Failover moves an active connection to the next target of the group
+func (tc *TargetCommand) Failover() error {
+	if tc.Svc == nil || !tc.Svc.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	if err := tc.Svc.Failover("manual failover"); err != nil {
+		return err
+	}
+	fmt.Printf("Now connected to %s\n", tc.Svc.GetActiveTarget())
+	return nil
+}
+
+// SetTLS updates the TLS settings of the target from "key=value" options and reconnects if active
+func (tc *TargetCommand) SetTLS(args []string) error {
+	tlsCfg, err := ParseTLSOptions(args, config.GetConfig().GetTLS())
+	if err != nil {
+		return err
+	}
+	if err := tlsCfg.Validate(); err != nil {
+		return err
+	}
+	config.GetConfig().SetTLS(tlsCfg)
+
+	if tc.Svc != nil && tc.Svc.IsConnected() {
+		fmt.Println("Disconnecting; reconnect to apply the TLS settings...")
+		_ = tc.Svc.Disconnect()
+	}
+
+	fmt.Println(describeTLS(tlsCfg))
+	return nil
+}
+
+// ParseTLSOptions applies TLS options to base. Options are "cert=<path>", "key=<path>",
+// "ca=<path>", "sni=<name>", "insecure", "client_auth", "on" and "off" ("off" clears everything).
+func ParseTLSOptions(args []string, base config.TLSConfig) (config.TLSConfig, error) {
+	cfg := base
+	if len(args) == 0 {
+		cfg.Enabled = true
+	}
+	for _, arg := range args {
+		key, val, hasVal := strings.Cut(arg, "=")
+		key = strings.ToLower(key)
+		switch key {
+		case "cert", "key", "ca", "sni", "server_name":
+			if !hasVal || val == "" {
+				return base, fmt.Errorf("TLS option '%s' needs a value, e.g. %s=<value>", key, key)
+			}
+		}
+		switch key {
+		case "on":
+			cfg.Enabled = true
+		case "off":
+			cfg = config.TLSConfig{}
+		case "insecure":
+			cfg.InsecureSkipVerify = true
+		case "client_auth":
+			cfg.ClientAuth = true
+		case "cert":
+			cfg.CertFile = val
+		case "key":
+			cfg.KeyFile = val
+		case "ca":
+			cfg.CAFile = val
+		case "sni", "server_name":
+			cfg.ServerName = val
+		default:
+			return base, fmt.Errorf("unknown TLS option '%s' (use cert=, key=, ca=, sni=, insecure, client_auth, on, off)", arg)
+		}
+	}
+	return cfg, nil
+}
+
+// describeTLS summarizes TLS settings for status output
+func describeTLS(c config.TLSConfig) string {
+	if !c.Active() {
+		return "TLS: off"
+	}
+	parts := []string{"TLS: on"}
+	if c.CertFile != "" {
+		parts = append(parts, "cert "+c.CertFile)
+	}
+	if c.CAFile != "" {
+		parts = append(parts, "CA "+c.CAFile)
+	}
+	if c.ServerName != "" {
+		parts = append(parts, "SNI "+c.ServerName)
+	}
+	if c.InsecureSkipVerify {
+		parts = append(parts, "no verification")
+	}
+	if c.ClientAuth {
+		parts = append(parts, "client certs required")
+	}
+	return strings.Join(parts, " | ")
+}
+
 // GetStatus prints current network target and connection status
 func (tc *TargetCommand) GetStatus() string {
 	host, port := "unknown", "unknown"
@@ -94,5 +224,18 @@ func (tc *TargetCommand) GetStatus() string {
 		statusStr = "ONLINE 🟢"
 	}
 
-	return fmt.Sprintf("Target: %s:%s | Connection Status: %s", host, port, statusStr)
+	correlation := connection.DefaultCorrelationKey
+	if tc.Svc != nil {
+		correlation = tc.Svc.GetCorrelationKey()
+	}
+
+	status := fmt.Sprintf("Target: %s:%s | Connection Status: %s | %s | Correlation: %s",
+		host, port, statusStr, describeTLS(config.GetConfig().GetTLS()), correlation)
+	if tc.Svc != nil && tc.Svc.HasSecondaryTargets() {
+		status += fmt.Sprintf(" | Group: %s | Active: %s", strings.Join(tc.Svc.GetTargets(), " > "), tc.Svc.GetActiveTarget())
+	}
+	if tc.Svc != nil && tc.Svc.PoolSize() > 1 {
+		status += fmt.Sprintf(" | Pool: %d sockets (%s)", tc.Svc.PoolSize(), tc.Svc.PoolStrategy())
+	}
+	return status
 }
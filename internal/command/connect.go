@@ -3,6 +3,7 @@ package command
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"jiso/internal/config"
 	"jiso/internal/server"
@@ -16,9 +17,10 @@ import (
 )
 
 type ConnectCommand struct {
-	Tc   transactions.Repository
-	Svc  *service.Service
-	Ctrl CLIController
+	Tc      transactions.Repository
+	Svc     *service.Service
+	Ctrl    CLIController
+	profile string // Profile given as "connect --profile <name>"
 }
 
 func (c *ConnectCommand) Name() string {
@@ -26,7 +28,11 @@ func (c *ConnectCommand) Name() string {
 }
 
 func (c *ConnectCommand) Synopsis() string {
-	return "Establishes connection to server."
+	return "Establishes connection to server (connect [--profile <name>])."
+}
+
+func (c *ConnectCommand) SetArgs(args []string) {
+	c.profile, _ = splitProfileArg(args)
 }
 
 func (c *ConnectCommand) Execute() error {
@@ -42,44 +48,10 @@ func (c *ConnectCommand) Execute() error {
 		}
 	}
 
-	qs := []*survey.Question{
-		{
-			Name: "length",
-			Prompt: &survey.Select{
-				Message: "Select length type:",
-				Options: []string{"ascii4", "binary2", "binary4", "bcd2", "NAPS", "visa"},
-			},
-			Validate: func(ans interface{}) error {
-				validTypes := map[string]bool{
-					"ascii4":  true,
-					"binary2": true,
-					"binary4": true,
-					"bcd2":    true,
-					"NAPS":    true,
-					"visa":    true,
-				}
-
-				// Properly handle the response type
-				option, ok := ans.(core.OptionAnswer)
-				if !ok {
-					// Try to convert directly to string as a fallback
-					str, ok := ans.(string)
-					if !ok {
-						return errors.New("unexpected answer type")
-					}
-					if _, valid := validTypes[str]; !valid {
-						return errors.New("invalid length type selected")
-					}
-					return nil
-				}
-
-				// Check if the value is valid
-				if _, valid := validTypes[option.Value]; !valid {
-					return errors.New("invalid length type selected")
-				}
-				return nil
-			},
-		},
+	if c.profile != "" {
+		if _, err := selectProfile(c.profile, c.Svc, c.Tc, c.Ctrl); err != nil {
+			return err
+		}
 	}
 
 	// Answer will be stored here
@@ -87,9 +59,12 @@ func (c *ConnectCommand) Execute() error {
 		Length string `survey:"length"`
 	}{}
 
-	err := survey.Ask(qs, &answers)
-	if err != nil {
-		return err
+	// A network profile fixes the header type
+	answers.Length = config.GetConfig().HeaderType("")
+	if answers.Length == "" {
+		if err := askLengthType(&answers); err != nil {
+			return err
+		}
 	}
 
 	if answers.Length == "visa" {
@@ -107,7 +82,7 @@ func (c *ConnectCommand) Execute() error {
 			stationPrompt := &survey.Input{
 				Message: "Enter Local Station ID (6-digit numeric):",
 			}
-			err = survey.AskOne(stationPrompt, &stationID, survey.WithValidator(func(val interface{}) error {
+			err := survey.AskOne(stationPrompt, &stationID, survey.WithValidator(func(val interface{}) error {
 				str, ok := val.(string)
 				if !ok {
 					return errors.New("invalid input type")
@@ -191,7 +166,15 @@ func (c *ConnectCommand) Execute() error {
 		return err
 	}
 
+	tlsCfg := config.GetConfig().GetTLS()
+	if err := c.Svc.ConfigureTLS(tlsCfg); err != nil {
+		return err
+	}
+
 	fmt.Println("Connecting to server...")
+	if tlsCfg.Active() {
+		fmt.Println("Using TLS 🔒")
+	}
 	naps := (answers.Length == "NAPS")
 	err = c.Svc.Connect(naps, header)
 	if err != nil {
@@ -211,3 +194,46 @@ func (c *ConnectCommand) Execute() error {
 	fmt.Printf("Successfully connected to server: %s\n", c.Svc.Address)
 	return nil
 }
+
+// askLengthType prompts for the TCP header type. Custom framings registered from the
+// transaction file are offered after the built-in types.
+func askLengthType(answers interface{}) error {
+	lengthTypes := append(slices.Clone(config.HeaderTypes), utils.FramingNames()...)
+	qs := []*survey.Question{
+		{
+			Name: "length",
+			Prompt: &survey.Select{
+				Message: "Select length type:",
+				Options: lengthTypes,
+			},
+			Validate: func(ans interface{}) error {
+				validTypes := make(map[string]bool, len(lengthTypes))
+				for _, t := range lengthTypes {
+					validTypes[t] = true
+				}
+
+				// Properly handle the response type
+				option, ok := ans.(core.OptionAnswer)
+				if !ok {
+					// Try to convert directly to string as a fallback
+					str, ok := ans.(string)
+					if !ok {
+						return errors.New("unexpected answer type")
+					}
+					if _, valid := validTypes[str]; !valid {
+						return errors.New("invalid length type selected")
+					}
+					return nil
+				}
+
+				// Check if the value is valid
+				if _, valid := validTypes[option.Value]; !valid {
+					return errors.New("invalid length type selected")
+				}
+				return nil
+			},
+		},
+	}
+
+	return survey.Ask(qs, answers)
+}
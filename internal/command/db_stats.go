@@ -44,6 +44,7 @@ func (c *DbStatsCommand) Execute() error {
 	fmt.Printf("Total Transactions: %v\n", stats["total_transactions"])
 	fmt.Printf("Successful Transactions: %v\n", stats["successful_transactions"])
 	fmt.Printf("Failed Transactions: %v\n", stats["failed_transactions"])
+	fmt.Printf("Reversed Transactions: %v\n", stats["reversed_transactions"])
 	fmt.Printf("Average Processing Time: %.2f ms\n", stats["average_processing_time_ms"])
 
 	if responseCodes, ok := stats["response_code_distribution"].(map[string]int); ok &&
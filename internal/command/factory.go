@@ -31,13 +31,15 @@ func NewFactory(
 	if cc, ok := controller.(CLIController); ok {
 		cliCtrl = cc
 	}
+	clientCfg := client.NewClientConfig(config.GetConfig().GetHost(), config.GetConfig().GetPort(), nil)
+	_ = clientCfg.SetSecondaries(config.GetConfig().GetSecondaryTargets())
 	return &Factory{
 		service:      svc,
 		transactions: tx,
 		networkStats: networkStats,
 		controller:   controller,
 		cliCtrl:      cliCtrl,
-		clientCfg:    client.NewClientConfig(config.GetConfig().GetHost(), config.GetConfig().GetPort(), nil),
+		clientCfg:    clientCfg,
 	}
 }
 
@@ -118,6 +120,14 @@ func (f *Factory) CreateRunScenarioCommand() Command {
 	}
 }
 
+// CreateRunSuiteCommand creates a scenario suite runner command
+func (f *Factory) CreateRunSuiteCommand() Command {
+	return &RunSuiteCommand{
+		Tc:  f.transactions,
+		Svc: f.service,
+	}
+}
+
 // CreateInitSpecCommand creates an init-spec command
 func (f *Factory) CreateInitSpecCommand() Command {
 	return &InitSpecCommand{}
@@ -116,7 +116,7 @@ func (c *InfoCommand) Execute() error {
 	} else {
 		fmt.Printf("Packed bytes: %d\n", len(packed))
 		fmt.Println("\nPacked HEX dump:")
-		fmt.Print(hexDump(packed))
+		fmt.Print(utils.HexDump(packed, utils.SpecCodePage(sampleMsg.GetSpec())))
 	}
 
 	fmt.Println("\nParsed field view:")
@@ -0,0 +1,65 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"jiso/internal/config"
+	"jiso/internal/service"
+	"jiso/internal/transactions"
+
+	"github.com/moov-io/iso8583"
+)
+
+// splitProfileArg removes a "--profile <name>" or "--profile=<name>" option from args
+func splitProfileArg(args []string) (string, []string) {
+	profile := ""
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--profile" && i+1 < len(args):
+			profile = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--profile="):
+			profile = strings.TrimPrefix(args[i], "--profile=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return profile, rest
+}
+
+// selectProfile selects a network profile for the rest of the session. A spec bundled
+// by the profile replaces the loaded one unless a spec was given explicitly, and the
+// service picks up the profile's correlation key and network management messages.
+func selectProfile(name string, svc *service.Service, tc transactions.Repository, ctrl CLIController) (config.NetworkProfile, error) {
+	c := config.GetConfig()
+	specBefore := c.GetSpec()
+	if err := c.SetProfile(name); err != nil {
+		return config.NetworkProfile{}, err
+	}
+	profile, _ := config.LookupProfile(name)
+
+	if specPath := c.GetSpec(); specPath != specBefore {
+		spec := &SpecCommand{SpecPath: specPath, Svc: svc, Tc: tc, Ctrl: ctrl}
+		if err := spec.Execute(); err != nil {
+			return profile, err
+		}
+	}
+
+	if svc != nil {
+		if err := svc.ConfigureCorrelation(c.GetCorrelationKey(), c.GetDuplicateKeyPolicy()); err != nil {
+			return profile, err
+		}
+		var compose func(name string) (*iso8583.Message, error)
+		if tc != nil {
+			compose = tc.Compose
+		}
+		if err := svc.ConfigureSession(c.GetSignOn(), c.GetEchoTx(), c.GetSignOff(), c.GetEchoInterval(), compose); err != nil {
+			return profile, err
+		}
+	}
+
+	fmt.Printf("Using %s profile: %s\n", profile.Name, profile.Description)
+	return profile, nil
+}
@@ -0,0 +1,28 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitProfileArg(t *testing.T) {
+	tests := []struct {
+		args    []string
+		profile string
+		rest    []string
+	}{
+		{[]string{"start", "9999"}, "", []string{"start", "9999"}},
+		{[]string{"start", "--profile", "mastercard", "9999"}, "mastercard", []string{"start", "9999"}},
+		{[]string{"--profile=tsys_dhi"}, "tsys_dhi", []string{}},
+		{[]string{"start", "--profile"}, "", []string{"start", "--profile"}},
+	}
+	for _, tt := range tests {
+		profile, rest := splitProfileArg(tt.args)
+		if profile != tt.profile {
+			t.Errorf("%v: expected profile '%s', got '%s'", tt.args, tt.profile, profile)
+		}
+		if !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("%v: expected remaining args %v, got %v", tt.args, tt.rest, rest)
+		}
+	}
+}
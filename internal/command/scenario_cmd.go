@@ -5,7 +5,11 @@ import (
 	json "github.com/goccy/go-json"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
 
+	"jiso/internal/reporter"
 	"jiso/internal/service"
 	"jiso/internal/transactions"
 
@@ -71,9 +75,6 @@ func (c *RunScenarioCommand) Execute() error {
 	if err := VerifyTx(c.Tc); err != nil {
 		return err
 	}
-	if err := VerifyConnection(c.Svc); err != nil {
-		return err
-	}
 
 	tcImpl, ok := c.Tc.(*transactions.TransactionCollection)
 	if !ok {
@@ -97,6 +98,13 @@ func (c *RunScenarioCommand) Execute() error {
 		}
 	}
 
+	// A scenario with its own mock does not need the target
+	if !tcImpl.ScenariosMocked([]string{name}) {
+		if err := VerifyConnection(c.Svc); err != nil {
+			return err
+		}
+	}
+
 	runner := transactions.NewScenarioRunner(c.Svc, tcImpl)
 	report, err := runner.RunScenario(name)
 	if err != nil {
@@ -170,3 +178,142 @@ func (c *RunScenarioCommand) saveReport(report *transactions.TestReport) error {
 	fmt.Printf("Test report exported to: %s\n", c.ReportPath)
 	return nil
 }
+
+// RunSuiteCommand runs every scenario selected by name, glob or tag, optionally
+// several at a time, and exports their reports as JSON and JUnit XML
+type RunSuiteCommand struct {
+	Tc        transactions.Repository
+	Svc       *service.Service
+	Patterns  []string // Scenario names or globs; none selects every scenario
+	Tags      []string // Only scenarios carrying one of these tags run
+	Parallel  int      // Scenarios run at a time
+	ReportDir string   // Directory receiving a JSON report per scenario
+	JUnitPath string   // Path of the JUnit XML report
+
+	argErr error // Why the REPL arguments could not be parsed
+}
+
+func (c *RunSuiteCommand) Name() string {
+	return "run-suite"
+}
+
+func (c *RunSuiteCommand) Synopsis() string {
+	return "Run the scenarios matching names, globs or tags. (requires connection to server)"
+}
+
+// SetArgs takes the REPL arguments: run-suite [--tag t] [--parallel n]
+// [--report-dir dir] [--junit path] [name or glob...]
+func (c *RunSuiteCommand) SetArgs(args []string) {
+	c.Patterns, c.Tags, c.Parallel, c.ReportDir, c.JUnitPath, c.argErr = nil, nil, 1, "", "", nil
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			c.Patterns = append(c.Patterns, arg)
+			continue
+		}
+		if i+1 >= len(args) {
+			c.argErr = fmt.Errorf("%s needs a value", arg)
+			return
+		}
+		i++
+		switch arg {
+		case "--tag":
+			c.Tags = append(c.Tags, strings.Split(args[i], ",")...)
+		case "--parallel":
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 1 {
+				c.argErr = fmt.Errorf("invalid --parallel '%s'", args[i])
+				return
+			}
+			c.Parallel = n
+		case "--report-dir":
+			c.ReportDir = args[i]
+		case "--junit":
+			c.JUnitPath = args[i]
+		default:
+			c.argErr = fmt.Errorf("unknown run-suite option %s", arg)
+			return
+		}
+	}
+}
+
+func (c *RunSuiteCommand) Execute() error {
+	if c.argErr != nil {
+		return c.argErr
+	}
+	if err := VerifySpec(c.Svc); err != nil {
+		return err
+	}
+	if err := VerifyTx(c.Tc); err != nil {
+		return err
+	}
+
+	tcImpl, ok := c.Tc.(*transactions.TransactionCollection)
+	if !ok {
+		return fmt.Errorf("invalid transaction repository type")
+	}
+
+	names, err := tcImpl.SelectScenarios(c.Patterns, c.Tags)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no scenarios carry the tags %s", strings.Join(c.Tags, ", "))
+	}
+	if !tcImpl.ScenariosMocked(names) {
+		if err := VerifyConnection(c.Svc); err != nil {
+			return err
+		}
+	}
+
+	parallel := transactions.SuiteParallelism(c.Svc, tcImpl, names, c.Parallel)
+	if parallel < c.Parallel && parallel < len(names) {
+		fmt.Printf("Limiting to %d at a time: each running scenario needs a socket of its own (--pool-size)\n", parallel)
+	}
+	fmt.Printf("Running %d scenarios, %d at a time...\n", len(names), parallel)
+	suite := transactions.RunSuite(c.Svc, tcImpl, "jiso", names, parallel)
+
+	for _, report := range suite.Scenarios {
+		if !report.Success {
+			reporter.PrintTerminalReport(report)
+		}
+	}
+	reporter.PrintSuiteSummary(suite)
+
+	if c.ReportDir != "" {
+		if err := os.MkdirAll(c.ReportDir, 0755); err != nil {
+			return err
+		}
+		for _, report := range suite.Scenarios {
+			if err := reporter.ExportJSONReport(report, filepath.Join(c.ReportDir, reportFileName(report.ScenarioName))); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Test reports exported to: %s\n", c.ReportDir)
+	}
+	if c.JUnitPath != "" {
+		if err := os.MkdirAll(filepath.Dir(c.JUnitPath), 0755); err != nil {
+			return err
+		}
+		if err := reporter.ExportJUnitReport(suite, c.JUnitPath); err != nil {
+			return err
+		}
+		fmt.Printf("JUnit report exported to: %s\n", c.JUnitPath)
+	}
+
+	if !suite.Success {
+		return fmt.Errorf("%d of %d scenarios failed", suite.Failed(), len(suite.Scenarios))
+	}
+	return nil
+}
+
+// reportFileName turns a scenario name into the name of its JSON report file
+func reportFileName(scenario string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, scenario)
+	return name + ".json"
+}
@@ -1,12 +1,53 @@
 package command
 
 import (
-	"fmt"
 	"strings"
 
+	iconn "jiso/internal/connection"
+	"jiso/internal/db"
+
+	json "github.com/goccy/go-json"
 	moovconnection "github.com/moov-io/iso8583-connection"
 )
 
+// reversalRecord is the outcome of an automatic reversal as stored with the original
+type reversalRecord struct {
+	MTI          string          `json:"mti"`
+	Attempts     int             `json:"attempts"`
+	ResponseCode string          `json:"response_code,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	Request      json.RawMessage `json:"request,omitempty"`
+	Response     json.RawMessage `json:"response,omitempty"`
+}
+
+// reversalJSON serializes a reversal outcome for the transaction log, or returns nil
+// when the request was not reversed
+func reversalJSON(r *iconn.ReversalResult) *string {
+	if r == nil {
+		return nil
+	}
+	record := reversalRecord{MTI: r.MTI, Attempts: r.Attempts, ResponseCode: r.ResponseCode}
+	if r.Err != nil {
+		record.Error = r.Err.Error()
+	}
+	if r.Request != nil {
+		if req, err := db.MessageToJSON(r.Request); err == nil {
+			record.Request = json.RawMessage(req)
+		}
+	}
+	if r.Response != nil {
+		if resp, err := db.MessageToJSON(r.Response); err == nil {
+			record.Response = json.RawMessage(resp)
+		}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil
+	}
+	out := string(data)
+	return &out
+}
+
 func isRetriableError(err error) bool {
 	if err == nil {
 		return false
@@ -65,34 +106,3 @@ func isRetriableError(err error) bool {
 	// Default: assume retriable for unknown errors (safer to retry)
 	return true
 }
-
-func hexDump(data []byte) string {
-	var buf strings.Builder
-	for i := 0; i < len(data); i += 16 {
-		// offset
-		fmt.Fprintf(&buf, "%08x  ", i)
-		// hex bytes
-		for j := 0; j < 16; j++ {
-			if i+j < len(data) {
-				fmt.Fprintf(&buf, "%02x ", data[i+j])
-			} else {
-				buf.WriteString("   ")
-			}
-			if j == 7 {
-				buf.WriteString(" ")
-			}
-		}
-		buf.WriteString(" |")
-		// ASCII
-		for j := 0; j < 16 && i+j < len(data); j++ {
-			b := data[i+j]
-			if b >= 32 && b <= 126 {
-				buf.WriteByte(b)
-			} else {
-				buf.WriteByte('.')
-			}
-		}
-		buf.WriteString("|\n")
-	}
-	return buf.String()
-}
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -12,6 +13,7 @@ import (
 	"jiso/internal/metrics"
 	"jiso/internal/service"
 	"jiso/internal/transactions"
+	"jiso/internal/utils"
 	"jiso/internal/view"
 
 	"github.com/AlecAivazis/survey/v2"
@@ -94,7 +96,7 @@ func (c *SendCommand) Execute() error {
 
 	// Only print hex dump if debug mode is not enabled (connection manager handles it)
 	if config.GetConfig().GetHex() && !c.Svc.GetDebugMode() {
-		fmt.Printf("Request HEX:\n%s", hexDump(rawMsg))
+		fmt.Printf("Request HEX:\n%s", utils.HexDump(rawMsg, utils.SpecCodePage(msg.GetSpec())))
 	}
 
 	rebuiltMsg := iso8583.NewMessage(msg.GetSpec())
@@ -117,6 +119,11 @@ func (c *SendCommand) Execute() error {
 
 	elapsed := time.Since(startTime)
 
+	var reversal *iconn.ReversalResult
+	if errors.Is(err, iconn.ErrResponseTimeout) {
+		reversal = c.reverse(msg, trxnName)
+	}
+
 	// Store transaction in database if configured
 	if config.GetConfig().GetDbPath() != "" {
 		requestJSON, _ := db.MessageToJSON(msg)
@@ -131,13 +138,14 @@ func (c *SendCommand) Execute() error {
 			processingTimeMs = 0 // Timeout or error
 		}
 
-		db.LogTransaction(
+		db.LogReversedTransaction(
 			config.GetConfig().GetSessionId(),
 			trxnName,
 			requestJSON,
 			responseJSON,
 			processingTimeMs,
 			success,
+			reversalJSON(reversal),
 		)
 	}
 
@@ -182,7 +190,7 @@ func (c *SendCommand) Execute() error {
 	if config.GetConfig().GetHex() && !c.Svc.GetDebugMode() {
 		responsePacked, packErr := response.Pack()
 		if packErr == nil {
-			fmt.Printf("Response HEX:\n%s", hexDump(responsePacked))
+			fmt.Printf("Response HEX:\n%s", utils.HexDump(responsePacked, utils.SpecCodePage(response.GetSpec())))
 		}
 	}
 
@@ -221,11 +229,26 @@ func (c *SendCommand) retrySend(msg *iso8583.Message, maxRetries int) (*iso8583.
 		if !isRetriableError(err) {
 			break
 		}
+
+		// A timed-out request is reversed rather than resent
+		if errors.Is(err, iconn.ErrResponseTimeout) && c.Svc.ReversalEnabled() {
+			break
+		}
 	}
 
 	return nil, lastErr
 }
 
+// reverse sends the auto-reversal of msg, which got no response, and prints its
+// outcome. It returns nil when auto-reversal is off.
+func (c *SendCommand) reverse(msg *iso8583.Message, trxnName string) *iconn.ReversalResult {
+	reversal := c.Svc.Reverse(msg)
+	if reversal != nil {
+		fmt.Printf("Reversal of %s: %s\n", trxnName, reversal)
+	}
+	return reversal
+}
+
 func (c *SendCommand) StartClock() {
 	c.statsMu.Lock()
 	if c.stats == nil {
@@ -310,17 +333,19 @@ func (c *SendCommand) ExecuteBackground(trxnName string, skipValidation bool, se
 	if resp == nil {
 		// Timeout occurred
 		c.Tc.LogTransaction(trxnName, false)
+		reversal := c.reverse(msg, trxnName)
 
 		// Store timeout transaction in database
 		if config.GetConfig().GetDbPath() != "" {
 			requestJSON, _ := db.MessageToJSON(msg)
-			db.LogTransaction(
+			db.LogReversedTransaction(
 				logSessionID,
 				trxnName,
 				requestJSON,
 				nil, // No response
 				int(execTime.Milliseconds()),
 				false,
+				reversalJSON(reversal),
 			)
 		}
 
@@ -3,8 +3,10 @@ package config
 import (
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,6 +26,24 @@ type Config struct {
 	dbPath              string
 	sessionId           string
 	visaStationId       string
+	tls                 TLSConfig
+	correlationKey      string
+	duplicateKeyPolicy  string
+	poolSize            int
+	poolStrategy        string
+	secondaryTargets    []string
+	failbackInterval    time.Duration
+	signOn              string
+	signOff             string
+	echoTx              string
+	echoInterval        time.Duration
+	minMessageSize      int
+	maxMessageSize      int
+	onInvalidFrame      string
+	autoReversal        string
+	reversalRepeats     int
+	profile             string
+	profileApplied      NetworkProfile // Settings the profile set, replaced when the profile changes
 	mu                  sync.RWMutex
 }
 
@@ -39,6 +59,7 @@ func GetConfig() *Config {
 			connectTimeout:      5 * time.Second,
 			totalConnectTimeout: 10 * time.Second,
 			responseTimeout:     5 * time.Second,
+			failbackInterval:    30 * time.Second,
 		}
 	})
 	return config
@@ -85,6 +106,28 @@ func (c *Config) Parse() error {
 	hex := flag.Bool("hex", false, "enable hex dump output for messages")
 	dbPath := flag.String("db-path", "", "path to SQLite database file for storing sessions")
 	visaStationId := flag.String("visa-station-id", "", "VISA Local Station ID (6-digit hex or decimal)")
+	tlsEnabled := flag.Bool("tls", false, "use TLS for client connections")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file (client certificate, or the mock server's certificate)")
+	tlsKey := flag.String("tls-key", "", "TLS private key file for -tls-cert")
+	tlsCA := flag.String("tls-ca", "", "CA bundle verifying the server (or, for the mock server, client certificates)")
+	tlsServerName := flag.String("tls-server-name", "", "TLS server name (SNI) sent on connect")
+	tlsInsecure := flag.Bool("tls-insecure", false, "skip TLS server certificate verification")
+	correlationKey := flag.String("correlation-key", "", "fields pairing responses with requests: stan, stan_terminal, stan_terminal_date, rrn or a list like 11+41+7")
+	duplicateKeyPolicy := flag.String("on-duplicate-key", "", "what a send does when its correlation key is already pending: fail or queue")
+	poolSize := flag.Int("pool-size", 1, "number of sockets opened to the target")
+	poolStrategy := flag.String("pool-strategy", "", "how requests are spread over pooled sockets: round_robin or least_pending")
+	secondaryTargets := flag.String("secondary-targets", "", "comma-separated standby host:port targets used when the primary fails")
+	failbackInterval := flag.Duration("failback-interval", 30*time.Second, "how often the primary is echo tested while on a secondary target (0 disables failback)")
+	signOn := flag.String("sign-on", "", "sign-on sent after every connect: auto for a built-in 0800 or a transaction name")
+	signOff := flag.String("sign-off", "", "sign-off sent before disconnect: auto for a built-in 0800 or a transaction name")
+	echoTx := flag.String("echo-tx", "", "transaction sent as echo test (default: built-in 0800 with DE70 301)")
+	echoInterval := flag.Duration("echo-interval", 0, "idle time before an echo test keeps the link alive (0 disables echo tests)")
+	minMessageSize := flag.Int("min-message-size", 0, "smallest message accepted from the target in bytes (default 20)")
+	maxMessageSize := flag.Int("max-message-size", 0, "largest message accepted from the target in bytes (default 8192)")
+	onInvalidFrame := flag.String("on-invalid-frame", "", "what happens to a frame outside the size limits: close (default) or skip, which hex-dumps and discards it")
+	autoReversal := flag.String("auto-reversal", "", "reversal sent for a request that gets no response: 0400, 0420 or off (default)")
+	reversalRepeats := flag.Int("reversal-repeats", 0, "repeats (0401/0421) sent while a reversal gets no response")
+	profile := flag.String("profile", "", "network profile bundling header, spec, sign-on/echo and correlation key: "+strings.Join(ProfileNames(), ", "))
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: jiso [OPTIONS]\n")
@@ -101,8 +144,38 @@ func (c *Config) Parse() error {
 	c.hex = *hex
 	c.dbPath = *dbPath
 	c.visaStationId = *visaStationId
+	c.tls = TLSConfig{
+		Enabled:            *tlsEnabled,
+		CertFile:           *tlsCert,
+		KeyFile:            *tlsKey,
+		CAFile:             *tlsCA,
+		ServerName:         *tlsServerName,
+		InsecureSkipVerify: *tlsInsecure,
+	}
+	c.correlationKey = *correlationKey
+	c.duplicateKeyPolicy = *duplicateKeyPolicy
+	c.poolSize = *poolSize
+	c.poolStrategy = *poolStrategy
+	c.secondaryTargets = SplitTargets(*secondaryTargets)
+	c.failbackInterval = *failbackInterval
+	c.signOn = *signOn
+	c.signOff = *signOff
+	c.echoTx = *echoTx
+	c.echoInterval = *echoInterval
+	c.minMessageSize = *minMessageSize
+	c.maxMessageSize = *maxMessageSize
+	c.onInvalidFrame = *onInvalidFrame
+	c.autoReversal = *autoReversal
+	c.reversalRepeats = *reversalRepeats
 	c.sessionId = generateSessionId()
 
+	// The profile only fills what the other flags left unset
+	if *profile != "" {
+		if err := c.SetProfile(*profile); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -131,6 +204,24 @@ func (c *Config) Reset() {
 	c.hex = false
 	c.dbPath = ""
 	c.visaStationId = ""
+	c.tls = TLSConfig{}
+	c.correlationKey = ""
+	c.duplicateKeyPolicy = ""
+	c.poolSize = 0
+	c.poolStrategy = ""
+	c.secondaryTargets = nil
+	c.failbackInterval = 30 * time.Second
+	c.signOn = ""
+	c.signOff = ""
+	c.echoTx = ""
+	c.echoInterval = 0
+	c.minMessageSize = 0
+	c.maxMessageSize = 0
+	c.onInvalidFrame = ""
+	c.autoReversal = ""
+	c.reversalRepeats = 0
+	c.profile = ""
+	c.profileApplied = NetworkProfile{}
 	c.reconnectAttempts = 3
 	c.connectTimeout = 5 * time.Second
 	c.totalConnectTimeout = 10 * time.Second
@@ -274,6 +365,230 @@ func (c *Config) SetVisaStationId(stationId string) {
 	c.visaStationId = stationId
 }
 
+// GetTLS returns the TLS settings for client connections and the mock server
+func (c *Config) GetTLS() TLSConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tls
+}
+
+// GetCorrelationKey returns the correlation key expression; empty selects STAN alone
+func (c *Config) GetCorrelationKey() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.correlationKey
+}
+
+func (c *Config) SetCorrelationKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.correlationKey = key
+}
+
+// GetDuplicateKeyPolicy returns the duplicate correlation key policy; empty selects fail
+func (c *Config) GetDuplicateKeyPolicy() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.duplicateKeyPolicy
+}
+
+func (c *Config) SetDuplicateKeyPolicy(policy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.duplicateKeyPolicy = policy
+}
+
+// GetPoolSize returns the number of sockets opened to the target; unset selects one
+func (c *Config) GetPoolSize() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.poolSize <= 0 {
+		return 1
+	}
+	return c.poolSize
+}
+
+func (c *Config) SetPoolSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.poolSize = size
+}
+
+// GetPoolStrategy returns how requests are spread over pooled sockets; empty selects round_robin
+func (c *Config) GetPoolStrategy() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.poolStrategy
+}
+
+func (c *Config) SetPoolStrategy(strategy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.poolStrategy = strategy
+}
+
+// GetSecondaryTargets returns the standby host:port targets in failover order
+func (c *Config) GetSecondaryTargets() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.secondaryTargets...)
+}
+
+func (c *Config) SetSecondaryTargets(targets []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.secondaryTargets = append([]string(nil), targets...)
+}
+
+// GetFailbackInterval returns how often the primary is echo tested while on a secondary
+func (c *Config) GetFailbackInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.failbackInterval
+}
+
+func (c *Config) SetFailbackInterval(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failbackInterval = interval
+}
+
+// GetSignOn returns the sign-on sent after every connect: auto, a transaction name or empty
+func (c *Config) GetSignOn() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.signOn
+}
+
+func (c *Config) SetSignOn(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.signOn = name
+}
+
+// GetSignOff returns the sign-off sent before disconnect: auto, a transaction name or empty
+func (c *Config) GetSignOff() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.signOff
+}
+
+func (c *Config) SetSignOff(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.signOff = name
+}
+
+// GetEchoTx returns the transaction sent as echo test; empty selects the built-in echo
+func (c *Config) GetEchoTx() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.echoTx
+}
+
+func (c *Config) SetEchoTx(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.echoTx = name
+}
+
+// GetEchoInterval returns the idle time before an echo test; zero disables echo tests
+func (c *Config) GetEchoInterval() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.echoInterval
+}
+
+func (c *Config) SetEchoInterval(interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.echoInterval = interval
+}
+
+// GetMinMessageSize returns the smallest accepted message size; zero selects the default
+func (c *Config) GetMinMessageSize() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.minMessageSize
+}
+
+func (c *Config) SetMinMessageSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.minMessageSize = size
+}
+
+// GetMaxMessageSize returns the largest accepted message size; zero selects the default
+func (c *Config) GetMaxMessageSize() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.maxMessageSize
+}
+
+func (c *Config) SetMaxMessageSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxMessageSize = size
+}
+
+// GetOnInvalidFrame returns what happens to frames outside the size limits: close or skip
+func (c *Config) GetOnInvalidFrame() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.onInvalidFrame
+}
+
+func (c *Config) SetOnInvalidFrame(policy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onInvalidFrame = policy
+}
+
+// GetAutoReversal returns the reversal sent for unanswered requests: 0400, 0420,
+// or empty or off when requests are not reversed
+func (c *Config) GetAutoReversal() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.autoReversal
+}
+
+func (c *Config) SetAutoReversal(policy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.autoReversal = policy
+}
+
+// GetReversalRepeats returns how many repeats are sent while a reversal gets no response
+func (c *Config) GetReversalRepeats() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.reversalRepeats
+}
+
+func (c *Config) SetReversalRepeats(repeats int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reversalRepeats = repeats
+}
+
+// SplitTargets splits a comma-separated target list, dropping empty entries
+func SplitTargets(s string) []string {
+	var targets []string
+	for _, target := range strings.Split(s, ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// SetTLS replaces the TLS settings
+func (c *Config) SetTLS(tls TLSConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tls = tls
+}
+
 func (c *Config) Validate() error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -331,6 +646,47 @@ func (c *Config) Validate() error {
 		)
 	}
 
+	for _, target := range c.secondaryTargets {
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("invalid secondary target '%s': %w", target, err)
+		}
+	}
+
+	if c.failbackInterval < 0 {
+		return fmt.Errorf("failback interval must be non-negative, got %v", c.failbackInterval)
+	}
+
+	if c.minMessageSize < 0 || c.maxMessageSize < 0 {
+		return fmt.Errorf("message size limits must be non-negative, got min %d and max %d", c.minMessageSize, c.maxMessageSize)
+	}
+	if c.maxMessageSize > 0 && c.minMessageSize > c.maxMessageSize {
+		return fmt.Errorf("min message size %d exceeds max message size %d", c.minMessageSize, c.maxMessageSize)
+	}
+	if c.onInvalidFrame != "" && c.onInvalidFrame != "close" && c.onInvalidFrame != "skip" {
+		return fmt.Errorf("invalid frame policy must be close or skip, got '%s'", c.onInvalidFrame)
+	}
+
+	if c.echoInterval < 0 {
+		return fmt.Errorf("echo interval must be non-negative, got %v", c.echoInterval)
+	}
+
+	switch c.autoReversal {
+	case "", "off", "0400", "0420":
+	default:
+		return fmt.Errorf("auto reversal must be 0400, 0420 or off, got '%s'", c.autoReversal)
+	}
+	if c.reversalRepeats < 0 || c.reversalRepeats > 10 {
+		return fmt.Errorf("reversal repeats must be between 0 and 10, got %d", c.reversalRepeats)
+	}
+
+	if c.poolSize < 0 || c.poolSize > 64 {
+		return fmt.Errorf("pool size must be between 1 and 64, got %d", c.poolSize)
+	}
+
+	if err := c.tls.Validate(); err != nil {
+		return err
+	}
+
 	// Validate database path if provided
 	if c.dbPath != "" {
 		if _, err := os.Stat(c.dbPath); os.IsNotExist(err) {
@@ -230,6 +230,17 @@ func TestConfigValidate(t *testing.T) {
 			expectError: true,
 			errorMsg:    "must be greater than or equal to connect timeout",
 		},
+		{
+			name: "unknown auto reversal",
+			setupConfig: func(c *Config) {
+				c.connectTimeout = time.Second
+				c.totalConnectTimeout = 2 * time.Second
+				c.responseTimeout = time.Second
+				c.autoReversal = "0410"
+			},
+			expectError: true,
+			errorMsg:    "auto reversal must be 0400, 0420 or off",
+		},
 	}
 
 	for _, tt := range tests {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"sort"
@@ -15,10 +16,13 @@ import (
 type ConfigDiscriminator string
 
 const (
-	TypeTransaction ConfigDiscriminator = "transaction"
-	TypeDataset     ConfigDiscriminator = "dataset"
-	TypeScenario    ConfigDiscriminator = "scenario"
-	TypeMockRoute   ConfigDiscriminator = "mock_route"
+	TypeTransaction  ConfigDiscriminator = "transaction"
+	TypeDataset      ConfigDiscriminator = "dataset"
+	TypeScenario     ConfigDiscriminator = "scenario"
+	TypeMockRoute    ConfigDiscriminator = "mock_route"
+	TypeMockOutbound ConfigDiscriminator = "mock_outbound"
+	TypeMockListener ConfigDiscriminator = "mock_listener"
+	TypeFraming      ConfigDiscriminator = "framing"
 )
 
 // MockRouteConfig defines configuration for embedded mock server response routes
@@ -26,14 +30,192 @@ type MockRouteConfig struct {
 	Name           string                 `json:"name"`
 	Description    string                 `json:"description,omitempty"`
 	MatchFields    map[string]interface{} `json:"match_fields,omitempty"`
+	MatchExpr      string                 `json:"match_expr,omitempty"`
+	MatchConn      map[string]interface{} `json:"match_conn,omitempty"`
 	RequiredFields []string               `json:"required_fields,omitempty"`
 	EchoFields     []int                  `json:"echo_fields,omitempty"`
 	ResponseMTI    string                 `json:"response_mti,omitempty"`
 	ResponseFields map[string]interface{} `json:"response_fields,omitempty"`
+	ResponseHeader map[string]string      `json:"response_header,omitempty"` // Response header fields by header.<name> key
 	DelayMs        int                    `json:"delay_ms,omitempty"`
 	LatencyMs      int                    `json:"latency_ms,omitempty"`
 	JitterMs       int                    `json:"jitter_ms,omitempty"`
 	DropConnection bool                   `json:"drop_connection,omitempty"`
+	NoResponse     bool                   `json:"no_response,omitempty"`
+	Fault          string                 `json:"fault,omitempty"`
+	StallMs        int                    `json:"stall_ms,omitempty"`
+	Priority       int                    `json:"priority,omitempty"`
+	Disabled       bool                   `json:"disabled,omitempty"`
+	Responses      []MockResponseVariant  `json:"responses,omitempty"`
+	State          *MockStateConfig       `json:"state,omitempty"`
+	Listener       string                 `json:"listener,omitempty"` // Restrict the route to one named listener
+}
+
+// MatchConnKeys lists the connection attributes a mock route can match through match_conn
+var MatchConnKeys = []string{"id", "ordinal", "remote_addr", "remote_ip", "port", "station_id", "request_num", "client_cn"}
+
+// Mock route fault injection modes
+const (
+	FaultNoResponse     = "no_response"     // Accept the request and never reply
+	FaultDuplicate      = "duplicate"       // Send the response twice
+	FaultMismatchedStan = "mismatched_stan" // Reply with a STAN (DE 11) that differs from the request
+	FaultTruncated      = "truncated"       // Send the full length header but only part of the payload, then close
+	FaultWrongLength    = "wrong_length"    // Send a length header that does not match the payload
+	FaultCorruptBitmap  = "corrupt_bitmap"  // Flip the bits of the primary bitmap's first byte
+	FaultStall          = "stall"           // Write half the frame, pause for stall_ms, then write the rest
+)
+
+// FaultModes lists every supported mock route fault mode
+var FaultModes = []string{
+	FaultNoResponse, FaultDuplicate, FaultMismatchedStan, FaultTruncated,
+	FaultWrongLength, FaultCorruptBitmap, FaultStall,
+}
+
+// MockResponseVariant is one weighted outcome of a mock route. Non-empty fields override the route.
+type MockResponseVariant struct {
+	Name           string                 `json:"name,omitempty"`
+	Weight         float64                `json:"weight"`
+	ResponseMTI    string                 `json:"response_mti,omitempty"`
+	ResponseFields map[string]interface{} `json:"response_fields,omitempty"`
+	DelayMs        int                    `json:"delay_ms,omitempty"`
+	LatencyMs      int                    `json:"latency_ms,omitempty"`
+	JitterMs       int                    `json:"jitter_ms,omitempty"`
+	DropConnection bool                   `json:"drop_connection,omitempty"`
+	NoResponse     bool                   `json:"no_response,omitempty"`
+	Fault          string                 `json:"fault,omitempty"`
+	StallMs        int                    `json:"stall_ms,omitempty"`
+}
+
+// MockStateConfig binds a mock route to the stateful account ledger of the embedded mock server
+type MockStateConfig struct {
+	Action       string `json:"action"`                  // authorize, purchase, complete, reverse or inquiry
+	AccountField string `json:"account_field,omitempty"` // Request field holding the PAN/account (default "2")
+	AmountField  string `json:"amount_field,omitempty"`  // Request field holding the amount (default "4")
+	DatasetName  string `json:"dataset_name,omitempty"`  // Dataset used to seed account balances
+	AccountKey   string `json:"account_key,omitempty"`   // Dataset column holding the account (default account_field)
+	BalanceKey   string `json:"balance_key,omitempty"`   // Dataset column holding the balance in minor units (default "balance")
+}
+
+// MockOutboundConfig defines a server-initiated message the embedded mock server sends to its clients.
+// Messages with an interval are sent on a schedule; the others are sent on demand ("serve push").
+type MockOutboundConfig struct {
+	Name           string                 `json:"name"`
+	Description    string                 `json:"description,omitempty"`
+	Fields         map[string]interface{} `json:"fields"`                     // Field values, "0" holds the MTI
+	IntervalMs     int                    `json:"interval_ms,omitempty"`      // Send period; each tick goes to the clients connected then, 0 for on-demand only
+	InitialDelayMs int                    `json:"initial_delay_ms,omitempty"` // Delay before the first scheduled send
+	Count          int                    `json:"count,omitempty"`            // Number of scheduled sends that reached a client, 0 for unlimited
+	Listener       string                 `json:"listener,omitempty"`         // Restrict the message to one named listener
+}
+
+// HeaderTypes lists the supported TCP length header formats
+var HeaderTypes = []string{"ascii4", "ebcdic4", "binary2", "binary4", "bcd2", "NAPS", "visa"}
+
+// MockListenerConfig defines one port of a multi-listener mock server. Each listener has its own
+// header format, spec, routes and statistics. Routes and outbound messages without a listener
+// are served on every listener.
+type MockListenerConfig struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Port        string `json:"port"`
+	Header      string `json:"header,omitempty"` // TCP length header format (default binary2)
+	Spec        string `json:"spec,omitempty"`   // Spec file, defaults to the spec given at startup
+
+	MinMessageSize int    `json:"min_message_size,omitempty"` // Smallest accepted request (default 1)
+	MaxMessageSize int    `json:"max_message_size,omitempty"` // Largest accepted request (default 65535)
+	OnInvalidFrame string `json:"on_invalid_frame,omitempty"` // close (default) or skip
+}
+
+// ValidateFrameLimits checks the listener's message size limits and invalid frame policy
+func (l MockListenerConfig) ValidateFrameLimits() error {
+	if l.MinMessageSize < 0 || l.MaxMessageSize < 0 {
+		return fmt.Errorf("message size limits must be non-negative")
+	}
+	if l.MaxMessageSize > 0 && l.MinMessageSize > l.MaxMessageSize {
+		return fmt.Errorf("min_message_size %d exceeds max_message_size %d", l.MinMessageSize, l.MaxMessageSize)
+	}
+	switch l.OnInvalidFrame {
+	case "", "close", "skip":
+		return nil
+	default:
+		return fmt.Errorf("unknown on_invalid_frame %q (valid: close, skip)", l.OnInvalidFrame)
+	}
+}
+
+// Length field encodings of a custom framing
+const (
+	LengthBinary = "binary"
+	LengthASCII  = "ascii"
+	LengthBCD    = "bcd"
+	LengthEBCDIC = "ebcdic"
+)
+
+// Trailers of a custom framing
+const (
+	TrailerETX    = "etx"     // ETX (0x03) after the message
+	TrailerETXLRC = "etx_lrc" // ETX followed by the XOR of the message bytes and the ETX
+)
+
+// FramingConfig describes a custom wire framing declaratively: a length field, an
+// optional fixed prefix and TPDU before the message, and an optional trailer after
+// it. The length counts everything after the length field (prefix, TPDU, message
+// and trailer), plus the length field itself with LengthIncludesHeader.
+type FramingConfig struct {
+	Name                 string `json:"name"`
+	Description          string `json:"description,omitempty"`
+	LengthBytes          int    `json:"length_bytes"`                     // Width of the length field in bytes (digits for ascii/ebcdic)
+	LengthEncoding       string `json:"length_encoding,omitempty"`        // binary (default), ascii, bcd or ebcdic
+	LengthIncludesHeader bool   `json:"length_includes_header,omitempty"` // The length counts the length field itself
+	Prefix               string `json:"prefix,omitempty"`                 // Fixed text after the length, e.g. "ISO016000070"
+	PrefixHex            string `json:"prefix_hex,omitempty"`             // Fixed bytes after the length, hex encoded
+	PrefixEncoding       string `json:"prefix_encoding,omitempty"`        // ascii (default) or ebcdic: how prefix text is sent
+	TPDU                 string `json:"tpdu,omitempty"`                   // 5-byte TPDU as 10 hex digits: ID, destination NII, source NII
+	Trailer              string `json:"trailer,omitempty"`                // etx or etx_lrc
+}
+
+// Validate checks the framing definition
+func (f FramingConfig) Validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("framing has empty name")
+	}
+	maxWidth := 4
+	switch f.LengthEncoding {
+	case "", LengthBinary, LengthBCD:
+	case LengthASCII, LengthEBCDIC:
+		maxWidth = 8
+	default:
+		return fmt.Errorf("framing '%s' has unknown length_encoding %q (valid: binary, ascii, bcd, ebcdic)", f.Name, f.LengthEncoding)
+	}
+	if f.LengthBytes < 1 || f.LengthBytes > maxWidth {
+		return fmt.Errorf("framing '%s' length_bytes must be between 1 and %d, got %d", f.Name, maxWidth, f.LengthBytes)
+	}
+	if f.Prefix != "" && f.PrefixHex != "" {
+		return fmt.Errorf("framing '%s' sets both prefix and prefix_hex", f.Name)
+	}
+	switch f.PrefixEncoding {
+	case "", LengthASCII, LengthEBCDIC:
+	default:
+		return fmt.Errorf("framing '%s' has unknown prefix_encoding %q (valid: ascii, ebcdic)", f.Name, f.PrefixEncoding)
+	}
+	if _, err := hex.DecodeString(f.PrefixHex); err != nil {
+		return fmt.Errorf("framing '%s' has invalid prefix_hex: %w", f.Name, err)
+	}
+	if f.TPDU != "" {
+		if b, err := hex.DecodeString(f.TPDU); err != nil || len(b) != 5 {
+			return fmt.Errorf("framing '%s' tpdu must be 10 hex digits, got %q", f.Name, f.TPDU)
+		}
+	}
+	switch f.Trailer {
+	case "", TrailerETX, TrailerETXLRC:
+	default:
+		return fmt.Errorf("framing '%s' has unknown trailer %q (valid: etx, etx_lrc)", f.Name, f.Trailer)
+	}
+	return nil
+}
+
+// ForListener reports whether a route tagged with listener applies to the named listener
+func ForListener(listener, name string) bool {
+	return listener == "" || listener == name
 }
 
 // ConfigItem represents a polymorphic configuration entry in the flat configuration array
@@ -47,14 +229,42 @@ type ConfigItem struct {
 	DatasetName    string                 `json:"dataset_name,omitempty"`
 	Steps          json.RawMessage        `json:"steps,omitempty"`
 	MatchFields    map[string]interface{} `json:"match_fields,omitempty"`
+	MatchExpr      string                 `json:"match_expr,omitempty"`
+	MatchConn      map[string]interface{} `json:"match_conn,omitempty"`
 	RequiredFields []string               `json:"required_fields,omitempty"`
 	EchoFields     []int                  `json:"echo_fields,omitempty"`
 	ResponseMTI    string                 `json:"response_mti,omitempty"`
 	ResponseFields map[string]interface{} `json:"response_fields,omitempty"`
+	ResponseHeader map[string]string      `json:"response_header,omitempty"`
 	DelayMs        int                    `json:"delay_ms,omitempty"`
 	LatencyMs      int                    `json:"latency_ms,omitempty"`
 	JitterMs       int                    `json:"jitter_ms,omitempty"`
 	DropConnection bool                   `json:"drop_connection,omitempty"`
+	NoResponse     bool                   `json:"no_response,omitempty"`
+	Fault          string                 `json:"fault,omitempty"`
+	StallMs        int                    `json:"stall_ms,omitempty"`
+	Priority       int                    `json:"priority,omitempty"`
+	Disabled       bool                   `json:"disabled,omitempty"`
+	Responses      []MockResponseVariant  `json:"responses,omitempty"`
+	State          *MockStateConfig       `json:"state,omitempty"`
+	IntervalMs     int                    `json:"interval_ms,omitempty"`
+	InitialDelayMs int                    `json:"initial_delay_ms,omitempty"`
+	Count          int                    `json:"count,omitempty"`
+	Listener       string                 `json:"listener,omitempty"`
+	Port           string                 `json:"port,omitempty"`
+	Header         string                 `json:"header,omitempty"`
+	Spec           string                 `json:"spec,omitempty"`
+	MinMessageSize int                    `json:"min_message_size,omitempty"`
+	MaxMessageSize int                    `json:"max_message_size,omitempty"`
+	OnInvalidFrame string                 `json:"on_invalid_frame,omitempty"`
+
+	LengthBytes          int    `json:"length_bytes,omitempty"`
+	LengthEncoding       string `json:"length_encoding,omitempty"`
+	LengthIncludesHeader bool   `json:"length_includes_header,omitempty"`
+	Prefix               string `json:"prefix,omitempty"`
+	PrefixHex            string `json:"prefix_hex,omitempty"`
+	TPDU                 string `json:"tpdu,omitempty"`
+	Trailer              string `json:"trailer,omitempty"`
 }
 
 // GetType returns the item discriminator, defaulting to "transaction" if unassigned
@@ -244,6 +454,11 @@ func SortInterfaceMapKeys(m map[string]interface{}) interface{} {
 	return ordered
 }
 
+// SwallowsRequest reports whether the route accepts a request without ever replying
+func (m *MockRouteConfig) SwallowsRequest() bool {
+	return m.NoResponse || m.Fault == FaultNoResponse
+}
+
 // GetTotalDelay calculates base latency + random jitter range in milliseconds
 func (m *MockRouteConfig) GetTotalDelay() time.Duration {
 	baseDelay := m.DelayMs
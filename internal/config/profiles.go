@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetworkProfile bundles the connection settings of a card network: the TCP header,
+// the spec and the network management messages and correlation key it expects.
+// Session steps use the sign-on/echo syntax: "auto:<code>" sends the built-in 0800
+// with that DE70 network management code.
+type NetworkProfile struct {
+	Name           string
+	Description    string
+	Header         string // TCP length header type
+	Spec           string // Spec file, empty to keep the loaded spec
+	SignOn         string
+	Echo           string
+	SignOff        string
+	CorrelationKey string
+}
+
+// NetworkProfiles lists the built-in connection profiles
+var NetworkProfiles = []NetworkProfile{
+	{
+		Name:           "mastercard",
+		Description:    "Mastercard MIP: 2-byte binary length, EBCDIC spec, group sign-on 061/062 and echo 270",
+		Header:         "binary2",
+		Spec:           "specs/mastercard.json",
+		SignOn:         "auto:061",
+		Echo:           "auto:270",
+		SignOff:        "auto:062",
+		CorrelationKey: "11+7",
+	},
+	{
+		Name:           "tsys_dhi",
+		Description:    "TSYS DHI: 2-byte binary length, sign-on 001/002 and echo 301, keyed by STAN and terminal",
+		Header:         "binary2",
+		Spec:           "specs/tsys_dhi.json",
+		SignOn:         "auto:001",
+		Echo:           "auto:301",
+		SignOff:        "auto:002",
+		CorrelationKey: "stan_terminal",
+	},
+	{
+		Name:           "discover",
+		Description:    "Discover: 2-byte binary length, sign-on 001/002 and echo 301 on the loaded spec",
+		Header:         "binary2",
+		SignOn:         "auto:001",
+		Echo:           "auto:301",
+		SignOff:        "auto:002",
+		CorrelationKey: "11+7",
+	},
+	{
+		Name:           "visa",
+		Description:    "VISA BASE I: 22-byte VISA header, sign-on 001/002 and echo 301, keyed by RRN",
+		Header:         "visa",
+		Spec:           "specs/visa.json",
+		SignOn:         "auto:001",
+		Echo:           "auto:301",
+		SignOff:        "auto:002",
+		CorrelationKey: "rrn",
+	},
+}
+
+// LookupProfile returns the built-in profile with the given name, ignoring case
+func LookupProfile(name string) (NetworkProfile, bool) {
+	for _, p := range NetworkProfiles {
+		if strings.EqualFold(p.Name, strings.TrimSpace(name)) {
+			return p, true
+		}
+	}
+	return NetworkProfile{}, false
+}
+
+// ProfileNames returns the names of the built-in profiles
+func ProfileNames() []string {
+	names := make([]string, len(NetworkProfiles))
+	for i, p := range NetworkProfiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// GetProfile returns the selected network profile name, empty for none
+func (c *Config) GetProfile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.profile
+}
+
+// SetProfile selects a network profile. It fills the settings the profile bundles that
+// were not given explicitly and replaces those a previous profile filled, so switching
+// profiles never keeps the old network's spec or session. A profile without a spec
+// keeps the loaded one. A bundled spec that cannot be found is an error, and leaves
+// the configuration unchanged.
+func (c *Config) SetProfile(name string) error {
+	p, ok := LookupProfile(name)
+	if !ok {
+		return fmt.Errorf("unknown profile '%s' (valid: %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p.Spec != "" && fromProfile(c.specFileName, c.profileApplied.Spec) {
+		spec, err := ResolveProfileSpec(p.Spec)
+		if err != nil {
+			return fmt.Errorf("%s profile: %w", p.Name, err)
+		}
+		p.Spec = spec
+	} else {
+		p.Spec = c.profileApplied.Spec
+	}
+
+	applied := NetworkProfile{Name: p.Name}
+	apply := func(setting *string, previous, value string) string {
+		if !fromProfile(*setting, previous) {
+			return ""
+		}
+		*setting = value
+		return value
+	}
+	applied.Spec = apply(&c.specFileName, c.profileApplied.Spec, p.Spec)
+	applied.SignOn = apply(&c.signOn, c.profileApplied.SignOn, p.SignOn)
+	applied.Echo = apply(&c.echoTx, c.profileApplied.Echo, p.Echo)
+	applied.SignOff = apply(&c.signOff, c.profileApplied.SignOff, p.SignOff)
+	applied.CorrelationKey = apply(&c.correlationKey, c.profileApplied.CorrelationKey, p.CorrelationKey)
+	c.profile = p.Name
+	c.profileApplied = applied
+	return nil
+}
+
+// fromProfile reports whether a setting is free for a profile: unset, or holding the
+// value the previous profile gave it rather than one set explicitly
+func fromProfile(setting, previous string) bool {
+	return setting == "" || (previous != "" && setting == previous)
+}
+
+// ResolveProfileSpec finds a spec bundled with jiso, such as "specs/visa.json": relative
+// to the working directory, then next to the jiso binary or one directory up, where
+// "make build" leaves it in bin/
+func ResolveProfileSpec(path string) (string, error) {
+	candidates := []string{path}
+	if exe, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		dir := filepath.Dir(exe)
+		candidates = append(candidates, filepath.Join(dir, path), filepath.Join(dir, "..", path))
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return filepath.Clean(candidate), nil
+		}
+	}
+	return "", fmt.Errorf("spec '%s' not found in the working directory or next to the jiso binary; give one with --spec", path)
+}
+
+// HeaderType returns the TCP header of the selected profile, or fallback without one
+func (c *Config) HeaderType(fallback string) string {
+	if p, ok := LookupProfile(c.GetProfile()); ok {
+		return p.Header
+	}
+	return fallback
+}
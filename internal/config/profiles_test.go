@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookupProfile(t *testing.T) {
+	for _, name := range []string{"mastercard", "tsys_dhi", "discover", "visa"} {
+		p, ok := LookupProfile(name)
+		if !ok {
+			t.Errorf("Expected built-in profile '%s'", name)
+			continue
+		}
+		if p.Header == "" || p.SignOn == "" || p.Echo == "" || p.CorrelationKey == "" {
+			t.Errorf("Profile '%s' is incomplete: %+v", name, p)
+		}
+	}
+
+	if p, ok := LookupProfile("MasterCard"); !ok || p.Name != "mastercard" {
+		t.Error("Expected profile lookup to ignore case")
+	}
+	if _, ok := LookupProfile("amex"); ok {
+		t.Error("Expected no profile named 'amex'")
+	}
+}
+
+func TestSetProfileFillsUnsetSettings(t *testing.T) {
+	c := &Config{specFileName: "my_spec.json", signOn: "logon", correlationKey: "rrn"}
+	if err := c.SetProfile("mastercard"); err != nil {
+		t.Fatalf("SetProfile failed: %v", err)
+	}
+
+	if c.GetProfile() != "mastercard" {
+		t.Errorf("Expected profile 'mastercard', got '%s'", c.GetProfile())
+	}
+	if c.GetSignOn() != "logon" {
+		t.Errorf("Expected explicit sign-on to be kept, got '%s'", c.GetSignOn())
+	}
+	if c.GetCorrelationKey() != "rrn" {
+		t.Errorf("Expected explicit correlation key to be kept, got '%s'", c.GetCorrelationKey())
+	}
+	if c.GetEchoTx() != "auto:270" {
+		t.Errorf("Expected echo 'auto:270', got '%s'", c.GetEchoTx())
+	}
+	if c.GetSignOff() != "auto:062" {
+		t.Errorf("Expected sign-off 'auto:062', got '%s'", c.GetSignOff())
+	}
+	if c.GetSpec() != "my_spec.json" {
+		t.Errorf("Expected explicit spec to be kept, got '%s'", c.GetSpec())
+	}
+	if c.HeaderType("ascii4") != "binary2" {
+		t.Errorf("Expected profile header 'binary2', got '%s'", c.HeaderType("ascii4"))
+	}
+}
+
+func TestSetProfileSwitchReplacesProfileSettings(t *testing.T) {
+	// Profile specs are relative to the repository root
+	wd, _ := os.Getwd()
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatalf("chdir failed: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	c := &Config{signOff: "logoff"}
+	if err := c.SetProfile("mastercard"); err != nil {
+		t.Fatalf("SetProfile failed: %v", err)
+	}
+	if c.GetSpec() != filepath.Join("specs", "mastercard.json") {
+		t.Errorf("Expected mastercard spec, got '%s'", c.GetSpec())
+	}
+
+	// Discover bundles no spec and keeps the loaded one
+	if err := c.SetProfile("discover"); err != nil {
+		t.Fatalf("SetProfile failed: %v", err)
+	}
+	if c.GetSpec() != filepath.Join("specs", "mastercard.json") {
+		t.Errorf("Expected the loaded spec to be kept, got '%s'", c.GetSpec())
+	}
+
+	if err := c.SetProfile("visa"); err != nil {
+		t.Fatalf("SetProfile failed: %v", err)
+	}
+	if c.GetSpec() != filepath.Join("specs", "visa.json") {
+		t.Errorf("Expected visa spec to replace mastercard's, got '%s'", c.GetSpec())
+	}
+	if c.GetSignOn() != "auto:001" || c.GetEchoTx() != "auto:301" {
+		t.Errorf("Expected visa session, got sign-on '%s' echo '%s'", c.GetSignOn(), c.GetEchoTx())
+	}
+	if c.GetCorrelationKey() != "rrn" {
+		t.Errorf("Expected visa correlation key 'rrn', got '%s'", c.GetCorrelationKey())
+	}
+	if c.GetSignOff() != "logoff" {
+		t.Errorf("Expected explicit sign-off to be kept, got '%s'", c.GetSignOff())
+	}
+	if c.HeaderType("ascii4") != "visa" {
+		t.Errorf("Expected visa header, got '%s'", c.HeaderType("ascii4"))
+	}
+}
+
+func TestSetProfileMissingSpec(t *testing.T) {
+	// The profile specs are not found from this package's directory
+	c := &Config{}
+	err := c.SetProfile("visa")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected missing spec error, got %v", err)
+	}
+	if c.GetProfile() != "" || c.GetSignOn() != "" {
+		t.Errorf("Expected the configuration to be left unchanged, got profile '%s'", c.GetProfile())
+	}
+}
+
+func TestSetProfileUnknown(t *testing.T) {
+	c := &Config{}
+	err := c.SetProfile("amex")
+	if err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Errorf("Expected unknown profile error, got %v", err)
+	}
+	if c.HeaderType("ascii4") != "ascii4" {
+		t.Errorf("Expected fallback header without a profile, got '%s'", c.HeaderType("ascii4"))
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// TLSConfig holds the certificate material for TLS client connections and the mock server.
+// On the client side CertFile/KeyFile are the client certificate presented for mutual TLS and
+// CAFile verifies the server. On the mock server CertFile/KeyFile are the server identity and
+// CAFile verifies client certificates.
+type TLSConfig struct {
+	Enabled            bool   `json:"enabled,omitempty"`
+	CertFile           string `json:"cert,omitempty"`
+	KeyFile            string `json:"key,omitempty"`
+	CAFile             string `json:"ca,omitempty"`
+	ServerName         string `json:"server_name,omitempty"` // SNI and the name verified in the server certificate
+	InsecureSkipVerify bool   `json:"insecure,omitempty"`    // Skip server certificate verification (test hosts only)
+	ClientAuth         bool   `json:"client_auth,omitempty"` // Mock server only: require a verified client certificate
+}
+
+// Active reports whether TLS is in use: explicitly enabled or implied by a certificate or CA
+func (t TLSConfig) Active() bool {
+	return t.Enabled || t.CertFile != "" || t.CAFile != ""
+}
+
+// Validate checks that certificate files exist and that a certificate comes with its key
+func (t TLSConfig) Validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("TLS certificate and key must be given together")
+	}
+	if t.ClientAuth && t.CAFile == "" {
+		return fmt.Errorf("TLS client authentication requires a CA file to verify client certificates")
+	}
+	for _, path := range []string{t.CertFile, t.KeyFile, t.CAFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("TLS file does not exist: %s", path)
+		}
+	}
+	return nil
+}
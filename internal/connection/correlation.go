@@ -0,0 +1,128 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/moov-io/iso8583"
+)
+
+// ErrDuplicateKey is returned when a request's correlation key is already held by a pending request
+var ErrDuplicateKey = errors.New("correlation key already in use by a pending request")
+
+// CorrelationKey lists the fields whose values pair a response with its request
+type CorrelationKey []int
+
+// DefaultCorrelationKey matches responses by STAN alone
+var DefaultCorrelationKey = CorrelationKey{11}
+
+// correlationPresets are the named keys accepted by ParseCorrelationKey
+var correlationPresets = map[string]CorrelationKey{
+	"stan":               {11},
+	"stan_terminal":      {11, 41},
+	"stan_terminal_date": {11, 41, 7},
+	"rrn":                {37},
+}
+
+// CorrelationPresets returns the names of the built-in correlation keys
+func CorrelationPresets() []string {
+	names := make([]string, 0, len(correlationPresets))
+	for name := range correlationPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseCorrelationKey accepts a preset name (stan, stan_terminal, stan_terminal_date, rrn)
+// or a list of field IDs joined by '+' or ',' such as "11+41+7"
+func ParseCorrelationKey(s string) (CorrelationKey, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return DefaultCorrelationKey, nil
+	}
+	if preset, ok := correlationPresets[s]; ok {
+		return preset, nil
+	}
+
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '+' || r == ',' })
+	key := make(CorrelationKey, 0, len(parts))
+	seen := make(map[int]bool)
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 2 || id > 128 {
+			return nil, fmt.Errorf("invalid correlation key '%s': '%s' is not a data field (2-128) or one of %s",
+				s, part, strings.Join(CorrelationPresets(), ", "))
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("invalid correlation key '%s': field %d listed twice", s, id)
+		}
+		seen[id] = true
+		key = append(key, id)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("invalid correlation key '%s'", s)
+	}
+	return key, nil
+}
+
+// String renders the key as a '+' separated field list
+func (k CorrelationKey) String() string {
+	ids := make([]string, len(k))
+	for i, id := range k {
+		ids[i] = strconv.Itoa(id)
+	}
+	return strings.Join(ids, "+")
+}
+
+// Of builds the correlation value of message. DE11 is normalized with NormalizeStan so
+// that "123" and "000123" correlate. It returns "" when none of the fields are present.
+func (k CorrelationKey) Of(message *iso8583.Message) string {
+	if message == nil {
+		return ""
+	}
+	values := make([]string, len(k))
+	present := false
+	for i, id := range k {
+		val, err := message.GetString(id)
+		if err != nil {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		if id == 11 {
+			val = NormalizeStan(val)
+		}
+		if val != "" {
+			present = true
+		}
+		values[i] = val
+	}
+	if !present {
+		return ""
+	}
+	return strings.Join(values, "|")
+}
+
+// DuplicatePolicy decides what a send does when its correlation key is already pending
+type DuplicatePolicy string
+
+const (
+	// DuplicateFail rejects the send with ErrDuplicateKey
+	DuplicateFail DuplicatePolicy = "fail"
+	// DuplicateQueue waits for the pending request to complete, up to the response timeout
+	DuplicateQueue DuplicatePolicy = "queue"
+)
+
+// ParseDuplicatePolicy accepts "fail" (the default) or "queue"
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch DuplicatePolicy(strings.ToLower(strings.TrimSpace(s))) {
+	case "", DuplicateFail:
+		return DuplicateFail, nil
+	case DuplicateQueue:
+		return DuplicateQueue, nil
+	}
+	return "", fmt.Errorf("invalid duplicate key policy '%s' (expected fail or queue)", s)
+}
@@ -0,0 +1,115 @@
+package connection
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moov-io/iso8583"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCorrelationKey(t *testing.T) {
+	key, err := ParseCorrelationKey("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCorrelationKey, key)
+
+	key, err = ParseCorrelationKey("stan_terminal_date")
+	require.NoError(t, err)
+	assert.Equal(t, CorrelationKey{11, 41, 7}, key)
+
+	key, err = ParseCorrelationKey("RRN")
+	require.NoError(t, err)
+	assert.Equal(t, CorrelationKey{37}, key)
+
+	key, err = ParseCorrelationKey("11+41, 7")
+	require.NoError(t, err)
+	assert.Equal(t, "11+41+7", key.String())
+
+	for _, bad := range []string{"stan+", "abc", "11+11", "1", "129"} {
+		_, err := ParseCorrelationKey(bad)
+		assert.Error(t, err, bad)
+	}
+
+	policy, err := ParseDuplicatePolicy("QUEUE")
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateQueue, policy)
+	_, err = ParseDuplicatePolicy("overwrite")
+	assert.Error(t, err)
+}
+
+func TestCorrelationKeyOf(t *testing.T) {
+	spec := mockMessageSpec()
+	msg := iso8583.NewMessage(spec)
+	require.NoError(t, msg.Field(0, "0200"))
+
+	key := CorrelationKey{11, 2}
+	assert.Equal(t, "", key.Of(msg), "no key fields present")
+	assert.Equal(t, "", key.Of(nil))
+
+	require.NoError(t, msg.Field(11, "123"))
+	assert.Equal(t, "000123|", key.Of(msg))
+
+	require.NoError(t, msg.Field(2, "4111111111111111"))
+	assert.Equal(t, "000123|4111111111111111", key.Of(msg))
+	assert.Equal(t, "000123", DefaultCorrelationKey.Of(msg))
+}
+
+func TestDuplicateCorrelationKey(t *testing.T) {
+	manager := NewManager("localhost", "8080", mockMessageSpec(), false, 3, time.Second, time.Second, nil)
+	manager.SetResponseTimeout(200 * time.Millisecond)
+
+	first := newPendingRequest(manager.GetResponseTimeout(), "first")
+	require.NoError(t, manager.addPending("000001", first, true))
+
+	// Default policy rejects the duplicate and leaves the original in place
+	second := newPendingRequest(manager.GetResponseTimeout(), "second")
+	err := manager.addPending("000001", second, true)
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	assert.Equal(t, int64(1), manager.DuplicateKeys())
+	assert.Same(t, first, manager.pendingRequests["000001"])
+
+	// Queue waits for the earlier request to leave the table
+	manager.SetDuplicatePolicy(DuplicateQueue)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		manager.takePending("000001", "")
+	}()
+	require.NoError(t, manager.addPending("000001", second, true))
+	assert.Same(t, second, manager.pendingRequests["000001"])
+
+	// ...but gives up after the response timeout
+	third := newPendingRequest(manager.GetResponseTimeout(), "third")
+	err = manager.addPending("000001", third, true)
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	assert.True(t, manager.removePending("000001", second))
+	assert.False(t, manager.removePending("000001", second))
+}
+
+func TestInboundDeliveredByCompositeKey(t *testing.T) {
+	spec := mockMessageSpec()
+	manager := NewManager("localhost", "8080", spec, false, 3, time.Second, time.Second, nil)
+	manager.SetCorrelationKey(CorrelationKey{11, 2})
+
+	// Two terminals legitimately reuse STAN 000042
+	a := newPendingRequest(time.Second, "a")
+	b := newPendingRequest(time.Second, "b")
+	require.NoError(t, manager.addPending("000042|4000000000000001", a, true))
+	require.NoError(t, manager.addPending("000042|4000000000000002", b, true))
+
+	resp := iso8583.NewMessage(spec)
+	require.NoError(t, resp.Field(0, "0210"))
+	require.NoError(t, resp.Field(11, "000042"))
+	require.NoError(t, resp.Field(2, "4000000000000002"))
+	manager.handleInboundMessage(resp)
+
+	select {
+	case got := <-b.responseChan:
+		assert.Same(t, resp, got)
+	case <-time.After(time.Second):
+		t.Fatal("response not delivered to the matching request")
+	}
+	assert.Len(t, a.responseChan, 0)
+	assert.Contains(t, manager.pendingRequests, "000042|4000000000000001")
+}
@@ -0,0 +1,52 @@
+package connection
+
+import (
+	"time"
+
+	"jiso/internal/utils"
+
+	"github.com/moov-io/iso8583"
+)
+
+// Network management information codes (DE70)
+const (
+	NetworkSignOn  = "001"
+	NetworkSignOff = "002"
+	NetworkEcho    = "301"
+)
+
+// NewNetworkMessage builds an 0800 network management request with the given DE70
+// code. DE7 and DE11 are filled when spec defines them.
+func NewNetworkMessage(spec *iso8583.MessageSpec, code string) *iso8583.Message {
+	msg := iso8583.NewMessage(spec)
+	msg.MTI("0800")
+	if _, ok := spec.Fields[7]; ok {
+		_ = msg.Field(7, time.Now().UTC().Format("0102150405"))
+	}
+	if _, ok := spec.Fields[11]; ok {
+		_ = msg.Field(11, utils.GetCounter().GetStan())
+	}
+	if _, ok := spec.Fields[70]; ok {
+		_ = msg.Field(70, code)
+	}
+	return msg
+}
+
+// NewEchoMessage builds an 0800 echo test (DE70 301)
+func NewEchoMessage(spec *iso8583.MessageSpec) *iso8583.Message {
+	return NewNetworkMessage(spec, NetworkEcho)
+}
+
+// EchoPassed reports whether resp answers a network management request
+// successfully: an x8x0 response (0810, 1814) without a response code, or with
+// response code 00
+func EchoPassed(resp *iso8583.Message) bool {
+	if resp == nil {
+		return false
+	}
+	if mti, _ := resp.GetMTI(); len(mti) != 4 || mti[1] != '8' || !utils.IsResponseMTI(mti) {
+		return false
+	}
+	rc, err := resp.GetString(39)
+	return err != nil || rc == "" || rc == "00"
+}
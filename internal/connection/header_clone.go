@@ -21,9 +21,10 @@ func cloneHeader(h network.Header) network.Header {
 		return network.NewASCII4BytesHeader()
 	case *network.BCD2BytesHeader:
 		return network.NewBCD2BytesHeader()
+	case *utils.FramingHeader:
+		return h.Clone()
 	case *utils.VisaHeader:
-		cloned, _ := utils.NewVisaHeader(h.RawStationID())
-		return cloned
+		return h.Clone()
 	}
 
 	// For unknown types, we return the original.
@@ -0,0 +1,55 @@
+package connection
+
+import (
+	"time"
+
+	"github.com/moov-io/iso8583"
+)
+
+// InboundMessage is a message from the target that no pending request took: an
+// unsolicited request or advice, or a response arriving after its request ended
+type InboundMessage struct {
+	Message  *iso8583.Message
+	Header   map[string]string // Header fields read with the message, for header types that have them
+	Received time.Time
+}
+
+// SubscribeInbound returns a channel receiving every inbound message no pending
+// request took, and a function ending the subscription. Messages are dropped while
+// the channel's buffer is full.
+func (m *Manager) SubscribeInbound(buffer int) (<-chan *InboundMessage, func()) {
+	ch := make(chan *InboundMessage, buffer)
+	m.inboundMu.Lock()
+	if m.inboundSubs == nil {
+		m.inboundSubs = make(map[int]chan *InboundMessage)
+	}
+	id := m.nextSub
+	m.nextSub++
+	m.inboundSubs[id] = ch
+	m.inboundMu.Unlock()
+
+	return ch, func() {
+		m.inboundMu.Lock()
+		defer m.inboundMu.Unlock()
+		if _, ok := m.inboundSubs[id]; ok {
+			delete(m.inboundSubs, id)
+			close(ch)
+		}
+	}
+}
+
+// publishInbound hands message and the header fields read with it to the inbound subscribers
+func (m *Manager) publishInbound(message *iso8583.Message, header map[string]string) {
+	m.inboundMu.Lock()
+	defer m.inboundMu.Unlock()
+	if len(m.inboundSubs) == 0 {
+		return
+	}
+	in := &InboundMessage{Message: message, Header: header, Received: time.Now()}
+	for _, ch := range m.inboundSubs {
+		select {
+		case ch <- in:
+		default:
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package connection
+
+import (
+	"testing"
+
+	"github.com/moov-io/iso8583"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeInbound(t *testing.T) {
+	m := &Manager{pendingRequests: make(map[string]*pendingRequest), correlationKey: DefaultCorrelationKey}
+	ch, cancel := m.SubscribeInbound(1)
+
+	advice := iso8583.NewMessage(mockMessageSpec())
+	advice.MTI("0620")
+	require.NoError(t, advice.Field(11, "000042"))
+
+	// Nothing is pending for the advice, so it reaches the subscriber
+	m.handleInboundMessage(advice)
+	m.publishInbound(advice, nil) // Dropped: the buffer is full
+
+	in := <-ch
+	assert.Same(t, advice, in.Message)
+	assert.False(t, in.Received.IsZero())
+	assert.Len(t, ch, 0)
+
+	cancel()
+	cancel()
+	_, open := <-ch
+	assert.False(t, open)
+	m.publishInbound(advice, nil) // No subscribers left
+}
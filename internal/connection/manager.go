@@ -2,11 +2,12 @@ package connection
 
 import (
 	"context"
-	"encoding/hex"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"jiso/internal/metrics"
@@ -29,19 +30,45 @@ type Manager struct {
 	totalConnectTimeout time.Duration
 	reconnecting        bool
 	reconnectMu         sync.Mutex
+	reconnects          int64 // Successful background reconnections
 	networkStats        *metrics.NetworkingStats
 	statusMu            sync.RWMutex // Protects connection status updates
 	mockMatcher         RouteMatcher
 
+	// Target group: targets[0] is the primary, address the active target
+	targets          []string
+	failbackInterval time.Duration
+	failbackRunning  bool
+
+	// Network management session
+	session       *Session
+	keepaliveStop chan struct{}
+	lastActivity  int64 // Unix nanoseconds of the last traffic on the link
+
 	// Connection parameters for reconnection
-	naps   bool
-	header network.Header
+	naps      bool
+	header    network.Header
+	tlsConfig *tls.Config // nil for plain TCP
+
+	frameLimits utils.FrameLimits // Accepted message sizes and invalid frame policy
+
+	// Header fields of inbound frames not handled yet, in read order, for header types that have fields
+	capturedHeaders []map[string]string
+	headersMu       sync.Mutex
+
+	// Subscribers to inbound messages no pending request took
+	inboundSubs map[int]chan *InboundMessage
+	nextSub     int
+	inboundMu   sync.Mutex
 
 	// Async processing fields
 	pendingRequests    map[string]*pendingRequest
 	pendingMu          sync.RWMutex
 	maxPendingRequests int // Maximum number of pending requests
 	responseTimeout    time.Duration
+	correlationKey     CorrelationKey
+	duplicatePolicy    DuplicatePolicy
+	duplicateKeys      int64 // Sends that found their correlation key already pending
 }
 
 func NewManager(
@@ -52,8 +79,11 @@ func NewManager(
 	connectTimeout, totalConnectTimeout time.Duration,
 	networkStats *metrics.NetworkingStats,
 ) *Manager {
+	address := fmt.Sprintf("%s:%s", host, port)
 	return &Manager{
-		address:             fmt.Sprintf("%s:%s", host, port),
+		address:             address,
+		targets:             []string{address},
+		failbackInterval:    DefaultFailbackInterval,
 		spec:                spec,
 		debugMode:           debugMode,
 		reconnectAttempts:   reconnectAttempts,
@@ -63,6 +93,9 @@ func NewManager(
 		pendingRequests:     make(map[string]*pendingRequest),
 		responseTimeout:     5 * time.Second, // Default 5s timeout
 		maxPendingRequests:  100,             // Default max 100 pending requests
+		correlationKey:      DefaultCorrelationKey,
+		duplicatePolicy:     DuplicateFail,
+		frameLimits:         utils.DefaultFrameLimits,
 	}
 }
 
@@ -85,15 +118,21 @@ func (m *Manager) Connect(naps bool, header network.Header) error {
 	var err error
 	// Clone headers for reading and writing to avoid race conditions
 	// Reader and Writer run in separate goroutines
+	utils.ApplyFrameLimits(header, m.frameLimits)
 	readHeader := cloneHeader(header)
 	writeHeader := cloneHeader(header)
 
-	readFunc := utils.ReadMessageLengthWrapper(readHeader)
+	readFunc := utils.ReadFrameLengthWrapper(readHeader, m.frameLimits)
 	writeFunc := utils.WriteMessageLengthWrapper(writeHeader)
 	if naps {
 		readFunc = utils.NapsReadLengthWrapper(readFunc)
 		writeFunc = utils.NapsWriteLengthWrapper(writeFunc)
 	}
+	readFunc = utils.SkipTrailerWrapper(readHeader, readFunc)
+	readFunc = m.captureHeaderWrapper(readHeader, readFunc)
+	m.headersMu.Lock()
+	m.capturedHeaders = nil
+	m.headersMu.Unlock()
 
 	// Add connection options with proper reconnection settings
 	options := []moovconnection.Option{
@@ -105,8 +144,9 @@ func (m *Manager) Connect(naps bool, header network.Header) error {
 
 			var unpackErr *iso8583errors.UnpackError
 			if errors.As(err, &unpackErr) {
+				m.takeHeader() // The frame is not handled as a message
 				fmt.Printf("Unpack error: %s\n", unpackErr)
-				fmt.Printf("\n%v\n", hex.Dump(unpackErr.RawMessage))
+				fmt.Printf("\n%v\n", utils.HexDump(unpackErr.RawMessage, utils.SpecCodePage(m.GetSpec())))
 				return
 			}
 
@@ -137,12 +177,35 @@ func (m *Manager) Connect(naps bool, header network.Header) error {
 			return nil
 		}),
 		moovconnection.ConnectionClosedHandler(func(c *moovconnection.Connection) {
+			c.SetStatus(moovconnection.StatusOffline)
 			if m.debugMode {
 				fmt.Printf("Connection closed to %s\n", m.address)
 			}
+			// Without reconnect attempts nothing else reacts to the loss, so go straight to failover
+			if m.reconnectAttempts == 0 {
+				go func() {
+					m.statusMu.RLock()
+					current := m.Connection == c
+					m.statusMu.RUnlock()
+					if current && m.HasSecondaryTargets() {
+						m.attemptReconnect()
+					}
+				}()
+			}
 		}),
 	}
 
+	if m.tlsConfig != nil {
+		tlsConfig := m.tlsConfig
+		options = append(options, moovconnection.SetTLSConfig(func(c *tls.Config) {
+			c.MinVersion = tlsConfig.MinVersion
+			c.Certificates = tlsConfig.Certificates
+			c.RootCAs = tlsConfig.RootCAs
+			c.ServerName = tlsConfig.ServerName
+			c.InsecureSkipVerify = tlsConfig.InsecureSkipVerify
+		}))
+	}
+
 	// Attempt to connect with retries and exponential backoff
 	maxBackoff := 30 * time.Second
 	baseDelay := 1 * time.Second
@@ -248,9 +311,59 @@ func (m *Manager) Connect(naps bool, header network.Header) error {
 		break
 	}
 
+	if m.IsConnected() {
+		m.startSession()
+	}
 	return nil
 }
 
+// sibling returns an unconnected manager with the same target and settings as m
+func (m *Manager) sibling() *Manager {
+	m.statusMu.RLock()
+	address, spec, matcher := m.address, m.spec, m.mockMatcher
+	targets := append([]string(nil), m.targets...)
+	m.statusMu.RUnlock()
+	m.pendingMu.RLock()
+	correlationKey, duplicatePolicy := m.correlationKey, m.duplicatePolicy
+	m.pendingMu.RUnlock()
+
+	return &Manager{
+		address:             address,
+		targets:             targets,
+		failbackInterval:    m.failbackInterval,
+		spec:                spec,
+		debugMode:           m.debugMode,
+		reconnectAttempts:   m.reconnectAttempts,
+		connectTimeout:      m.connectTimeout,
+		totalConnectTimeout: m.totalConnectTimeout,
+		networkStats:        m.networkStats,
+		mockMatcher:         matcher,
+		session:             m.session,
+		tlsConfig:           m.tlsConfig,
+		frameLimits:         m.frameLimits,
+		pendingRequests:     make(map[string]*pendingRequest),
+		responseTimeout:     m.responseTimeout,
+		maxPendingRequests:  m.maxPendingRequests,
+		correlationKey:      correlationKey,
+		duplicatePolicy:     duplicatePolicy,
+	}
+}
+
+// SetTLSConfig enables TLS for subsequent connections; nil switches back to plain TCP
+func (m *Manager) SetTLSConfig(cfg *tls.Config) {
+	m.tlsConfig = cfg
+}
+
+// SetFrameLimits sets the accepted message sizes and what happens to invalid frames from the next connect
+func (m *Manager) SetFrameLimits(limits utils.FrameLimits) {
+	m.frameLimits = limits
+}
+
+// GetFrameLimits returns the accepted message sizes and invalid frame policy
+func (m *Manager) GetFrameLimits() utils.FrameLimits {
+	return m.frameLimits
+}
+
 // GetSpec returns the current ISO8583 message specification
 func (m *Manager) GetSpec() *iso8583.MessageSpec {
 	m.statusMu.RLock()
@@ -290,11 +403,12 @@ func (m *Manager) GetAddress() string {
 	return m.address
 }
 
-// SetAddress updates the connection address
+// SetAddress updates the primary target and makes it the active one
 func (m *Manager) SetAddress(host, port string) {
 	m.statusMu.Lock()
 	defer m.statusMu.Unlock()
 	m.address = fmt.Sprintf("%s:%s", host, port)
+	m.targets[0] = m.address
 }
 
 // Close closes the connection
@@ -304,11 +418,14 @@ func (m *Manager) Close() error {
 	m.pendingMu.Lock()
 
 	// Clear pending requests
-	for stan, req := range m.pendingRequests {
+	for key, req := range m.pendingRequests {
 		close(req.responseChan)
-		delete(m.pendingRequests, stan)
+		close(req.done)
+		delete(m.pendingRequests, key)
 	}
 
+	m.stopKeepalive()
+
 	var closeErr error
 	if m.Connection != nil {
 		// Explicitly set status to offline before closing
@@ -364,4 +481,54 @@ func (m *Manager) GetMaxPendingRequests() int {
 	return m.maxPendingRequests
 }
 
+// SetCorrelationKey sets the fields used to pair responses with pending requests
+func (m *Manager) SetCorrelationKey(key CorrelationKey) {
+	if len(key) == 0 {
+		key = DefaultCorrelationKey
+	}
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	m.correlationKey = key
+}
+
+// GetCorrelationKey returns the fields used to pair responses with pending requests
+func (m *Manager) GetCorrelationKey() CorrelationKey {
+	m.pendingMu.RLock()
+	defer m.pendingMu.RUnlock()
+	return m.correlationKey
+}
+
+// SetDuplicatePolicy sets what a send does when its correlation key is already pending
+func (m *Manager) SetDuplicatePolicy(policy DuplicatePolicy) {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	m.duplicatePolicy = policy
+}
+
+// DuplicateKeys returns how many sends found their correlation key already pending
+func (m *Manager) DuplicateKeys() int64 {
+	m.pendingMu.RLock()
+	defer m.pendingMu.RUnlock()
+	return m.duplicateKeys
+}
+
+// PendingCount returns the number of requests awaiting a response
+func (m *Manager) PendingCount() int {
+	m.pendingMu.RLock()
+	defer m.pendingMu.RUnlock()
+	return len(m.pendingRequests)
+}
+
+// Reconnects returns how many background reconnections succeeded
+func (m *Manager) Reconnects() int64 {
+	return atomic.LoadInt64(&m.reconnects)
+}
+
+// IsReconnecting reports whether a background reconnection is in progress
+func (m *Manager) IsReconnecting() bool {
+	m.reconnectMu.Lock()
+	defer m.reconnectMu.Unlock()
+	return m.reconnecting
+}
+
 // attemptReconnect tries to reconnect in the background with exponential backoff
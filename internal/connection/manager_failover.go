@@ -0,0 +1,237 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/moov-io/iso8583/network"
+)
+
+// ErrNoSecondaryTarget is returned when a failover is requested without secondary targets
+var ErrNoSecondaryTarget = errors.New("no secondary target configured")
+
+// DefaultFailbackInterval is how often the primary is echo tested while on a secondary
+const DefaultFailbackInterval = 30 * time.Second
+
+// SetSecondaryTargets sets the standby targets tried, in order, when the primary fails
+func (m *Manager) SetSecondaryTargets(addresses []string) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	m.targets = append([]string{m.targets[0]}, addresses...)
+}
+
+// Targets returns the target group, primary first
+func (m *Manager) Targets() []string {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+	return slices.Clone(m.targets)
+}
+
+// HasSecondaryTargets reports whether the manager can fail over
+func (m *Manager) HasSecondaryTargets() bool {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+	return len(m.targets) > 1
+}
+
+// OnPrimary reports whether the active target is the primary
+func (m *Manager) OnPrimary() bool {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+	return m.address == m.targets[0]
+}
+
+// SetFailbackInterval sets how often the primary is echo tested while on a secondary; zero disables failback
+func (m *Manager) SetFailbackInterval(interval time.Duration) {
+	m.reconnectMu.Lock()
+	defer m.reconnectMu.Unlock()
+	m.failbackInterval = interval
+}
+
+// Failover moves the connection to the next reachable target of the group
+func (m *Manager) Failover(reason string) error {
+	if !m.HasSecondaryTargets() {
+		return ErrNoSecondaryTarget
+	}
+	if !m.beginSwitch() {
+		return fmt.Errorf("reconnection to %s already in progress", m.GetAddress())
+	}
+	defer m.endSwitch()
+	return m.failover(reason)
+}
+
+// connectGroup connects to the active target and fails over when that fails
+func (m *Manager) connectGroup(naps bool, header network.Header) error {
+	err := m.Connect(naps, header)
+	if err == nil || !m.HasSecondaryTargets() {
+		return err
+	}
+	if !m.beginSwitch() {
+		return err
+	}
+	defer m.endSwitch()
+	return m.failover("connect attempts exhausted")
+}
+
+// beginSwitch claims the reconnecting flag so that one goroutine at a time moves the connection
+func (m *Manager) beginSwitch() bool {
+	m.reconnectMu.Lock()
+	defer m.reconnectMu.Unlock()
+	if m.reconnecting {
+		return false
+	}
+	m.reconnecting = true
+	return true
+}
+
+func (m *Manager) endSwitch() {
+	m.reconnectMu.Lock()
+	m.reconnecting = false
+	m.reconnectMu.Unlock()
+}
+
+// failover tries every other target of the group once, in order after the active
+// one. The caller must hold the reconnecting flag.
+func (m *Manager) failover(reason string) error {
+	m.statusMu.RLock()
+	targets := slices.Clone(m.targets)
+	from := m.address
+	m.statusMu.RUnlock()
+	if len(targets) < 2 {
+		return ErrNoSecondaryTarget
+	}
+
+	start := max(slices.Index(targets, from), 0)
+	var lastErr error
+	for i := 1; i < len(targets); i++ {
+		to := targets[(start+i)%len(targets)]
+		fmt.Printf("Failing over from %s to %s (%s)\n", from, to, reason)
+		if err := m.switchTo(to); err != nil {
+			fmt.Printf("Failover to %s failed: %v\n", to, err)
+			lastErr = err
+			continue
+		}
+		if m.networkStats != nil {
+			m.networkStats.RecordTargetSwitch(from, to, reason, false)
+		}
+		if to != targets[0] {
+			m.startFailback()
+		}
+		return nil
+	}
+
+	// Nothing answered: the next connect starts again from the primary
+	m.statusMu.Lock()
+	m.address = m.targets[0]
+	m.statusMu.Unlock()
+	return fmt.Errorf("failover from %s failed, no target of the group accepted the connection: %w", from, lastErr)
+}
+
+// switchTo makes address the active target and connects to it
+func (m *Manager) switchTo(address string) error {
+	m.statusMu.Lock()
+	m.address = address
+	m.statusMu.Unlock()
+	return m.Connect(m.naps, m.header)
+}
+
+// startFailback launches the primary echo test loop unless it is already running
+func (m *Manager) startFailback() {
+	m.reconnectMu.Lock()
+	defer m.reconnectMu.Unlock()
+	if m.failbackRunning || m.failbackInterval <= 0 {
+		return
+	}
+	m.failbackRunning = true
+	go m.runFailback(m.failbackInterval)
+}
+
+// runFailback echo tests the primary while a secondary is active and moves the
+// connection back once the primary answers. It ends when the session is closed.
+func (m *Manager) runFailback(interval time.Duration) {
+	defer func() {
+		m.reconnectMu.Lock()
+		m.failbackRunning = false
+		m.reconnectMu.Unlock()
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if m.OnPrimary() {
+			return
+		}
+		if m.IsReconnecting() {
+			continue
+		}
+		if !m.IsConnected() {
+			return
+		}
+
+		primary := m.Targets()[0]
+		err := m.probe(primary)
+		if m.networkStats != nil {
+			m.networkStats.RecordHealthCheck(err == nil)
+		}
+		if err != nil {
+			if m.debugMode {
+				fmt.Printf("Primary %s still unavailable: %v\n", primary, err)
+			}
+			continue
+		}
+
+		if !m.beginSwitch() {
+			continue
+		}
+		from := m.GetAddress()
+		fmt.Printf("Primary %s passed echo test, failing back from %s\n", primary, from)
+		err = m.switchTo(primary)
+		m.endSwitch()
+		if err != nil {
+			fmt.Printf("Failback to %s failed: %v\n", primary, err)
+			continue
+		}
+		if m.networkStats != nil {
+			m.networkStats.RecordTargetSwitch(from, primary, "primary passed echo test", true)
+		}
+		return
+	}
+}
+
+// probe opens a separate connection to address and sends an echo test on it
+func (m *Manager) probe(address string) error {
+	p := m.sibling()
+	p.address = address
+	p.targets = []string{address}
+	p.reconnectAttempts = 0
+	p.networkStats = nil
+	p.mockMatcher = nil
+	p.session = nil
+	p.debugMode = false
+
+	if err := p.Connect(m.naps, m.header); err != nil {
+		return err
+	}
+	defer p.Close()
+
+	// The session echo carries the network's own echo code, if one is configured
+	msg := NewEchoMessage(p.GetSpec())
+	if session := m.GetSession(); session != nil && session.Echo != nil {
+		built, err := session.Echo(p.GetSpec())
+		if err != nil {
+			return fmt.Errorf("failed to build echo test: %w", err)
+		}
+		msg = built
+	}
+
+	resp, err := p.Send(msg)
+	if err != nil {
+		return err
+	}
+	if !EchoPassed(resp) {
+		return fmt.Errorf("echo test to %s was declined", address)
+	}
+	return nil
+}
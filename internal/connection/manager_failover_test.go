@@ -0,0 +1,82 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"jiso/internal/metrics"
+	"jiso/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetGroup(t *testing.T) {
+	manager := NewManager("primary", "9000", mockMessageSpec(), false, 0, time.Second, time.Second, nil)
+	assert.False(t, manager.HasSecondaryTargets())
+	assert.True(t, errors.Is(manager.Failover("test"), ErrNoSecondaryTarget))
+
+	manager.SetSecondaryTargets([]string{"backup1:9000", "backup2:9000"})
+	assert.True(t, manager.HasSecondaryTargets())
+	assert.Equal(t, []string{"primary:9000", "backup1:9000", "backup2:9000"}, manager.Targets())
+
+	// Changing the primary keeps the standby targets
+	manager.SetAddress("other", "9100")
+	assert.Equal(t, []string{"other:9100", "backup1:9000", "backup2:9000"}, manager.Targets())
+	assert.True(t, manager.OnPrimary())
+	assert.Equal(t, manager.Targets(), manager.sibling().Targets())
+}
+
+// freePort returns a local port that nothing listens on
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+	return port
+}
+
+func TestFailoverAndFailback(t *testing.T) {
+	spec := mockMessageSpec()
+	secondary, err := startTestServer(spec, true)
+	require.NoError(t, err)
+	defer secondary.Close()
+
+	stats := metrics.NewNetworkingStats()
+	primaryPort := freePort(t)
+	manager := NewManager("localhost", fmt.Sprintf("%d", primaryPort), spec, false, 0, time.Second, time.Second, stats)
+	manager.SetSecondaryTargets([]string{fmt.Sprintf("localhost:%d", secondary.port())})
+	manager.SetFailbackInterval(100 * time.Millisecond)
+
+	// The primary is down, so connecting lands on the secondary
+	require.NoError(t, manager.connectGroup(false, utils.NewBinary2BytesAdapter()))
+	defer manager.Close()
+	assert.True(t, manager.IsConnected())
+	assert.False(t, manager.OnPrimary())
+	assert.Equal(t, int64(1), stats.Failovers())
+
+	// Bring the primary up; the echo test passes and the connection moves back
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", primaryPort))
+	require.NoError(t, err)
+	primary := &testServer{
+		listener: listener,
+		spec:     spec,
+		header:   utils.NewBinary2BytesAdapter(),
+		respond:  true,
+		done:     make(chan struct{}),
+	}
+	go primary.run()
+	defer primary.Close()
+
+	require.Eventually(t, manager.OnPrimary, 5*time.Second, 50*time.Millisecond)
+	assert.True(t, manager.IsConnected())
+	assert.Equal(t, int64(1), stats.Failbacks())
+
+	switches := stats.TargetSwitches()
+	require.Len(t, switches, 2)
+	assert.Equal(t, "connect attempts exhausted", switches[0].Reason)
+	assert.True(t, switches[1].Failback)
+}
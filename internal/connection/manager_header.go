@@ -0,0 +1,50 @@
+package connection
+
+import (
+	"io"
+
+	"jiso/internal/utils"
+
+	moovconnection "github.com/moov-io/iso8583-connection"
+	"github.com/moov-io/iso8583/network"
+)
+
+// maxCapturedHeaders bounds the header fields kept for inbound messages not handled yet
+const maxCapturedHeaders = 64
+
+// captureHeaderWrapper queues the fields of every header read. Frames are read one at a
+// time, so the queue is in read order and each inbound message takes the oldest entry
+// with takeHeader; frames that never become a handled message take theirs as well, so
+// later messages stay aligned with their own headers.
+func (m *Manager) captureHeaderWrapper(header network.Header, read moovconnection.MessageLengthReader) moovconnection.MessageLengthReader {
+	fh, ok := header.(utils.FieldHeader)
+	if !ok {
+		return read
+	}
+	return func(r io.Reader) (int, error) {
+		length, err := read(r)
+		if err != nil {
+			return length, err
+		}
+		m.headersMu.Lock()
+		m.capturedHeaders = append(m.capturedHeaders, fh.HeaderFields())
+		if len(m.capturedHeaders) > maxCapturedHeaders {
+			m.capturedHeaders = m.capturedHeaders[1:]
+		}
+		m.headersMu.Unlock()
+		return length, nil
+	}
+}
+
+// takeHeader removes and returns the header fields of the oldest frame not handled yet,
+// or nil when the header type has none
+func (m *Manager) takeHeader() map[string]string {
+	m.headersMu.Lock()
+	defer m.headersMu.Unlock()
+	if len(m.capturedHeaders) == 0 {
+		return nil
+	}
+	fields := m.capturedHeaders[0]
+	m.capturedHeaders = m.capturedHeaders[1:]
+	return fields
+}
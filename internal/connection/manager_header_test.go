@@ -0,0 +1,94 @@
+package connection
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"jiso/internal/utils"
+
+	"github.com/moov-io/iso8583"
+	"github.com/moov-io/iso8583/encoding"
+	"github.com/moov-io/iso8583/field"
+	"github.com/moov-io/iso8583/prefix"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapturedHeadersFollowReadOrder(t *testing.T) {
+	spec := mockMessageSpec()
+	spec.Fields[52] = field.NewBinary(&field.Spec{
+		Length:      8,
+		Description: "PIN Data",
+		Enc:         encoding.ASCIIHexToBytes,
+		Pref:        prefix.ASCII.Fixed,
+	})
+
+	// The peer sends lowercase hex, which repacks as uppercase
+	build := func(stan string) []byte {
+		msg := iso8583.NewMessage(spec)
+		msg.MTI("0210")
+		require.NoError(t, msg.Field(11, stan))
+		require.NoError(t, msg.BinaryField(52, []byte{0xAB, 0xCD, 0xEF, 0x01, 0x23, 0x45, 0x67, 0x89}))
+		packed, err := msg.Pack()
+		require.NoError(t, err)
+		return bytes.Replace(packed, []byte("ABCDEF"), []byte("abcdef"), 1)
+	}
+
+	writeHeader, err := utils.NewVisaHeader("123456")
+	require.NoError(t, err)
+	var stream bytes.Buffer
+	for _, frame := range []struct {
+		stan, rejectCode string
+	}{{"000001", "0542"}, {"000002", ""}} {
+		require.NoError(t, writeHeader.SetHeaderField("reject_code", frame.rejectCode))
+		wire := build(frame.stan)
+		writeHeader.SetLength(len(wire))
+		_, err := writeHeader.WriteTo(&stream)
+		require.NoError(t, err)
+		stream.Write(wire)
+	}
+
+	m := NewManager("localhost", "8080", spec, false, 3, time.Second, time.Second, nil)
+	inbound, cancel := m.SubscribeInbound(2)
+	defer cancel()
+
+	// Read both frames before handling either, as the connection's read loop may
+	readHeader, err := utils.NewVisaHeader("000000")
+	require.NoError(t, err)
+	read := m.captureHeaderWrapper(readHeader, func(r io.Reader) (int, error) {
+		_, err := readHeader.ReadFrom(r)
+		return readHeader.Length(), err
+	})
+	var messages []*iso8583.Message
+	for range 2 {
+		length, err := read(&stream)
+		require.NoError(t, err)
+		wire := make([]byte, length)
+		_, err = io.ReadFull(&stream, wire)
+		require.NoError(t, err)
+
+		msg := iso8583.NewMessage(spec)
+		require.NoError(t, msg.Unpack(wire))
+		repacked, err := msg.Pack()
+		require.NoError(t, err)
+		require.NotEqual(t, wire, repacked)
+		messages = append(messages, msg)
+	}
+
+	for _, msg := range messages {
+		m.handleInboundMessage(msg)
+	}
+	first := <-inbound
+	assert.Same(t, messages[0], first.Message)
+	assert.Equal(t, "0542", first.Header["reject_code"])
+	assert.Equal(t, "123456", first.Header["source_station"])
+	second := <-inbound
+	assert.Same(t, messages[1], second.Message)
+	assert.NotContains(t, second.Header, "reject_code")
+	assert.Equal(t, "123456", second.Header["source_station"])
+
+	// Nothing is left for later messages
+	assert.Nil(t, m.takeHeader())
+}
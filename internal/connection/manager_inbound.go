@@ -3,6 +3,7 @@ package connection
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"jiso/internal/config"
@@ -43,33 +44,33 @@ func getStan(message *iso8583.Message) string {
 // Manager handles connections to ISO8583 servers
 
 func (m *Manager) handleInboundMessage(message *iso8583.Message) {
-	// Get normalized STAN from response if present
-	stan := getStan(message)
+	m.touch()
+	header := m.takeHeader()
+
+	// Get correlation key from response if present
+	key := m.correlationKeyOf(message)
 	mti, _ := message.GetMTI()
 
 	// Check if message is a response MTI (e.g., 0810, 0110, 0210, 0410 - 3rd digit is odd 1/3/5)
 	isResponse := len(mti) == 4 && (mti[2] == '1' || mti[2] == '3' || mti[2] == '5')
 
 	var pending *pendingRequest
-	var exists bool
-	if stan != "" {
+	if key != "" {
 		// Find and remove pending request atomically
-		m.pendingMu.Lock()
-		pending, exists = m.pendingRequests[stan]
-		if exists {
-			delete(m.pendingRequests, stan)
-		}
-		m.pendingMu.Unlock()
+		pending = m.takePending(key, mti)
 	}
+	exists := pending != nil
 
 	if exists && pending != nil {
+		pending.header = header
+
 		// Send response to waiting goroutine with timeout protection
 		select {
 		case pending.responseChan <- message:
 			// Successfully sent response
 		case <-time.After(100 * time.Millisecond):
 			if m.debugMode {
-				fmt.Printf("Timeout sending inbound message to channel for STAN %s\n", stan)
+				fmt.Printf("Timeout sending inbound message to channel for key %s\n", key)
 			}
 			// Close the channel to signal completion
 			close(pending.responseChan)
@@ -77,6 +78,8 @@ func (m *Manager) handleInboundMessage(message *iso8583.Message) {
 		return
 	}
 
+	m.publishInbound(message, header)
+
 	// If this is a response to a synchronous Send() call, iso8583-connection matches it internally.
 	// We don't want to log unsolicited warning or trigger mock route matchers for response messages.
 	if isResponse {
@@ -90,7 +93,7 @@ func (m *Manager) handleInboundMessage(message *iso8583.Message) {
 			// Successfully sent response
 		case <-time.After(100 * time.Millisecond):
 			if m.debugMode {
-				fmt.Printf("Timeout sending inbound message to channel for STAN %s\n", stan)
+				fmt.Printf("Timeout sending inbound message to channel for key %s\n", key)
 			}
 			// Close the channel to signal completion
 			close(pending.responseChan)
@@ -122,6 +125,10 @@ func (m *Manager) handleInboundMessage(message *iso8583.Message) {
 					_ = m.Close()
 					return
 				}
+				if matchedRoute.SwallowsRequest() {
+					fmt.Printf("\n[CLIENT-UNSOLICITED] 🔇 Matched Route '%s' for MTI %s -> Swallowing request (no response)\n", routeName, mti)
+					return
+				}
 			}
 
 			respCode := ""
@@ -141,13 +148,13 @@ func (m *Manager) handleInboundMessage(message *iso8583.Message) {
 			m.statusMu.RUnlock()
 
 			if conn != nil {
-				if err := conn.Reply(resp); err != nil && m.debugMode {
+				if err := m.reply(conn, resp); err != nil && m.debugMode {
 					fmt.Printf("\n[CLIENT-UNSOLICITED] ❌ Error sending reply: %v\n", err)
 				}
 			}
 		}(message)
 	} else if m.debugMode {
-		fmt.Printf("Unmatched inbound message received for STAN %s\n", stan)
+		fmt.Printf("Unmatched inbound message received for key %s\n", key)
 	}
 }
 
@@ -198,6 +205,7 @@ func (m *Manager) attemptReconnect() {
 		startTime := time.Now()
 		err := m.Connect(m.naps, m.header)
 		if err == nil {
+			atomic.AddInt64(&m.reconnects, 1)
 			if m.networkStats != nil {
 				duration := time.Since(startTime)
 				m.networkStats.RecordReconnectSuccess(duration)
@@ -220,4 +228,10 @@ func (m *Manager) attemptReconnect() {
 	if m.debugMode {
 		fmt.Printf("All reconnection attempts failed\n")
 	}
+
+	if m.HasSecondaryTargets() && !m.IsConnected() {
+		if err := m.failover("reconnect attempts exhausted"); err != nil {
+			fmt.Printf("%v\n", err)
+		}
+	}
 }
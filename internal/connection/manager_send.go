@@ -2,7 +2,7 @@ package connection
 
 import (
 	"bytes"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,25 +12,142 @@ import (
 	moovconnection "github.com/moov-io/iso8583-connection"
 )
 
+// ErrResponseTimeout is returned when a request sent to the target gets no response in time
+var ErrResponseTimeout = errors.New("response timeout")
+
 type pendingRequest struct {
 	responseChan    chan *iso8583.Message
+	done            chan struct{} // Closed once the request leaves the pending table
 	timeout         time.Time
 	transactionName string
+	header          map[string]string // Header fields read with the response, set before it is delivered
+	mti             string            // MTI of the request, which only its response MTI answers
+}
+
+func newPendingRequest(timeout time.Duration, transactionName string) *pendingRequest {
+	return &pendingRequest{
+		responseChan:    make(chan *iso8583.Message, 1),
+		done:            make(chan struct{}),
+		timeout:         time.Now().Add(timeout),
+		transactionName: transactionName,
+	}
+}
+
+// correlationKeyOf returns the correlation value of msg under the configured key
+func (m *Manager) correlationKeyOf(msg *iso8583.Message) string {
+	m.pendingMu.RLock()
+	key := m.correlationKey
+	m.pendingMu.RUnlock()
+	return key.Of(msg)
+}
+
+// addPending registers p under key. A key that is already pending is never
+// overwritten: the send fails or, with DuplicateQueue, waits for the earlier
+// request to complete for at most the response timeout.
+func (m *Manager) addPending(key string, p *pendingRequest, enforceLimit bool) error {
+	deadline := time.After(m.responseTimeout)
+	for {
+		m.pendingMu.Lock()
+		existing, duplicate := m.pendingRequests[key]
+		if !duplicate {
+			if enforceLimit && len(m.pendingRequests) >= m.maxPendingRequests {
+				m.pendingMu.Unlock()
+				return fmt.Errorf("maximum pending requests limit reached (%d)", m.maxPendingRequests)
+			}
+			m.pendingRequests[key] = p
+			m.pendingMu.Unlock()
+			return nil
+		}
+		m.duplicateKeys++
+		policy := m.duplicatePolicy
+		m.pendingMu.Unlock()
+
+		if policy != DuplicateQueue {
+			return fmt.Errorf("%w: %s", ErrDuplicateKey, key)
+		}
+		select {
+		case <-existing.done:
+		case <-deadline:
+			return fmt.Errorf("%w: %s still pending after %v", ErrDuplicateKey, key, m.responseTimeout)
+		}
+	}
+}
+
+// removePending drops p from the pending table if it is still registered under key
+func (m *Manager) removePending(key string, p *pendingRequest) bool {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	if current, ok := m.pendingRequests[key]; ok && current == p {
+		delete(m.pendingRequests, key)
+		close(p.done)
+		return true
+	}
+	return false
+}
+
+// takePending removes and returns the request registered under key when respMTI answers it
+func (m *Manager) takePending(key, respMTI string) *pendingRequest {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	p, ok := m.pendingRequests[key]
+	if !ok {
+		return nil
+	}
+	// A reversal keeps the original's key, so a late response to the original
+	// must not be taken for the reversal's
+	if !answersMTI(p.mti, respMTI) {
+		if m.debugMode {
+			fmt.Printf("Ignoring %s for key %s: it does not answer the pending %s\n", respMTI, key, p.mti)
+		}
+		return nil
+	}
+	delete(m.pendingRequests, key)
+	close(p.done)
+	return p
+}
+
+// answersMTI reports whether respMTI is the response MTI of reqMTI: same version and
+// class with the response function, so 0110 answers 0100 and 0410 answers 0400 or
+// its repeat 0401. An unknown MTI on either side answers anything.
+func answersMTI(reqMTI, respMTI string) bool {
+	if len(reqMTI) != 4 || len(respMTI) != 4 {
+		return true
+	}
+	return respMTI[:2] == reqMTI[:2] && respMTI[2] == reqMTI[2]|1 && reqMTI[2] != respMTI[2]
 }
 
 // NewManager creates a new connection manager
 
 func (m *Manager) buildFullPayload(msg *iso8583.Message) ([]byte, error) {
+	return m.buildPayloadWithHeader(msg, nil)
+}
+
+// buildPayloadWithHeader is buildFullPayload with header field overrides for this message only
+func (m *Manager) buildPayloadWithHeader(msg *iso8583.Message, headerFields map[string]string) ([]byte, error) {
 	packedMsg, err := msg.Pack()
 	if err != nil {
 		return nil, fmt.Errorf("failed to pack message: %w", err)
 	}
 
 	if m.header == nil {
+		if len(headerFields) > 0 {
+			return nil, fmt.Errorf("header fields set without a message header")
+		}
 		return packedMsg, nil
 	}
 
 	hdr := cloneHeader(m.header)
+	if len(headerFields) > 0 {
+		fh, ok := hdr.(utils.FieldHeader)
+		if !ok {
+			return nil, fmt.Errorf("header type has no fields")
+		}
+		for name, value := range headerFields {
+			if err := fh.SetHeaderField(name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
 	hdr.SetLength(len(packedMsg))
 
 	var buf bytes.Buffer
@@ -46,10 +163,41 @@ func (m *Manager) buildFullPayload(msg *iso8583.Message) ([]byte, error) {
 	}
 
 	fullPayload := append(buf.Bytes(), packedMsg...)
+	if th, ok := hdr.(utils.TrailerHeader); ok {
+		fullPayload = append(fullPayload, th.Trailer(packedMsg)...)
+	}
 	return fullPayload, nil
 }
 
+// hasTrailer reports whether the framing appends a trailer, which the connection's own
+// writer does not emit: such frames must be built with buildPayloadWithHeader
+func (m *Manager) hasTrailer() bool {
+	th, ok := m.header.(utils.TrailerHeader)
+	return ok && th.TrailerLen() > 0
+}
+
+// reply answers an inbound message. Framings with a trailer are written directly
+// because the connection's own writer only emits the length header.
+func (m *Manager) reply(conn *moovconnection.Connection, resp *iso8583.Message) error {
+	if !m.hasTrailer() {
+		return conn.Reply(resp)
+	}
+	fullPayload, err := m.buildFullPayload(resp)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(fullPayload)
+	return err
+}
+
 func (m *Manager) Send(msg *iso8583.Message) (*iso8583.Message, error) {
+	response, _, err := m.SendWithHeader(msg, nil)
+	return response, err
+}
+
+// SendWithHeader is Send with header field overrides for this message. It also returns
+// the header fields read with the response, or nil when the header type has none.
+func (m *Manager) SendWithHeader(msg *iso8583.Message, headerFields map[string]string) (*iso8583.Message, map[string]string, error) {
 	// Connection validation and error handling
 	m.statusMu.RLock()
 	conn := m.Connection
@@ -60,69 +208,72 @@ func (m *Manager) Send(msg *iso8583.Message) (*iso8583.Message, error) {
 	m.statusMu.RUnlock()
 
 	if conn == nil || status == moovconnection.StatusOffline {
-		return nil, moovconnection.ErrConnectionClosed
+		return nil, nil, moovconnection.ErrConnectionClosed
 	}
 
-	stan := getStan(msg)
+	key := m.correlationKeyOf(msg)
+	if key == "" && m.hasTrailer() {
+		return nil, nil, fmt.Errorf("request missing correlation key fields (%s): framings with a trailer cannot send it", m.GetCorrelationKey())
+	}
 	var responseChan chan *iso8583.Message
-	if stan != "" {
-		responseChan = make(chan *iso8583.Message, 1)
-		pending := &pendingRequest{
-			responseChan: responseChan,
-			timeout:      time.Now().Add(m.responseTimeout),
+	var pendingReq *pendingRequest
+	if key != "" {
+		pending := newPendingRequest(m.responseTimeout, "")
+		pending.mti, _ = msg.GetMTI()
+		if err := m.addPending(key, pending, false); err != nil {
+			return nil, nil, err
 		}
-		m.pendingMu.Lock()
-		m.pendingRequests[stan] = pending
-		m.pendingMu.Unlock()
-
-		defer func() {
-			m.pendingMu.Lock()
-			delete(m.pendingRequests, stan)
-			m.pendingMu.Unlock()
-		}()
+		responseChan = pending.responseChan
+		pendingReq = pending
+		defer m.removePending(key, pending)
 	}
 
-	fullPayload, err := m.buildFullPayload(msg)
+	fullPayload, err := m.buildPayloadWithHeader(msg, headerFields)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build message payload: %w", err)
+		return nil, nil, fmt.Errorf("failed to build message payload: %w", err)
 	}
 
 	if m.debugMode {
-		fmt.Printf("\nSENDING MESSAGE:\n%v\n", hex.Dump(fullPayload))
+		fmt.Printf("\nSENDING MESSAGE:\n%v\n", utils.HexDump(fullPayload, utils.SpecCodePage(m.GetSpec())))
 	}
 
 	// Send raw combined header + message payload directly in one TCP write
 	if _, err := conn.Write(fullPayload); err != nil {
-		return nil, fmt.Errorf("failed to send message: %w", err)
+		return nil, nil, fmt.Errorf("failed to send message: %w", err)
 	}
+	m.touch()
 
 	// Wait for response via responseChan (delivered immediately by reader goroutine) or timeout
 	var response *iso8583.Message
+	var responseHeader map[string]string
 	if responseChan != nil {
 		select {
 		case response = <-responseChan:
 			if response == nil {
-				return nil, fmt.Errorf("response timeout for STAN %s", stan)
+				return nil, nil, fmt.Errorf("%w for key %s", ErrResponseTimeout, key)
 			}
+			responseHeader = pendingReq.header
 		case <-time.After(m.responseTimeout):
-			return nil, fmt.Errorf("response timeout after %v for STAN %s", m.responseTimeout, stan)
+			return nil, nil, fmt.Errorf("%w after %v for key %s", ErrResponseTimeout, m.responseTimeout, key)
 		}
 	} else {
-		// Fallback for requests without STAN
+		// Fallback for requests without any correlation field. The connection hands the
+		// response straight back, so its header is taken here rather than by the handler.
 		response, err = m.Connection.Send(msg)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		responseHeader = m.takeHeader()
 	}
 
 	if m.debugMode && response != nil {
 		packedResponse, packErr := response.Pack()
 		if packErr == nil {
-			fmt.Printf("\nRECEIVED RESPONSE:\n%v\n", hex.Dump(packedResponse))
+			fmt.Printf("\nRECEIVED RESPONSE:\n%v\n", utils.HexDump(packedResponse, utils.SpecCodePage(m.GetSpec())))
 		}
 	}
 
-	return response, nil
+	return response, responseHeader, nil
 }
 
 // BackgroundSend sends a message without debug logging (for background operations)
@@ -147,6 +298,7 @@ func (m *Manager) BackgroundSend(msg *iso8583.Message) (*iso8583.Message, error)
 	if _, err := conn.Write(fullPayload); err != nil {
 		return nil, fmt.Errorf("failed to send message: %w", err)
 	}
+	m.touch()
 
 	return nil, nil
 }
@@ -167,74 +319,51 @@ func (m *Manager) SendAsync(
 		return nil, moovconnection.ErrConnectionClosed
 	}
 
-	// Get STAN from request
-	stan := getStan(msg)
-	if stan == "" {
-		return nil, fmt.Errorf("request missing or invalid STAN field")
-	}
-
-	// Create pending request
-	responseChan := make(chan *iso8583.Message, 1)
-	pending := &pendingRequest{
-		responseChan:    responseChan,
-		timeout:         time.Now().Add(m.responseTimeout),
-		transactionName: transactionName,
-	}
-
-	// Check for duplicate STAN and add to pending requests atomically
-	m.pendingMu.Lock()
-	if _, exists := m.pendingRequests[stan]; exists {
-		m.pendingMu.Unlock()
-		return nil, fmt.Errorf("STAN %s already in use by pending request", stan)
+	// Get correlation key from request
+	key := m.correlationKeyOf(msg)
+	if key == "" {
+		return nil, fmt.Errorf("request missing correlation key fields (%s)", m.GetCorrelationKey())
 	}
 
-	// Check if max pending requests limit is reached
-	if len(m.pendingRequests) >= m.maxPendingRequests {
-		m.pendingMu.Unlock()
-		return nil, fmt.Errorf("maximum pending requests limit reached (%d)", m.maxPendingRequests)
+	// Register the pending request; duplicates fail or queue, never overwrite
+	pending := newPendingRequest(m.responseTimeout, transactionName)
+	pending.mti, _ = msg.GetMTI()
+	if err := m.addPending(key, pending, true); err != nil {
+		return nil, err
 	}
 
-	m.pendingRequests[stan] = pending
-	m.pendingMu.Unlock()
-
 	fullPayload, err := m.buildFullPayload(msg)
 	if err != nil {
-		m.pendingMu.Lock()
-		delete(m.pendingRequests, stan)
-		m.pendingMu.Unlock()
+		m.removePending(key, pending)
 		return nil, fmt.Errorf("failed to build message payload: %w", err)
 	}
 
 	if m.debugMode {
-		fmt.Printf("\nSENDING MESSAGE:\n%v\n", hex.Dump(fullPayload))
+		fmt.Printf("\nSENDING MESSAGE:\n%v\n", utils.HexDump(fullPayload, utils.SpecCodePage(m.GetSpec())))
 	}
 
 	if _, err := conn.Write(fullPayload); err != nil {
-		m.pendingMu.Lock()
-		delete(m.pendingRequests, stan)
-		m.pendingMu.Unlock()
+		m.removePending(key, pending)
 		return nil, fmt.Errorf("failed to send message: %w", err)
 	}
+	m.touch()
 
 	// Start timeout handler
 	go func() {
 		time.Sleep(m.responseTimeout)
-		m.pendingMu.Lock()
-		if pendingReq, exists := m.pendingRequests[stan]; exists && pendingReq == pending {
-			delete(m.pendingRequests, stan)
+		if m.removePending(key, pending) {
 			select {
-			case pendingReq.responseChan <- nil: // Send nil to indicate timeout
+			case pending.responseChan <- nil: // Send nil to indicate timeout
 			default:
 			}
-			close(pendingReq.responseChan)
+			close(pending.responseChan)
 			if m.debugMode {
-				fmt.Printf("Request timeout for STAN %s, transaction %s\n", stan, transactionName)
+				fmt.Printf("Request timeout for key %s, transaction %s\n", key, transactionName)
 			}
 		}
-		m.pendingMu.Unlock()
 	}()
 
-	return responseChan, nil
+	return pending.responseChan, nil
 }
 
 // SetResponseTimeout sets the timeout for waiting responses
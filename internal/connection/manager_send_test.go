@@ -1,17 +1,20 @@
 package connection
 
 import (
+	"fmt"
 	"io"
 	"net"
 	"testing"
 	"time"
 
+	"jiso/internal/config"
 	"jiso/internal/utils"
 
 	"github.com/moov-io/iso8583"
 	moovconnection "github.com/moov-io/iso8583-connection"
 	"github.com/moov-io/iso8583/network"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestManagerSendWithNoConnection(t *testing.T) {
@@ -34,6 +37,25 @@ func TestManagerSendWithNoConnection(t *testing.T) {
 	assert.Equal(t, moovconnection.ErrConnectionClosed, err)
 }
 
+func TestSendWithoutKeyOnTrailerFramingIsRejected(t *testing.T) {
+	spec := mockMessageSpec()
+	server, err := startTestServer(spec, true)
+	require.NoError(t, err)
+	defer server.Close()
+
+	header, err := utils.NewFramingHeader(config.FramingConfig{Name: "etx", LengthBytes: 2, Trailer: config.TrailerETX})
+	require.NoError(t, err)
+	manager := NewManager("localhost", fmt.Sprintf("%d", server.port()), spec, false, 0, time.Second, time.Second, nil)
+	require.NoError(t, manager.Connect(false, header))
+	defer manager.Close()
+
+	// Without DE11 the request would go out through the library writer, without its ETX
+	message := iso8583.NewMessage(spec)
+	require.NoError(t, message.Field(0, "0800"))
+	_, err = manager.Send(message)
+	assert.ErrorContains(t, err, "framings with a trailer cannot send it")
+}
+
 type testServer struct {
 	listener net.Listener
 	spec     *iso8583.MessageSpec
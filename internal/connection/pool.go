@@ -0,0 +1,327 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/moov-io/iso8583"
+	moovconnection "github.com/moov-io/iso8583-connection"
+	"github.com/moov-io/iso8583/network"
+)
+
+// ErrNoHealthySocket is returned when every socket of a pool is offline
+var ErrNoHealthySocket = errors.New("no connected socket in pool")
+
+// PoolStrategy decides which socket of a pool carries the next request
+type PoolStrategy string
+
+const (
+	// PoolRoundRobin rotates through the connected sockets
+	PoolRoundRobin PoolStrategy = "round_robin"
+	// PoolLeastPending picks the connected socket with the fewest requests awaiting a response
+	PoolLeastPending PoolStrategy = "least_pending"
+)
+
+// MaxPoolSize bounds the number of sockets a pool may open to one target
+const MaxPoolSize = 64
+
+// poolHealthInterval is how often a connected pool checks its sockets
+const poolHealthInterval = 2 * time.Second
+
+// ParsePoolStrategy accepts "round_robin" (the default) or "least_pending"
+func ParsePoolStrategy(s string) (PoolStrategy, error) {
+	switch PoolStrategy(strings.ToLower(strings.TrimSpace(s))) {
+	case "", PoolRoundRobin:
+		return PoolRoundRobin, nil
+	case PoolLeastPending:
+		return PoolLeastPending, nil
+	}
+	return "", fmt.Errorf("invalid pool strategy '%s' (expected round_robin or least_pending)", s)
+}
+
+type poolMember struct {
+	manager *Manager
+	sent    int64
+	failed  int64
+}
+
+// SocketStats is a snapshot of one pool socket
+type SocketStats struct {
+	Index      int
+	Address    string
+	Status     string
+	Pending    int
+	Sent       int64
+	Failed     int64
+	Reconnects int64
+}
+
+// Pool spreads requests over several connection managers to the same target.
+// Each socket keeps its own pending table, so responses are always correlated
+// on the link that carried the request.
+type Pool struct {
+	members  []*poolMember
+	strategy PoolStrategy
+	next     uint64
+
+	healthMu   sync.Mutex
+	healthStop chan struct{}
+}
+
+// NewPool builds a pool of size sockets. The first socket is primary; the others
+// copy its target and settings.
+func NewPool(primary *Manager, size int, strategy PoolStrategy) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	if strategy == "" {
+		strategy = PoolRoundRobin
+	}
+	members := make([]*poolMember, size)
+	members[0] = &poolMember{manager: primary}
+	for i := 1; i < size; i++ {
+		members[i] = &poolMember{manager: primary.sibling()}
+	}
+	return &Pool{members: members, strategy: strategy}
+}
+
+// Size returns the number of sockets in the pool
+func (p *Pool) Size() int {
+	return len(p.members)
+}
+
+// Strategy returns how the pool distributes requests
+func (p *Pool) Strategy() PoolStrategy {
+	return p.strategy
+}
+
+// Managers returns the connection managers of every socket, primary first
+func (p *Pool) Managers() []*Manager {
+	managers := make([]*Manager, len(p.members))
+	for i, member := range p.members {
+		managers[i] = member.manager
+	}
+	return managers
+}
+
+// Connect opens every socket in parallel. It fails only when no socket connects;
+// sockets that failed are retried by the health check.
+func (p *Pool) Connect(naps bool, header network.Header) error {
+	errs := make([]error, len(p.members))
+	var wg sync.WaitGroup
+	for i, member := range p.members {
+		wg.Add(1)
+		go func(i int, m *Manager) {
+			defer wg.Done()
+			errs[i] = m.connectGroup(naps, header)
+		}(i, member.manager)
+	}
+	wg.Wait()
+
+	connected := 0
+	for i, err := range errs {
+		if err == nil {
+			connected++
+			continue
+		}
+		if len(p.members) > 1 {
+			fmt.Printf("Socket %d of %d failed to connect: %v\n", i+1, len(p.members), err)
+		}
+	}
+	if connected == 0 {
+		return errs[0]
+	}
+
+	if len(p.members) > 1 {
+		p.startHealthCheck()
+	}
+	return nil
+}
+
+// Close stops the health check and closes every socket
+func (p *Pool) Close() error {
+	p.stopHealthCheck()
+	var firstErr error
+	for _, member := range p.members {
+		if err := member.manager.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// IsConnected reports whether at least one socket is online
+func (p *Pool) IsConnected() bool {
+	for _, member := range p.members {
+		if member.manager.IsConnected() {
+			return true
+		}
+	}
+	return false
+}
+
+// Connection returns the connection of the first online socket, or nil
+func (p *Pool) Connection() *moovconnection.Connection {
+	for _, member := range p.members {
+		m := member.manager
+		if m.IsConnected() {
+			m.statusMu.RLock()
+			conn := m.Connection
+			m.statusMu.RUnlock()
+			return conn
+		}
+	}
+	return nil
+}
+
+// pick selects the socket for the next request according to the strategy
+func (p *Pool) pick() (*poolMember, error) {
+	if len(p.members) == 1 {
+		// A single socket reports its own offline error, exactly as a bare manager does
+		return p.members[0], nil
+	}
+
+	start := int(atomic.AddUint64(&p.next, 1)-1) % len(p.members)
+	var best *poolMember
+	bestPending := 0
+	for i := range p.members {
+		member := p.members[(start+i)%len(p.members)]
+		if !member.manager.IsConnected() {
+			continue
+		}
+		if p.strategy != PoolLeastPending {
+			return member, nil
+		}
+		pending := member.manager.PendingCount()
+		if best == nil || pending < bestPending {
+			best, bestPending = member, pending
+		}
+	}
+	if best == nil {
+		return nil, ErrNoHealthySocket
+	}
+	return best, nil
+}
+
+// record counts the outcome of a send on member
+func (member *poolMember) record(err error) {
+	if err != nil {
+		atomic.AddInt64(&member.failed, 1)
+		return
+	}
+	atomic.AddInt64(&member.sent, 1)
+}
+
+// Send sends msg on the selected socket and waits for the response
+func (p *Pool) Send(msg *iso8583.Message) (*iso8583.Message, error) {
+	member, err := p.pick()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := member.manager.Send(msg)
+	member.record(err)
+	return resp, err
+}
+
+// SendWithHeader is Send with header field overrides, also returning the response header fields
+func (p *Pool) SendWithHeader(msg *iso8583.Message, headerFields map[string]string) (*iso8583.Message, map[string]string, error) {
+	member, err := p.pick()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, header, err := member.manager.SendWithHeader(msg, headerFields)
+	member.record(err)
+	return resp, header, err
+}
+
+// BackgroundSend writes msg on the selected socket without waiting for a response
+func (p *Pool) BackgroundSend(msg *iso8583.Message) (*iso8583.Message, error) {
+	member, err := p.pick()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := member.manager.BackgroundSend(msg)
+	member.record(err)
+	return resp, err
+}
+
+// SendAsync sends msg on the selected socket and returns its response channel
+func (p *Pool) SendAsync(msg *iso8583.Message, transactionName string) (<-chan *iso8583.Message, error) {
+	member, err := p.pick()
+	if err != nil {
+		return nil, err
+	}
+	ch, err := member.manager.SendAsync(msg, transactionName)
+	member.record(err)
+	return ch, err
+}
+
+// Stats returns a snapshot of every socket, primary first
+func (p *Pool) Stats() []SocketStats {
+	stats := make([]SocketStats, len(p.members))
+	for i, member := range p.members {
+		m := member.manager
+		stats[i] = SocketStats{
+			Index:      i + 1,
+			Address:    m.GetAddress(),
+			Status:     m.GetStatus(),
+			Pending:    m.PendingCount(),
+			Sent:       atomic.LoadInt64(&member.sent),
+			Failed:     atomic.LoadInt64(&member.failed),
+			Reconnects: m.Reconnects(),
+		}
+	}
+	return stats
+}
+
+// startHealthCheck periodically reconnects sockets that went offline
+func (p *Pool) startHealthCheck() {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	if p.healthStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	p.healthStop = stop
+
+	go func() {
+		ticker := time.NewTicker(poolHealthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				p.checkHealth()
+			}
+		}
+	}()
+}
+
+func (p *Pool) stopHealthCheck() {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	if p.healthStop != nil {
+		close(p.healthStop)
+		p.healthStop = nil
+	}
+}
+
+// checkHealth records the state of every socket and starts a background
+// reconnection for those that are offline and not already reconnecting
+func (p *Pool) checkHealth() {
+	for _, member := range p.members {
+		m := member.manager
+		healthy := m.IsConnected()
+		if m.networkStats != nil {
+			m.networkStats.RecordHealthCheck(healthy)
+		}
+		if !healthy && (m.reconnectAttempts > 0 || m.HasSecondaryTargets()) && !m.IsReconnecting() {
+			go m.attemptReconnect()
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"jiso/internal/utils"
+
+	"github.com/moov-io/iso8583"
+	moovconnection "github.com/moov-io/iso8583-connection"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePoolStrategy(t *testing.T) {
+	strategy, err := ParsePoolStrategy("")
+	require.NoError(t, err)
+	assert.Equal(t, PoolRoundRobin, strategy)
+
+	strategy, err = ParsePoolStrategy(" Least_Pending ")
+	require.NoError(t, err)
+	assert.Equal(t, PoolLeastPending, strategy)
+
+	_, err = ParsePoolStrategy("random")
+	assert.Error(t, err)
+}
+
+func TestNewPoolCopiesSettings(t *testing.T) {
+	primary := NewManager("localhost", "8080", mockMessageSpec(), false, 2, time.Second, time.Second, nil)
+	primary.SetCorrelationKey(CorrelationKey{11, 41})
+	primary.SetResponseTimeout(750 * time.Millisecond)
+
+	pool := NewPool(primary, 3, "")
+	assert.Equal(t, 3, pool.Size())
+	assert.Equal(t, PoolRoundRobin, pool.Strategy())
+
+	managers := pool.Managers()
+	assert.Same(t, primary, managers[0])
+	for _, m := range managers[1:] {
+		assert.NotSame(t, primary, m)
+		assert.Equal(t, "localhost:8080", m.GetAddress())
+		assert.Equal(t, CorrelationKey{11, 41}, m.GetCorrelationKey())
+		assert.Equal(t, 750*time.Millisecond, m.GetResponseTimeout())
+	}
+}
+
+func TestPoolWithoutConnectedSockets(t *testing.T) {
+	primary := NewManager("localhost", "8080", mockMessageSpec(), false, 0, time.Second, time.Second, nil)
+	pool := NewPool(primary, 2, PoolRoundRobin)
+
+	msg := iso8583.NewMessage(mockMessageSpec())
+	require.NoError(t, msg.Field(0, "0800"))
+	require.NoError(t, msg.Field(11, "000001"))
+
+	_, err := pool.Send(msg)
+	assert.True(t, errors.Is(err, ErrNoHealthySocket))
+	assert.False(t, pool.IsConnected())
+	assert.Nil(t, pool.Connection())
+}
+
+func TestPoolDistributesRequests(t *testing.T) {
+	spec := mockMessageSpec()
+	server, err := startTestServer(spec, true)
+	require.NoError(t, err)
+	defer server.Close()
+
+	primary := NewManager("localhost", fmt.Sprintf("%d", server.port()), spec, false, 0, time.Second, 2*time.Second, nil)
+	pool := NewPool(primary, 3, PoolRoundRobin)
+	require.NoError(t, pool.Connect(false, utils.NewBinary2BytesAdapter()))
+	defer pool.Close()
+	assert.True(t, pool.IsConnected())
+
+	for i := 0; i < 6; i++ {
+		msg := iso8583.NewMessage(spec)
+		require.NoError(t, msg.Field(0, "0800"))
+		require.NoError(t, msg.Field(11, fmt.Sprintf("%06d", i+1)))
+		resp, err := pool.Send(msg)
+		require.NoError(t, err)
+		stan, _ := resp.GetString(11)
+		assert.Equal(t, fmt.Sprintf("%06d", i+1), stan)
+	}
+
+	for _, socket := range pool.Stats() {
+		assert.Equal(t, string(moovconnection.StatusOnline), socket.Status)
+		assert.Equal(t, int64(2), socket.Sent, "socket %d", socket.Index)
+		assert.Equal(t, int64(0), socket.Failed)
+	}
+}
+
+func TestPoolLeastPending(t *testing.T) {
+	spec := mockMessageSpec()
+	server, err := startTestServer(spec, false)
+	require.NoError(t, err)
+	defer server.Close()
+
+	primary := NewManager("localhost", fmt.Sprintf("%d", server.port()), spec, false, 0, time.Second, 2*time.Second, nil)
+	pool := NewPool(primary, 3, PoolLeastPending)
+	require.NoError(t, pool.Connect(false, utils.NewBinary2BytesAdapter()))
+	defer pool.Close()
+
+	managers := pool.Managers()
+	require.NoError(t, managers[0].addPending("a", newPendingRequest(time.Second, "a"), true))
+	require.NoError(t, managers[0].addPending("b", newPendingRequest(time.Second, "b"), true))
+	require.NoError(t, managers[2].addPending("c", newPendingRequest(time.Second, "c"), true))
+
+	for i := 0; i < 3; i++ {
+		member, err := pool.pick()
+		require.NoError(t, err)
+		assert.Same(t, managers[1], member.manager)
+	}
+}
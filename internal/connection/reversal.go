@@ -0,0 +1,172 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/iso8583"
+)
+
+// reversalFields are copied from the original request into its reversal. DE7 is set
+// to the reversal's own transmission time and DE90 is built from the original.
+var reversalFields = []int{2, 3, 4, 5, 6, 11, 12, 13, 14, 18, 19, 22, 23, 32, 33, 37, 41, 42, 43, 49, 50, 51}
+
+// ReversalPolicy selects the reversal sent when a request gets no response
+type ReversalPolicy struct {
+	MTI     string // 0400 or 0420; the version digit is taken from the original
+	Repeats int    // Repeats (0401/0421) sent while the reversal gets no response
+}
+
+// ParseReversalPolicy parses an auto-reversal policy: 0400, 0420, or empty or off,
+// which disables reversals and returns nil
+func ParseReversalPolicy(policy string, repeats int) (*ReversalPolicy, error) {
+	switch policy {
+	case "", "off":
+		return nil, nil
+	case "0400", "0420":
+	default:
+		return nil, fmt.Errorf("invalid auto reversal '%s' (expected 0400, 0420 or off)", policy)
+	}
+	if repeats < 0 {
+		return nil, fmt.Errorf("reversal repeats must not be negative: %d", repeats)
+	}
+	return &ReversalPolicy{MTI: policy, Repeats: repeats}, nil
+}
+
+// ReversalMTI maps the original MTI to the reversal's: the original's version
+// digit, the policy's class and function, and a repeat origin for repeats.
+// 0100 becomes 0400 or 0420, and 1200 repeated becomes 1421.
+func (p *ReversalPolicy) ReversalMTI(origMTI string, repeat bool) string {
+	version := "0"
+	if len(origMTI) == 4 {
+		version = origMTI[:1]
+	}
+	origin := "0"
+	if repeat {
+		origin = "1"
+	}
+	return version + p.MTI[1:3] + origin
+}
+
+// OriginalDataElements builds DE90 for a reversal of orig: original MTI, STAN,
+// transmission date and time, acquiring and forwarding institution IDs
+func OriginalDataElements(orig *iso8583.Message) (string, error) {
+	mti, err := orig.GetMTI()
+	if err != nil {
+		return "", fmt.Errorf("reading original MTI: %w", err)
+	}
+	stan, _ := orig.GetString(11)
+	transmitted, _ := orig.GetString(7)
+	acquirer, _ := orig.GetString(32)
+	forwarder, _ := orig.GetString(33)
+	return padDigits(mti, 4) + padDigits(stan, 6) + padDigits(transmitted, 10) +
+		padDigits(acquirer, 11) + padDigits(forwarder, 11), nil
+}
+
+// padDigits right-justifies s in n zero-filled positions, keeping its last n characters
+func padDigits(s string, n int) string {
+	if len(s) >= n {
+		return s[len(s)-n:]
+	}
+	return strings.Repeat("0", n-len(s)) + s
+}
+
+// BuildReversal builds a reversal of orig with the given MTI. The original's
+// STAN, RRN and amount are retained so the host can match the reversal.
+func BuildReversal(orig *iso8583.Message, mti string) (*iso8583.Message, error) {
+	spec := orig.GetSpec()
+	rev := iso8583.NewMessage(spec)
+	rev.MTI(mti)
+	for _, id := range reversalFields {
+		f := orig.GetField(id)
+		if f == nil {
+			continue
+		}
+		b, err := f.Bytes()
+		if err != nil || len(b) == 0 {
+			continue
+		}
+		if err := rev.Field(id, string(b)); err != nil {
+			return nil, fmt.Errorf("copying field %d: %w", id, err)
+		}
+	}
+	if _, ok := spec.Fields[7]; ok {
+		_ = rev.Field(7, time.Now().UTC().Format("0102150405"))
+	}
+	if _, ok := spec.Fields[90]; ok {
+		de90, err := OriginalDataElements(orig)
+		if err != nil {
+			return nil, err
+		}
+		if err := rev.Field(90, de90); err != nil {
+			return nil, fmt.Errorf("setting original data elements: %w", err)
+		}
+	}
+	return rev, nil
+}
+
+// ReversalResult is the outcome of reversing an unanswered request
+type ReversalResult struct {
+	MTI          string           // MTI of the last reversal sent
+	Attempts     int              // Reversals sent, the first one included
+	Request      *iso8583.Message // Last reversal sent
+	Response     *iso8583.Message // Host response, nil when every attempt went unanswered
+	ResponseCode string           // DE39 of the response
+	Err          error            // Why the last attempt failed
+}
+
+// Acknowledged reports whether the host answered the reversal, whatever its response code
+func (r *ReversalResult) Acknowledged() bool {
+	return r != nil && r.Response != nil
+}
+
+// String summarizes the outcome, such as "0421 x3: response code 00"
+func (r *ReversalResult) String() string {
+	if r == nil {
+		return "not sent"
+	}
+	outcome := "response code " + r.ResponseCode
+	switch {
+	case r.Err != nil:
+		outcome = r.Err.Error()
+	case r.ResponseCode == "":
+		outcome = "answered without response code"
+	}
+	return fmt.Sprintf("%s x%d: %s", r.MTI, r.Attempts, outcome)
+}
+
+// SendReversal reverses orig with send, repeating the reversal while it times out
+func SendReversal(
+	orig *iso8583.Message,
+	policy *ReversalPolicy,
+	send func(*iso8583.Message) (*iso8583.Message, error),
+) *ReversalResult {
+	origMTI, _ := orig.GetMTI()
+	result := &ReversalResult{}
+	for attempt := 0; attempt <= policy.Repeats; attempt++ {
+		result.MTI = policy.ReversalMTI(origMTI, attempt > 0)
+		rev, err := BuildReversal(orig, result.MTI)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Request = rev
+		result.Attempts++
+
+		resp, err := send(rev)
+		if err == nil {
+			result.Response, result.Err = resp, nil
+			if resp != nil {
+				result.ResponseCode, _ = resp.GetString(39)
+			}
+			return result
+		}
+		result.Err = err
+		if !errors.Is(err, ErrResponseTimeout) {
+			return result
+		}
+	}
+	return result
+}
@@ -0,0 +1,153 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/moov-io/iso8583"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuthorization(t *testing.T) *iso8583.Message {
+	msg := iso8583.NewMessage(iso8583.Spec87)
+	msg.MTI("0100")
+	for id, v := range map[int]string{
+		2:  "4242424242424242",
+		3:  "000000",
+		4:  "000000010000",
+		7:  "1016120000",
+		11: "000123",
+		32: "123456",
+		37: "123456789012",
+		41: "TERM0001",
+		52: "PINBLOCK",
+	} {
+		require.NoError(t, msg.Field(id, v))
+	}
+	return msg
+}
+
+func TestReversalMTI(t *testing.T) {
+	p, err := ParseReversalPolicy("0420", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "0420", p.ReversalMTI("0100", false))
+	assert.Equal(t, "0421", p.ReversalMTI("0200", true))
+	assert.Equal(t, "1421", p.ReversalMTI("1200", true))
+
+	p, err = ParseReversalPolicy("off", 2)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	_, err = ParseReversalPolicy("0410", 0)
+	assert.Error(t, err)
+}
+
+func TestBuildReversal(t *testing.T) {
+	orig := newAuthorization(t)
+
+	rev, err := BuildReversal(orig, "0400")
+	require.NoError(t, err)
+
+	mti, err := rev.GetMTI()
+	require.NoError(t, err)
+	assert.Equal(t, "0400", mti)
+	for _, id := range []int{4, 11, 37, 41} {
+		want, _ := orig.GetString(id)
+		got, err := rev.GetString(id)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "field %d", id)
+	}
+	assert.Nil(t, rev.GetField(52), "PIN block must not be copied")
+
+	de90, err := rev.GetString(90)
+	require.NoError(t, err)
+	assert.Equal(t, "0100"+"000123"+"1016120000"+"00000123456"+"00000000000", de90)
+}
+
+func TestSendReversalRepeats(t *testing.T) {
+	orig := newAuthorization(t)
+	policy := &ReversalPolicy{MTI: "0420", Repeats: 2}
+
+	var sent []string
+	send := func(msg *iso8583.Message) (*iso8583.Message, error) {
+		mti, _ := msg.GetMTI()
+		sent = append(sent, mti)
+		if len(sent) < 3 {
+			return nil, fmt.Errorf("%w for key 000123", ErrResponseTimeout)
+		}
+		resp := iso8583.NewMessage(iso8583.Spec87)
+		resp.MTI("0430")
+		_ = resp.Field(39, "00")
+		return resp, nil
+	}
+
+	result := SendReversal(orig, policy, send)
+	assert.Equal(t, []string{"0420", "0421", "0421"}, sent)
+	assert.Equal(t, 3, result.Attempts)
+	assert.True(t, result.Acknowledged())
+	assert.Equal(t, "00", result.ResponseCode)
+	assert.NoError(t, result.Err)
+	assert.Equal(t, "0421 x3: response code 00", result.String())
+}
+
+func TestSendReversalStopsOnSendError(t *testing.T) {
+	orig := newAuthorization(t)
+	policy := &ReversalPolicy{MTI: "0400", Repeats: 3}
+	closed := errors.New("connection closed")
+
+	attempts := 0
+	result := SendReversal(orig, policy, func(*iso8583.Message) (*iso8583.Message, error) {
+		attempts++
+		return nil, closed
+	})
+	assert.Equal(t, 1, attempts)
+	assert.False(t, result.Acknowledged())
+	assert.ErrorIs(t, result.Err, closed)
+}
+
+func TestLateOriginalResponseIsNotTheReversalAnswer(t *testing.T) {
+	manager := NewManager("localhost", "8080", iso8583.Spec87, false, 0, time.Second, time.Second, nil)
+
+	// The reversal keeps the original's STAN, so both share a correlation key
+	rev, err := BuildReversal(newAuthorization(t), "0400")
+	require.NoError(t, err)
+	key := manager.correlationKeyOf(rev)
+	pending := newPendingRequest(time.Second, "reversal")
+	pending.mti = "0400"
+	require.NoError(t, manager.addPending(key, pending, true))
+
+	answer := func(mti string) *iso8583.Message {
+		resp := iso8583.NewMessage(iso8583.Spec87)
+		resp.MTI(mti)
+		require.NoError(t, resp.Field(11, "000123"))
+		require.NoError(t, resp.Field(39, "00"))
+		return resp
+	}
+
+	// The host answers the timed-out authorization late
+	manager.handleInboundMessage(answer("0110"))
+	assert.Len(t, pending.responseChan, 0)
+	assert.Contains(t, manager.pendingRequests, key)
+
+	manager.handleInboundMessage(answer("0410"))
+	select {
+	case got := <-pending.responseChan:
+		mti, _ := got.GetMTI()
+		assert.Equal(t, "0410", mti)
+	case <-time.After(time.Second):
+		t.Fatal("reversal response not delivered")
+	}
+}
+
+func TestAnswersMTI(t *testing.T) {
+	assert.True(t, answersMTI("0100", "0110"))
+	assert.True(t, answersMTI("0401", "0410"))
+	assert.True(t, answersMTI("1420", "1430"))
+	assert.True(t, answersMTI("", "0110"))
+	assert.False(t, answersMTI("0400", "0110"))
+	assert.False(t, answersMTI("0400", "0400"))
+	assert.False(t, answersMTI("0100", "1110"))
+}
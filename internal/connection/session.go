@@ -0,0 +1,154 @@
+package connection
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/moov-io/iso8583"
+)
+
+// MessageFactory builds a fresh network management message for spec
+type MessageFactory func(spec *iso8583.MessageSpec) (*iso8583.Message, error)
+
+// Session keeps a link signed on: a sign-on after every connect, echo tests while
+// the link is idle and a sign-off before an orderly close.
+type Session struct {
+	SignOn       MessageFactory // nil skips the sign-on
+	Echo         MessageFactory // nil sends NewEchoMessage
+	SignOff      MessageFactory // nil skips the sign-off
+	EchoInterval time.Duration  // Idle time before an echo test; zero disables echo tests
+}
+
+// SetSession sets the network management session used from the next connect; nil disables it
+func (m *Manager) SetSession(session *Session) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	m.session = session
+}
+
+// GetSession returns the network management session, or nil
+func (m *Manager) GetSession() *Session {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+	return m.session
+}
+
+// touch records traffic on the link
+func (m *Manager) touch() {
+	atomic.StoreInt64(&m.lastActivity, time.Now().UnixNano())
+}
+
+// IdleTime returns how long the link has carried no traffic
+func (m *Manager) IdleTime() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&m.lastActivity)))
+}
+
+// startSession signs on and starts the echo loop after a successful connect
+func (m *Manager) startSession() {
+	m.touch()
+	session := m.GetSession()
+	if session == nil {
+		return
+	}
+
+	if session.SignOn != nil {
+		err := m.exchange(session.SignOn, "sign-on")
+		if m.networkStats != nil {
+			m.networkStats.RecordHealthCheck(err == nil)
+		}
+		if err != nil {
+			fmt.Printf("Sign-on to %s failed: %v\n", m.GetAddress(), err)
+		} else if m.debugMode {
+			fmt.Printf("Signed on to %s\n", m.GetAddress())
+		}
+	}
+
+	if session.EchoInterval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	m.statusMu.Lock()
+	if m.keepaliveStop != nil {
+		close(m.keepaliveStop)
+	}
+	m.keepaliveStop = stop
+	m.statusMu.Unlock()
+	go m.keepalive(session, stop)
+}
+
+// stopKeepalive ends the echo loop; the caller must hold statusMu
+func (m *Manager) stopKeepalive() {
+	if m.keepaliveStop != nil {
+		close(m.keepaliveStop)
+		m.keepaliveStop = nil
+	}
+}
+
+// keepalive sends an echo test whenever the link has been idle for the echo
+// interval. A failed echo counts as a failed health check and triggers a reconnect.
+func (m *Manager) keepalive(session *Session, stop <-chan struct{}) {
+	timer := time.NewTimer(session.EchoInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+
+		if idle := m.IdleTime(); idle < session.EchoInterval {
+			timer.Reset(session.EchoInterval - idle)
+			continue
+		}
+
+		echo := session.Echo
+		if echo == nil {
+			echo = func(spec *iso8583.MessageSpec) (*iso8583.Message, error) {
+				return NewEchoMessage(spec), nil
+			}
+		}
+		err := m.exchange(echo, "echo test")
+		if m.networkStats != nil {
+			m.networkStats.RecordHealthCheck(err == nil)
+		}
+		if err != nil {
+			fmt.Printf("Echo test to %s failed: %v\n", m.GetAddress(), err)
+			if m.reconnectAttempts > 0 || m.HasSecondaryTargets() {
+				go m.attemptReconnect()
+				return
+			}
+		}
+		timer.Reset(session.EchoInterval)
+	}
+}
+
+// SignOff sends the session's sign-off on a connected link. It is a no-op
+// without a session sign-off message.
+func (m *Manager) SignOff() error {
+	session := m.GetSession()
+	if session == nil || session.SignOff == nil || !m.IsConnected() {
+		return nil
+	}
+	m.statusMu.Lock()
+	m.stopKeepalive()
+	m.statusMu.Unlock()
+	return m.exchange(session.SignOff, "sign-off")
+}
+
+// exchange sends a network management message and checks its response
+func (m *Manager) exchange(build MessageFactory, what string) error {
+	msg, err := build(m.GetSpec())
+	if err != nil {
+		return fmt.Errorf("failed to build %s: %w", what, err)
+	}
+	resp, err := m.Send(msg)
+	if err != nil {
+		return err
+	}
+	if !EchoPassed(resp) {
+		rc, _ := resp.GetString(39)
+		return fmt.Errorf("%s declined (RC %s)", what, rc)
+	}
+	return nil
+}
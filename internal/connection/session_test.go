@@ -0,0 +1,81 @@
+package connection
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"jiso/internal/metrics"
+	"jiso/internal/utils"
+
+	"github.com/moov-io/iso8583"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingMessage builds network management messages with code and counts them
+func countingMessage(code string, count *int64) MessageFactory {
+	return func(spec *iso8583.MessageSpec) (*iso8583.Message, error) {
+		atomic.AddInt64(count, 1)
+		return NewNetworkMessage(spec, code), nil
+	}
+}
+
+func TestSessionSignOnEchoSignOff(t *testing.T) {
+	spec := mockMessageSpec()
+	server, err := startTestServer(spec, true)
+	require.NoError(t, err)
+	defer server.Close()
+
+	stats := metrics.NewNetworkingStats()
+	manager := NewManager("localhost", fmt.Sprintf("%d", server.port()), spec, false, 0, time.Second, time.Second, stats)
+
+	var signOns, echoes, signOffs int64
+	manager.SetSession(&Session{
+		SignOn:       countingMessage(NetworkSignOn, &signOns),
+		Echo:         countingMessage(NetworkEcho, &echoes),
+		SignOff:      countingMessage(NetworkSignOff, &signOffs),
+		EchoInterval: 100 * time.Millisecond,
+	})
+
+	require.NoError(t, manager.Connect(false, utils.NewBinary2BytesAdapter()))
+	defer manager.Close()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&signOns))
+
+	// An idle link is echo tested and every echo counts as a health check
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&echoes) >= 2 }, 2*time.Second, 20*time.Millisecond)
+	assert.True(t, manager.IsConnected())
+	assert.GreaterOrEqual(t, stats.HealthChecks(), int64(3))
+	assert.Zero(t, stats.HealthCheckFailures())
+
+	require.NoError(t, manager.SignOff())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&signOffs))
+
+	// Sign-off stops the echo loop
+	sent := atomic.LoadInt64(&echoes)
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, sent, atomic.LoadInt64(&echoes))
+}
+
+func TestEchoPassed(t *testing.T) {
+	spec := mockMessageSpec()
+
+	assert.False(t, EchoPassed(nil))
+
+	resp := iso8583.NewMessage(spec)
+	resp.MTI("0810")
+	assert.True(t, EchoPassed(resp))
+
+	require.NoError(t, resp.Field(39, "00"))
+	assert.True(t, EchoPassed(resp))
+
+	require.NoError(t, resp.Field(39, "91"))
+	assert.False(t, EchoPassed(resp))
+
+	// A financial response is not an answer to network management
+	approved := iso8583.NewMessage(spec)
+	approved.MTI("0110")
+	require.NoError(t, approved.Field(39, "00"))
+	assert.False(t, EchoPassed(approved))
+}
@@ -26,6 +26,7 @@ type TransactionRecord struct {
 	ResponseJSON     *string
 	ProcessingTimeMs int
 	Success          bool
+	ReversalJSON     *string // Outcome of the automatic reversal, nil when not reversed
 }
 
 var (
@@ -61,9 +62,15 @@ func StopAsyncLogger() {
 
 // LogTransaction queues a transaction for logging
 func LogTransaction(sessionID, txName, requestJSON string, responseJSON *string, processingTimeMs int, success bool) {
+	LogReversedTransaction(sessionID, txName, requestJSON, responseJSON, processingTimeMs, success, nil)
+}
+
+// LogReversedTransaction queues a transaction for logging together with the outcome of
+// its automatic reversal
+func LogReversedTransaction(sessionID, txName, requestJSON string, responseJSON *string, processingTimeMs int, success bool, reversalJSON *string) {
 	if logger == nil {
 		// Fallback to synchronous if async logger isn't initialized
-		if err := InsertTransaction(sessionID, txName, requestJSON, responseJSON, processingTimeMs, success); err != nil {
+		if err := InsertReversedTransaction(sessionID, txName, requestJSON, responseJSON, processingTimeMs, success, reversalJSON); err != nil {
 			log.Printf("Failed to insert transaction synchronously: %v", err)
 		}
 		return
@@ -76,6 +83,7 @@ func LogTransaction(sessionID, txName, requestJSON string, responseJSON *string,
 		ResponseJSON:     responseJSON,
 		ProcessingTimeMs: processingTimeMs,
 		Success:          success,
+		ReversalJSON:     reversalJSON,
 	}
 
 	select {
@@ -146,8 +154,8 @@ func (l *AsyncLogger) writeBatch(batch []*TransactionRecord) error {
 	insertSQL := `
 		INSERT INTO transactions (
 			session_id, transaction_name, request_json, response_json, 
-			processing_time_ms, success, response_code
-		) VALUES (?, ?, ?, ?, ?, ?, ?)
+			processing_time_ms, success, response_code, reversal_json
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	for _, record := range batch {
@@ -162,6 +170,7 @@ func (l *AsyncLogger) writeBatch(batch []*TransactionRecord) error {
 				record.ProcessingTimeMs,
 				record.Success,
 				responseCode,
+				derefOrNil(record.ReversalJSON),
 			},
 		})
 		if err != nil {
@@ -62,7 +62,29 @@ func createTables() error {
 	}
 
 	indexSQL2 := `CREATE INDEX IF NOT EXISTS idx_response_code ON transactions(response_code)`
-	return sqlitex.ExecuteTransient(dbConn, indexSQL2, nil)
+	if err := sqlitex.ExecuteTransient(dbConn, indexSQL2, nil); err != nil {
+		return err
+	}
+
+	// Databases created before auto-reversal lack the reversal column
+	return ensureColumn("transactions", "reversal_json", "TEXT")
+}
+
+// ensureColumn adds a column to an existing table unless it is already there
+func ensureColumn(table, column, decl string) error {
+	exists := false
+	err := sqlitex.ExecuteTransient(dbConn, fmt.Sprintf("PRAGMA table_info(%s)", table), &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			if stmt.ColumnText(1) == column {
+				exists = true
+			}
+			return nil
+		},
+	})
+	if err != nil || exists {
+		return err
+	}
+	return sqlitex.ExecuteTransient(dbConn, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl), nil)
 }
 
 // InsertTransaction inserts a new transaction record with proper transaction handling
@@ -71,6 +93,18 @@ func InsertTransaction(
 	responseJSON *string,
 	processingTimeMs int,
 	success bool,
+) error {
+	return InsertReversedTransaction(sessionID, txName, requestJSON, responseJSON, processingTimeMs, success, nil)
+}
+
+// InsertReversedTransaction inserts a transaction record together with the outcome of
+// its automatic reversal, or nil when it was not reversed
+func InsertReversedTransaction(
+	sessionID, txName, requestJSON string,
+	responseJSON *string,
+	processingTimeMs int,
+	success bool,
+	reversalJSON *string,
 ) error {
 	if dbConn == nil {
 		return fmt.Errorf("database not initialized")
@@ -88,13 +122,14 @@ func InsertTransaction(
 	insertSQL := `
 		INSERT INTO transactions (
 			session_id, transaction_name, request_json, response_json, 
-			processing_time_ms, success, response_code
-		) VALUES (?, ?, ?, ?, ?, ?, ?)
+			processing_time_ms, success, response_code, reversal_json
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	err = sqlitex.ExecuteTransient(dbConn, insertSQL, &sqlitex.ExecOptions{
 		Args: []interface{}{
 			sessionID, txName, requestJSON, derefOrNil(responseJSON), processingTimeMs, success, responseCode,
+			derefOrNil(reversalJSON),
 		},
 	})
 	if err != nil {
@@ -243,6 +278,23 @@ func GetTransactionStats(sessionID string) (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	// Get reversed count
+	var reversedCount int
+	err = sqlitex.ExecuteTransient(
+		dbConn,
+		"SELECT COUNT(*) FROM transactions WHERE session_id = ? AND reversal_json IS NOT NULL",
+		&sqlitex.ExecOptions{
+			Args: []interface{}{sessionID},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				reversedCount = int(stmt.ColumnInt64(0))
+				return nil
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get response code distribution
 	responseCodes := make(map[string]int)
 	err = sqlitex.ExecuteTransient(
@@ -271,6 +323,7 @@ func GetTransactionStats(sessionID string) (map[string]interface{}, error) {
 	stats["total_transactions"] = totalCount
 	stats["successful_transactions"] = successCount
 	stats["failed_transactions"] = totalCount - successCount
+	stats["reversed_transactions"] = reversedCount
 	stats["average_processing_time_ms"] = avgProcessingTime
 	stats["response_code_distribution"] = responseCodes
 
@@ -251,6 +251,43 @@ func TestGetTransactionStats(t *testing.T) {
 	}
 }
 
+func TestInsertReversedTransactionMigratesTable(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// A database created before the reversal column existed
+	conn, err := sqlite.OpenConn(dbPath, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	oldSchema := `CREATE TABLE transactions (id INTEGER PRIMARY KEY AUTOINCREMENT, session_id TEXT NOT NULL, timestamp DATETIME DEFAULT CURRENT_TIMESTAMP, transaction_name TEXT, request_json TEXT, response_json TEXT, processing_time_ms INTEGER, success BOOLEAN, response_code TEXT)`
+	if err := sqlitex.ExecuteTransient(conn, oldSchema, nil); err != nil {
+		t.Fatalf("Failed to create old table: %v", err)
+	}
+	conn.Close()
+
+	if err := InitDB(dbPath); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer Close()
+
+	sessionID := "test-session-reversal"
+	reversal := `{"mti":"0421","attempts":2,"response_code":"00"}`
+	if err := InsertReversedTransaction(sessionID, "Purchase", `{"mti":"0200"}`, nil, 0, false, &reversal); err != nil {
+		t.Fatalf("Failed to insert reversed transaction: %v", err)
+	}
+	if err := InsertTransaction(sessionID, "Purchase", `{"mti":"0200"}`, nil, 0, false); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+
+	stats, err := GetTransactionStats(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get transaction stats: %v", err)
+	}
+	if stats["reversed_transactions"] != 1 {
+		t.Errorf("Expected 1 reversed transaction, got %v", stats["reversed_transactions"])
+	}
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
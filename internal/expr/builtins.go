@@ -0,0 +1,290 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+
+	"jiso/internal/utils"
+)
+
+// Func is a builtin expression function
+type Func func(args []interface{}) (interface{}, error)
+
+var (
+	builtinsMu sync.RWMutex
+	builtins   = map[string]Func{
+		"len":        fnLen,
+		"substr":     fnSubstr,
+		"pad":        fnPadLeft,
+		"padLeft":    fnPadLeft,
+		"padRight":   fnPadRight,
+		"upper":      stringFunc(strings.ToUpper),
+		"lower":      stringFunc(strings.ToLower),
+		"trim":       stringFunc(strings.TrimSpace),
+		"num":        fnNum,
+		"int":        fnInt,
+		"str":        fnStr,
+		"abs":        fnAbs,
+		"min":        fnMin,
+		"max":        fnMax,
+		"sprintf":    fnSprintf,
+		"contains":   stringPredicate(strings.Contains),
+		"startsWith": stringPredicate(strings.HasPrefix),
+		"endsWith":   stringPredicate(strings.HasSuffix),
+		"matches":    fnMatches,
+		"in":         fnIn,
+		"exists":     fnExists,
+		"default":    fnDefault,
+		"stan":       func([]interface{}) (interface{}, error) { return utils.GetCounter().GetStan(), nil },
+		"rrn":        func([]interface{}) (interface{}, error) { return utils.GetRRNInstance().GetRRN(), nil },
+		"auth_code":  func([]interface{}) (interface{}, error) { return utils.RandString(6), nil },
+		"datetime":   func([]interface{}) (interface{}, error) { return utils.GetTrxnDateTime(), nil },
+	}
+)
+
+// Register adds or replaces a builtin function. Programs compiled before the call keep the old binding.
+func Register(name string, fn Func) {
+	builtinsMu.Lock()
+	defer builtinsMu.Unlock()
+	builtins[name] = fn
+}
+
+func lookupBuiltin(name string) (Func, bool) {
+	builtinsMu.RLock()
+	defer builtinsMu.RUnlock()
+	fn, ok := builtins[name]
+	return fn, ok
+}
+
+func argCount(args []interface{}, min, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		if min == max {
+			return fmt.Errorf("expected %d argument(s), got %d", min, len(args))
+		}
+		return fmt.Errorf("expected %d to %d argument(s), got %d", min, max, len(args))
+	}
+	return nil
+}
+
+func stringFunc(f func(string) string) Func {
+	return func(args []interface{}) (interface{}, error) {
+		if err := argCount(args, 1, 1); err != nil {
+			return nil, err
+		}
+		return f(ToString(args[0])), nil
+	}
+}
+
+func stringPredicate(f func(string, string) bool) Func {
+	return func(args []interface{}) (interface{}, error) {
+		if err := argCount(args, 2, 2); err != nil {
+			return nil, err
+		}
+		return f(ToString(args[0]), ToString(args[1])), nil
+	}
+}
+
+func fnLen(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return float64(len(ToString(args[0]))), nil
+}
+
+// substr(s, start[, length]) with byte offsets clamped to the string bounds
+func fnSubstr(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 2, 3); err != nil {
+		return nil, err
+	}
+	s := ToString(args[0])
+	start, err := toNumber(args[1])
+	if err != nil {
+		return nil, err
+	}
+	from := clamp(int(start), 0, len(s))
+	to := len(s)
+	if len(args) == 3 {
+		n, err := toNumber(args[2])
+		if err != nil {
+			return nil, err
+		}
+		to = clamp(from+int(n), from, len(s))
+	}
+	return s[from:to], nil
+}
+
+func fnPadLeft(args []interface{}) (interface{}, error) {
+	return pad(args, true)
+}
+
+func fnPadRight(args []interface{}) (interface{}, error) {
+	return pad(args, false)
+}
+
+// pad(s, width[, char]) pads to width with char ("0" by default for left, " " for right)
+func pad(args []interface{}, left bool) (interface{}, error) {
+	if err := argCount(args, 2, 3); err != nil {
+		return nil, err
+	}
+	s := ToString(args[0])
+	width, err := toNumber(args[1])
+	if err != nil {
+		return nil, err
+	}
+	ch := " "
+	if left {
+		ch = "0"
+	}
+	if len(args) == 3 {
+		ch = ToString(args[2])
+		if ch == "" {
+			return nil, fmt.Errorf("pad character must not be empty")
+		}
+	}
+	missing := int(width) - len(s)
+	if missing <= 0 {
+		return s, nil
+	}
+	fill := strings.Repeat(ch, missing)[:missing]
+	if left {
+		return fill + s, nil
+	}
+	return s + fill, nil
+}
+
+func fnNum(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return toNumber(args[0])
+}
+
+func fnInt(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+	n, err := toNumber(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return math.Trunc(n), nil
+}
+
+func fnStr(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return ToString(args[0]), nil
+}
+
+func fnAbs(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+	n, err := toNumber(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return math.Abs(n), nil
+}
+
+func fnMin(args []interface{}) (interface{}, error) {
+	return fold(args, math.Min)
+}
+
+func fnMax(args []interface{}) (interface{}, error) {
+	return fold(args, math.Max)
+}
+
+func fold(args []interface{}, f func(a, b float64) float64) (interface{}, error) {
+	if err := argCount(args, 1, -1); err != nil {
+		return nil, err
+	}
+	acc, err := toNumber(args[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range args[1:] {
+		n, err := toNumber(a)
+		if err != nil {
+			return nil, err
+		}
+		acc = f(acc, n)
+	}
+	return acc, nil
+}
+
+// sprintf(format, args...) passes integral numbers as int64 so %d and %012d work as expected
+func fnSprintf(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 1, -1); err != nil {
+		return nil, err
+	}
+	vals := make([]interface{}, len(args)-1)
+	for i, a := range args[1:] {
+		if n, ok := a.(float64); ok && n == math.Trunc(n) {
+			vals[i] = int64(n)
+			continue
+		}
+		vals[i] = a
+	}
+	return fmt.Sprintf(ToString(args[0]), vals...), nil
+}
+
+func fnMatches(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 2, 2); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(ToString(args[1]))
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(ToString(args[0])), nil
+}
+
+// in(x, a, b, ...) reports whether x equals any of the remaining arguments
+func fnIn(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 1, -1); err != nil {
+		return nil, err
+	}
+	for _, candidate := range args[1:] {
+		eq, err := binaryOp("==", args[0], candidate)
+		if err != nil {
+			return nil, err
+		}
+		if eq.(bool) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func fnExists(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return args[0] != nil, nil
+}
+
+// default(x, fallback) returns fallback when x is missing or empty
+func fnDefault(args []interface{}) (interface{}, error) {
+	if err := argCount(args, 2, 2); err != nil {
+		return nil, err
+	}
+	if args[0] == nil || ToString(args[0]) == "" {
+		return args[1], nil
+	}
+	return args[0], nil
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,340 @@
+// Package expr implements the small expression language used in mock routes and scenarios.
+//
+// Expressions are written inside {{ }} delimiters, e.g. {{req.4 > 100000 ? "05" : "00"}}.
+// They support string/number/bool literals, dotted identifiers resolved through an Env
+// (req.4, req.55.9F26, context.AuthId), arithmetic, comparison, logical operators,
+// the ternary operator and a set of builtin functions (see builtins.go).
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Env resolves dotted identifiers such as "req.4" or "context.AuthId"
+type Env interface {
+	Lookup(name string) (interface{}, bool)
+}
+
+// MapEnv is an Env backed by a flat map of identifier to value
+type MapEnv map[string]interface{}
+
+func (m MapEnv) Lookup(name string) (interface{}, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+// EnvFunc adapts a function to the Env interface
+type EnvFunc func(name string) (interface{}, bool)
+
+func (f EnvFunc) Lookup(name string) (interface{}, bool) {
+	return f(name)
+}
+
+// Program is a compiled expression
+type Program struct {
+	source string
+	eval   evalFunc
+}
+
+// Source returns the expression text the program was compiled from
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Eval evaluates the program against env
+func (p *Program) Eval(env Env) (interface{}, error) {
+	return p.eval(env)
+}
+
+var programCache sync.Map // map[string]*Program
+
+// Compile parses an expression (without {{ }} delimiters). Results are cached by source text.
+func Compile(src string) (*Program, error) {
+	if cached, ok := programCache.Load(src); ok {
+		return cached.(*Program), nil
+	}
+
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+	p := &parser{tokens: tokens}
+	fn, err := p.parseExpression()
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", src, err)
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("expression %q: unexpected %q at position %d", src, t.text, t.pos)
+	}
+
+	prog := &Program{source: src, eval: fn}
+	programCache.Store(src, prog)
+	return prog, nil
+}
+
+// Eval compiles and evaluates an expression (without {{ }} delimiters)
+func Eval(src string, env Env) (interface{}, error) {
+	prog, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return prog.Eval(env)
+}
+
+// IsTemplate reports whether s contains at least one {{ }} expression
+func IsTemplate(s string) bool {
+	start := strings.Index(s, "{{")
+	return start >= 0 && strings.Contains(s[start+2:], "}}")
+}
+
+// Unwrap returns the inner expression if s consists of exactly one {{ }} block
+func Unwrap(s string) (string, bool) {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{{") {
+		return "", false
+	}
+	end := findClose(trimmed, 2)
+	if end < 0 || end+2 != len(trimmed) {
+		return "", false
+	}
+	return strings.TrimSpace(trimmed[2:end]), true
+}
+
+// EvalTemplate evaluates a template. A template made of a single {{ }} block returns the
+// raw expression value (number, bool, string); anything else is rendered to a string.
+func EvalTemplate(s string, env Env) (interface{}, error) {
+	if inner, ok := Unwrap(s); ok {
+		return Eval(inner, env)
+	}
+	return Render(s, env)
+}
+
+// CheckTemplate compiles every {{ }} block in s without evaluating it
+func CheckTemplate(s string) error {
+	rest := s
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			return nil
+		}
+		end := findClose(rest, start+2)
+		if end < 0 {
+			return nil
+		}
+		if _, err := Compile(strings.TrimSpace(rest[start+2 : end])); err != nil {
+			return err
+		}
+		rest = rest[end+2:]
+	}
+}
+
+// Render replaces every {{ }} block in s with the string form of its value
+func Render(s string, env Env) (string, error) {
+	var sb strings.Builder
+	rest := s
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			sb.WriteString(rest)
+			return sb.String(), nil
+		}
+		end := findClose(rest, start+2)
+		if end < 0 {
+			sb.WriteString(rest)
+			return sb.String(), nil
+		}
+		sb.WriteString(rest[:start])
+		v, err := Eval(strings.TrimSpace(rest[start+2:end]), env)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(ToString(v))
+		rest = rest[end+2:]
+	}
+}
+
+// findClose returns the index of the "}}" closing a block opened before from, skipping quoted strings
+func findClose(s string, from int) int {
+	var quote byte
+	for i := from; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			continue
+		}
+		if c == '}' && i+1 < len(s) && s[i+1] == '}' {
+			return i
+		}
+	}
+	return -1
+}
+
+// Truthy converts an expression value to a boolean
+func Truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case string:
+		return t != "" && t != "false" && t != "0"
+	default:
+		return true
+	}
+}
+
+// ToString converts an expression value to its string form. Integral numbers have no decimals.
+func ToString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1e18 {
+			return strconv.FormatInt(int64(t), 10)
+		}
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
+func toNumber(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return t, nil
+	case bool:
+		if t {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot use %q as a number", t)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("cannot use %v as a number", t)
+	}
+}
+
+// isNumeric reports whether v is a number or a string holding one
+func isNumeric(v interface{}) bool {
+	switch t := v.(type) {
+	case float64:
+		return true
+	case string:
+		_, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return err == nil
+	}
+	return false
+}
+
+func binaryOp(op string, l, r interface{}) (interface{}, error) {
+	_, lNum := l.(float64)
+	_, rNum := r.(float64)
+
+	switch op {
+	case "==", "!=":
+		var eq bool
+		if (lNum || rNum) && isNumeric(l) && isNumeric(r) {
+			ln, _ := toNumber(l)
+			rn, _ := toNumber(r)
+			eq = ln == rn
+		} else if l == nil || r == nil {
+			eq = l == nil && r == nil
+		} else {
+			eq = ToString(l) == ToString(r)
+		}
+		if op == "!=" {
+			return !eq, nil
+		}
+		return eq, nil
+
+	case "<", "<=", ">", ">=":
+		var cmp int
+		if isNumeric(l) && isNumeric(r) {
+			ln, _ := toNumber(l)
+			rn, _ := toNumber(r)
+			switch {
+			case ln < rn:
+				cmp = -1
+			case ln > rn:
+				cmp = 1
+			}
+		} else {
+			cmp = strings.Compare(ToString(l), ToString(r))
+		}
+		switch op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+
+	case "+":
+		// Numeric addition when either side is a number; string concatenation otherwise
+		if lNum || rNum {
+			ln, err := toNumber(l)
+			if err != nil {
+				return nil, err
+			}
+			rn, err := toNumber(r)
+			if err != nil {
+				return nil, err
+			}
+			return ln + rn, nil
+		}
+		return ToString(l) + ToString(r), nil
+	}
+
+	ln, err := toNumber(l)
+	if err != nil {
+		return nil, err
+	}
+	rn, err := toNumber(r)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "-":
+		return ln - rn, nil
+	case "*":
+		return ln * rn, nil
+	case "/":
+		if rn == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return ln / rn, nil
+	case "%":
+		if int64(rn) == 0 {
+			return nil, fmt.Errorf("modulo by zero")
+		}
+		return float64(int64(ln) % int64(rn)), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
@@ -0,0 +1,81 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalExpressions(t *testing.T) {
+	env := MapEnv{
+		"req.4":       "000000150000",
+		"req.39":      "00",
+		"req.55.9F26": "11223344",
+		"context.Amt": "2500",
+	}
+
+	tests := []struct {
+		src      string
+		expected interface{}
+	}{
+		{`req.4 > 100000 ? "05" : "00"`, "05"},
+		{`req.4 <= 100000 ? "05" : "00"`, "00"},
+		{`req.39 == "00" && exists(req.55.9F26)`, true},
+		{`!exists(req.38)`, true},
+		{`req.4 + 1`, float64(150001)},
+		{`"A" + req.39`, "A00"},
+		{`substr(req.4, 6)`, "150000"},
+		{`substr(req.4, 6, 2)`, "15"},
+		{`pad(context.Amt, 12)`, "000000002500"},
+		{`padRight("AB", 4, "*")`, "AB**"},
+		{`sprintf("%012d", req.4 * 2)`, "000000300000"},
+		{`in(req.39, "00", "10", "11")`, true},
+		{`default(req.38, "NONE")`, "NONE"},
+		{`(1 + 2) * 3 % 4`, float64(1)},
+		{`-context.Amt`, float64(-2500)},
+		{`matches(req.55.9F26, "^11")`, true},
+		{`len(req.4) == 12`, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.src, func(t *testing.T) {
+			v, err := Eval(tc.src, env)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	for _, src := range []string{`1 +`, `unknown(1)`, `"open`, `(1`, `1 ? 2`, `req.4 @ 1`} {
+		_, err := Eval(src, nil)
+		assert.Error(t, err, src)
+	}
+
+	_, err := Eval(`"abc" * 2`, nil)
+	assert.Error(t, err)
+	_, err = Eval(`1 / 0`, nil)
+	assert.Error(t, err)
+}
+
+func TestTemplates(t *testing.T) {
+	env := MapEnv{"req.11": "000123", "req.4": "5000"}
+
+	out, err := Render(`STAN-{{req.11}}/{{req.4 / 100}}`, env)
+	require.NoError(t, err)
+	assert.Equal(t, "STAN-000123/50", out)
+
+	v, err := EvalTemplate(`{{ req.4 > 1000 }}`, env)
+	require.NoError(t, err)
+	assert.Equal(t, true, v)
+
+	inner, ok := Unwrap(`{{ req.4 == "}}" }}`)
+	require.True(t, ok)
+	assert.Equal(t, `req.4 == "}}"`, inner)
+
+	assert.True(t, IsTemplate("x{{1}}"))
+	assert.False(t, IsTemplate("plain"))
+	assert.NoError(t, CheckTemplate("{{1}} and {{req.4}}"))
+	assert.Error(t, CheckTemplate("{{1 +}}"))
+}
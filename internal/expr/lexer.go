@@ -0,0 +1,124 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize splits an expression source into tokens.
+// Identifiers may carry dotted path segments that start with digits (e.g. "req.55.9F26").
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			quote := c
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < len(src) {
+				if src[i] == '\\' && i+1 < len(src) {
+					switch src[i+1] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[i+1])
+					}
+					i += 2
+					continue
+				}
+				if src[i] == quote {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string starting at position %d", start)
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			// Dotted path segments: req.4, req.55.9F26, context.AuthId
+			for i+1 < len(src) && src[i] == '.' && isIdentPart(src[i+1]) {
+				i++
+				for i < len(src) && isIdentPart(src[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ",", pos: i})
+			i++
+		default:
+			if i+1 < len(src) {
+				two := src[i : i+2]
+				switch two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, token{kind: tokOp, text: two, pos: i})
+					i += 2
+					continue
+				}
+			}
+			if strings.IndexByte("+-*/%<>!?:", c) >= 0 {
+				tokens = append(tokens, token{kind: tokOp, text: string(c), pos: i})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, pos: len(src)})
+	return tokens, nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool { return isIdentStart(c) || isDigit(c) }
@@ -0,0 +1,295 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// evalFunc is a compiled expression node
+type evalFunc func(env Env) (interface{}, error)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseExpression() (evalFunc, error) {
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.acceptOp("?"); !ok {
+		return cond, nil
+	}
+	whenTrue, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.acceptOp(":"); !ok {
+		return nil, fmt.Errorf("expected ':' in conditional at position %d", p.peek().pos)
+	}
+	whenFalse, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return func(env Env) (interface{}, error) {
+		c, err := cond(env)
+		if err != nil {
+			return nil, err
+		}
+		if Truthy(c) {
+			return whenTrue(env)
+		}
+		return whenFalse(env)
+	}, nil
+}
+
+func (p *parser) parseOr() (evalFunc, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(env Env) (interface{}, error) {
+			lv, err := l(env)
+			if err != nil {
+				return nil, err
+			}
+			if Truthy(lv) {
+				return true, nil
+			}
+			rv, err := right(env)
+			if err != nil {
+				return nil, err
+			}
+			return Truthy(rv), nil
+		}
+	}
+}
+
+func (p *parser) parseAnd() (evalFunc, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(env Env) (interface{}, error) {
+			lv, err := l(env)
+			if err != nil {
+				return nil, err
+			}
+			if !Truthy(lv) {
+				return false, nil
+			}
+			rv, err := right(env)
+			if err != nil {
+				return nil, err
+			}
+			return Truthy(rv), nil
+		}
+	}
+}
+
+func (p *parser) parseEquality() (evalFunc, error) {
+	return p.parseBinary(p.parseComparison, "==", "!=")
+}
+
+func (p *parser) parseComparison() (evalFunc, error) {
+	return p.parseBinary(p.parseAdditive, "<", "<=", ">", ">=")
+}
+
+func (p *parser) parseAdditive() (evalFunc, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *parser) parseMultiplicative() (evalFunc, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+func (p *parser) parseBinary(operand func() (evalFunc, error), ops ...string) (evalFunc, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(env Env) (interface{}, error) {
+			lv, err := l(env)
+			if err != nil {
+				return nil, err
+			}
+			rv, err := right(env)
+			if err != nil {
+				return nil, err
+			}
+			return binaryOp(op, lv, rv)
+		}
+	}
+}
+
+func (p *parser) parseUnary() (evalFunc, error) {
+	if op, ok := p.acceptOp("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(env Env) (interface{}, error) {
+			v, err := operand(env)
+			if err != nil {
+				return nil, err
+			}
+			if op == "!" {
+				return !Truthy(v), nil
+			}
+			n, err := toNumber(v)
+			if err != nil {
+				return nil, err
+			}
+			return -n, nil
+		}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (evalFunc, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return func(Env) (interface{}, error) { return n, nil }, nil
+	case tokString:
+		s := t.text
+		return func(Env) (interface{}, error) { return s, nil }, nil
+	case tokLParen:
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("expected ')' at position %d", t.pos)
+		}
+		return inner, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return func(Env) (interface{}, error) { return true, nil }, nil
+		case "false":
+			return func(Env) (interface{}, error) { return false, nil }, nil
+		case "nil", "null":
+			return func(Env) (interface{}, error) { return nil, nil }, nil
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseCall(t)
+		}
+		name := t.text
+		return func(env Env) (interface{}, error) {
+			if env == nil {
+				return nil, nil
+			}
+			v, ok := env.Lookup(name)
+			if !ok {
+				return nil, nil
+			}
+			return v, nil
+		}, nil
+	}
+	if t.kind == tokEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+func (p *parser) parseCall(name token) (evalFunc, error) {
+	fn, ok := lookupBuiltin(name.text)
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+	p.next() // consume '('
+
+	var args []evalFunc
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind == tokComma {
+				p.next()
+				continue
+			}
+			break
+		}
+	}
+	if p.next().kind != tokRParen {
+		return nil, fmt.Errorf("expected ')' to close call to %s", name.text)
+	}
+
+	fnName := name.text
+	return func(env Env) (interface{}, error) {
+		vals := make([]interface{}, len(args))
+		for i, a := range args {
+			v, err := a(env)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+		}
+		res, err := fn(vals)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): %w", fnName, err)
+		}
+		return res, nil
+	}, nil
+}
@@ -31,6 +31,24 @@ type NetworkingStats struct {
 	// Error classification metrics
 	retriableErrors int64
 	permanentErrors int64
+
+	// Target group metrics
+	failovers      int64
+	failbacks      int64
+	targetSwitches []TargetSwitch
+	switchLock     sync.Mutex
+}
+
+// maxTargetSwitches bounds the number of target switch events kept
+const maxTargetSwitches = 100
+
+// TargetSwitch records a connection moving from one target of a group to another
+type TargetSwitch struct {
+	Time     time.Time
+	From     string
+	To       string
+	Reason   string
+	Failback bool
 }
 
 // NewNetworkingStats creates a new NetworkingStats instance
@@ -91,6 +109,27 @@ func (ns *NetworkingStats) RecordError(retriable bool) {
 	}
 }
 
+// RecordTargetSwitch records a failover to another target, or a failback to the primary
+func (ns *NetworkingStats) RecordTargetSwitch(from, to, reason string, failback bool) {
+	if failback {
+		atomic.AddInt64(&ns.failbacks, 1)
+	} else {
+		atomic.AddInt64(&ns.failovers, 1)
+	}
+	ns.switchLock.Lock()
+	defer ns.switchLock.Unlock()
+	ns.targetSwitches = append(ns.targetSwitches, TargetSwitch{
+		Time:     time.Now(),
+		From:     from,
+		To:       to,
+		Reason:   reason,
+		Failback: failback,
+	})
+	if len(ns.targetSwitches) > maxTargetSwitches {
+		ns.targetSwitches = ns.targetSwitches[len(ns.targetSwitches)-maxTargetSwitches:]
+	}
+}
+
 // Getters for metrics
 func (ns *NetworkingStats) ReconnectAttempts() int64 {
 	return atomic.LoadInt64(&ns.reconnectAttempts)
@@ -154,6 +193,23 @@ func (ns *NetworkingStats) PermanentErrors() int64 {
 	return atomic.LoadInt64(&ns.permanentErrors)
 }
 
+func (ns *NetworkingStats) Failovers() int64 {
+	return atomic.LoadInt64(&ns.failovers)
+}
+
+func (ns *NetworkingStats) Failbacks() int64 {
+	return atomic.LoadInt64(&ns.failbacks)
+}
+
+// TargetSwitches returns the most recent target switch events, oldest first
+func (ns *NetworkingStats) TargetSwitches() []TargetSwitch {
+	ns.switchLock.Lock()
+	defer ns.switchLock.Unlock()
+	out := make([]TargetSwitch, len(ns.targetSwitches))
+	copy(out, ns.targetSwitches)
+	return out
+}
+
 // GetAllMetrics returns all networking metrics as a map
 func (ns *NetworkingStats) GetAllMetrics() map[string]interface{} {
 	return map[string]interface{}{
@@ -169,5 +225,7 @@ func (ns *NetworkingStats) GetAllMetrics() map[string]interface{} {
 		"health_check_failures":  ns.HealthCheckFailures(),
 		"retriable_errors":       ns.RetriableErrors(),
 		"permanent_errors":       ns.PermanentErrors(),
+		"failovers":              ns.Failovers(),
+		"failbacks":              ns.Failbacks(),
 	}
 }
@@ -138,6 +138,38 @@ func TestErrorMetrics(t *testing.T) {
 	}
 }
 
+func TestTargetSwitchMetrics(t *testing.T) {
+	stats := NewNetworkingStats()
+
+	stats.RecordTargetSwitch("primary:9000", "backup:9000", "reconnect attempts exhausted", false)
+	stats.RecordTargetSwitch("backup:9000", "primary:9000", "primary passed echo test", true)
+
+	if stats.Failovers() != 1 {
+		t.Errorf("Expected failovers 1, got %d", stats.Failovers())
+	}
+	if stats.Failbacks() != 1 {
+		t.Errorf("Expected failbacks 1, got %d", stats.Failbacks())
+	}
+
+	events := stats.TargetSwitches()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 target switch events, got %d", len(events))
+	}
+	if events[0].To != "backup:9000" || events[0].Failback {
+		t.Errorf("Unexpected first event %+v", events[0])
+	}
+	if events[1].To != "primary:9000" || !events[1].Failback {
+		t.Errorf("Unexpected second event %+v", events[1])
+	}
+
+	for i := 0; i < maxTargetSwitches+10; i++ {
+		stats.RecordTargetSwitch("a", "b", "test", false)
+	}
+	if len(stats.TargetSwitches()) != maxTargetSwitches {
+		t.Errorf("Expected event log capped at %d, got %d", maxTargetSwitches, len(stats.TargetSwitches()))
+	}
+}
+
 func TestGetAllMetrics(t *testing.T) {
 	stats := NewNetworkingStats()
 
@@ -0,0 +1,123 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+
+	"jiso/internal/transactions"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite reports one scenario
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase reports one scenario step
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// seconds formats milliseconds as the seconds JUnit reports durations in
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+// ExportJUnitReport exports a suite as JUnit XML: a testsuite per scenario and a
+// testcase per step. A step that failed with continue_on_failure passes, with its
+// failure in system-out, as it did not fail the scenario.
+func ExportJUnitReport(suite *transactions.SuiteReport, filePath string) error {
+	if suite == nil {
+		return fmt.Errorf("suite report is nil")
+	}
+
+	doc := junitTestSuites{Name: suite.Name, Time: seconds(suite.DurationMs)}
+	for _, report := range suite.Scenarios {
+		ts := junitTestSuite{
+			Name:      report.ScenarioName,
+			Time:      seconds(report.DurationMs),
+			Timestamp: report.StartTime.Format("2006-01-02T15:04:05"),
+		}
+		for _, step := range report.Steps {
+			tc := junitTestCase{Name: step.StepName, ClassName: report.ScenarioName, Time: seconds(step.LatencyMs)}
+			if phase := stepPhase(step); phase != "" {
+				tc.Name = phase + ": " + step.StepName
+			}
+			switch {
+			case step.Skipped:
+				tc.Skipped = &struct{}{}
+				ts.Skipped++
+			case !step.Success && step.ContinuedOnFail:
+				tc.SystemOut = "continued after failure: " + stepFailure(step)
+			case !step.Success:
+				tc.Failure = &junitFailure{Message: firstLine(stepFailure(step)), Text: stepFailure(step)}
+				ts.Failures++
+			}
+			ts.Cases = append(ts.Cases, tc)
+		}
+		ts.Tests = len(ts.Cases)
+		doc.Tests += ts.Tests
+		doc.Failures += ts.Failures
+		doc.Skipped += ts.Skipped
+		doc.Suites = append(doc.Suites, ts)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JUnit report: %w", err)
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write JUnit report to %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// stepFailure describes why a step failed: its error and failed assertions, one per line
+func stepFailure(step transactions.StepResult) string {
+	var lines []string
+	if step.Error != "" {
+		lines = append(lines, step.Error)
+	}
+	for _, v := range step.ValidationErrors {
+		lines = append(lines, fmt.Sprintf("field %s: %s (expected '%s', actual '%s')", v.Field, v.Message, v.Expected, v.Actual))
+	}
+	if len(lines) == 0 {
+		return "step failed"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
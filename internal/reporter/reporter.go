@@ -1,9 +1,11 @@
 package reporter
 
 import (
+	"cmp"
 	"fmt"
 	json "github.com/goccy/go-json"
 	"os"
+	"strings"
 
 	"jiso/internal/transactions"
 )
@@ -28,15 +30,32 @@ func PrintTerminalReport(report *transactions.TestReport) {
 	fmt.Printf(" Start Time:  %s\n", report.StartTime.Format("2006-01-02 15:04:05"))
 	fmt.Printf(" Total Time:  %d ms\n", report.DurationMs)
 	fmt.Println("--------------------------------------------------------------------------------")
-	fmt.Println(" STEPS:")
 
+	phase := "-"
 	for i, step := range report.Steps {
+		if p := stepPhase(step); p != phase {
+			phase = p
+			fmt.Printf(" %s:\n", strings.ToUpper(cmp.Or(phase, "steps")))
+		}
 		stepStatus := "PASSED ✅"
-		if !step.Success {
+		switch {
+		case step.Skipped:
+			stepStatus = "SKIPPED ⏭️"
+		case !step.Success && step.ContinuedOnFail:
+			stepStatus = "FAILED ⚠️ continued"
+		case !step.Success:
 			stepStatus = "FAILED ❌"
 		}
 		fmt.Printf("  Step %d: %-30s [%s] (%d ms)\n", i+1, step.StepName, stepStatus, step.LatencyMs)
 
+		if step.Attempts > 1 {
+			fmt.Printf("    └─ 🔁 %d attempts\n", step.Attempts)
+		}
+
+		if step.Messages > 1 {
+			fmt.Printf("    └─ 📨 %d messages received\n", step.Messages)
+		}
+
 		if step.Error != "" {
 			fmt.Printf("    └─ 🔴 Error: %s\n", step.Error)
 		}
@@ -45,10 +64,43 @@ func PrintTerminalReport(report *transactions.TestReport) {
 			fmt.Printf("    └─ ⚠️ Field %s Validation Failed: %s (Expected: '%s', Actual: '%s')\n",
 				vErr.Field, vErr.Message, vErr.Expected, vErr.Actual)
 		}
+
+		if rev := step.Reversal; rev != nil {
+			outcome := "no response"
+			switch {
+			case rev.Acknowledged:
+				outcome = fmt.Sprintf("response code %s", rev.ResponseCode)
+			case rev.Error != "":
+				outcome = rev.Error
+			}
+			fmt.Printf("    └─ ↩️ Reversal %s sent %d time(s): %s\n", rev.MTI, rev.Attempts, outcome)
+		}
 	}
 	fmt.Println("================================================================================")
 }
 
+// PrintSuiteSummary outputs one line per scenario of a suite and the totals to stdout
+func PrintSuiteSummary(suite *transactions.SuiteReport) {
+	if suite == nil {
+		return
+	}
+
+	fmt.Println("================================================================================")
+	fmt.Printf(" SUITE REPORT: %s\n", suite.Name)
+	fmt.Println("================================================================================")
+	for _, report := range suite.Scenarios {
+		status := "PASSED ✅"
+		if !report.Success {
+			status = "FAILED ❌"
+		}
+		fmt.Printf("  %-50s [%s] (%d ms)\n", report.ScenarioName, status, report.DurationMs)
+	}
+	fmt.Println("--------------------------------------------------------------------------------")
+	failed := suite.Failed()
+	fmt.Printf(" %d scenarios, %d passed, %d failed in %d ms\n", len(suite.Scenarios), len(suite.Scenarios)-failed, failed, suite.DurationMs)
+	fmt.Println("================================================================================")
+}
+
 // ExportJSONReport exports the TestReport struct to a specified JSON file path
 func ExportJSONReport(report *transactions.TestReport, filePath string) error {
 	if report == nil {
@@ -66,3 +118,12 @@ func ExportJSONReport(report *transactions.TestReport, filePath string) error {
 
 	return nil
 }
+
+// stepPhase names the part of a scenario a step ran in: setup, finally or teardown,
+// or empty for its steps
+func stepPhase(step transactions.StepResult) string {
+	if step.Finally {
+		return "finally"
+	}
+	return step.Fixture
+}
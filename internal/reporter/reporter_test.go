@@ -38,3 +38,40 @@ func TestExportJSONReport(t *testing.T) {
 	_, err = os.Stat(outPath)
 	assert.NoError(t, err)
 }
+
+func TestExportJUnitReport(t *testing.T) {
+	suite := &transactions.SuiteReport{
+		Name:       "ci",
+		DurationMs: 1500,
+		Scenarios: []*transactions.TestReport{
+			{
+				ScenarioName: "Purchase",
+				Success:      false,
+				StartTime:    time.Now(),
+				DurationMs:   1200,
+				Steps: []transactions.StepResult{
+					{StepName: "Sign On", Success: true, LatencyMs: 200},
+					{StepName: "Purchase", LatencyMs: 1000, ValidationErrors: []transactions.ValidationError{
+						{Field: "39", Expected: "00", Actual: "05", Message: "Field 39 exact match assertion failed"},
+					}},
+					{StepName: "Retry", Success: true, Skipped: true},
+					{StepName: "Reverse", Finally: true, ContinuedOnFail: true, Error: "network send failed"},
+					{StepName: "Sign Off", Fixture: transactions.FixtureTeardown, Success: true},
+				},
+			},
+		},
+	}
+
+	outPath := filepath.Join(t.TempDir(), "junit.xml")
+	require.NoError(t, ExportJUnitReport(suite, outPath))
+
+	data, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	xml := string(data)
+	assert.Contains(t, xml, `<testsuites name="ci" tests="5" failures="1" skipped="1" time="1.500">`)
+	assert.Contains(t, xml, `<testcase name="Purchase" classname="Purchase" time="1.000">`)
+	assert.Contains(t, xml, `<failure message="field 39: Field 39 exact match assertion failed (expected &#39;00&#39;, actual &#39;05&#39;)">`)
+	assert.Contains(t, xml, `<testcase name="finally: Reverse"`)
+	assert.Contains(t, xml, `<testcase name="teardown: Sign Off"`)
+	assert.Contains(t, xml, `<system-out>continued after failure: network send failed</system-out>`)
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"jiso/internal/config"
+
+	json "github.com/goccy/go-json"
+	"github.com/moov-io/iso8583"
+)
+
+// AdminHandler returns the HTTP control API of the mock server:
+//
+//	GET    /routes                    list routes
+//	POST   /routes                    add a route (MockRouteConfig JSON body)
+//	DELETE /routes/{name}             remove a route
+//	POST   /routes/{name}/enable      enable a route
+//	POST   /routes/{name}/disable     disable a route
+//	PUT    /routes/{name}/fault       set a route's fault mode ({"fault": "stall", "stall_ms": 5000})
+//	GET    /stats                     traffic statistics
+//	GET    /connections               active client connections
+//	POST   /connections/{id}/send     push a message to a client ({"mti": "0800", "fields": {"70": "301"}})
+//	GET    /outbound                  server-initiated message definitions
+//	GET    /outbound/log              recent server-initiated messages and the clients' responses
+//	POST   /outbound/{name}/send      send a defined message to all clients, or one with ?conn=<id>
+func (s *Server) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /routes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Routes())
+	})
+	mux.HandleFunc("POST /routes", func(w http.ResponseWriter, r *http.Request) {
+		var route config.MockRouteConfig
+		if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid route JSON: %w", err))
+			return
+		}
+		if err := s.AddRoute(route); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, errRouteExists) {
+				status = http.StatusConflict
+			}
+			writeError(w, status, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, route)
+	})
+	mux.HandleFunc("DELETE /routes/{name}", func(w http.ResponseWriter, r *http.Request) {
+		s.adminResult(w, s.RemoveRoute(r.PathValue("name")))
+	})
+	mux.HandleFunc("POST /routes/{name}/enable", func(w http.ResponseWriter, r *http.Request) {
+		s.adminResult(w, s.SetRouteEnabled(r.PathValue("name"), true))
+	})
+	mux.HandleFunc("POST /routes/{name}/disable", func(w http.ResponseWriter, r *http.Request) {
+		s.adminResult(w, s.SetRouteEnabled(r.PathValue("name"), false))
+	})
+	mux.HandleFunc("PUT /routes/{name}/fault", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Fault   string `json:"fault"`
+			StallMs int    `json:"stall_ms"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid fault JSON: %w", err))
+			return
+		}
+		s.adminResult(w, s.SetRouteFault(r.PathValue("name"), body.Fault, body.StallMs))
+	})
+
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.stats.Snapshot())
+	})
+	mux.HandleFunc("GET /connections", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Connections())
+	})
+	mux.HandleFunc("POST /connections/{id}/send", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			MTI    string                 `json:"mti"`
+			Fields map[string]interface{} `json:"fields"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid message JSON: %w", err))
+			return
+		}
+		msg, err := s.buildMessage(body.MTI, body.Fields)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.Send(r.PathValue("id"), msg); err != nil {
+			s.adminResult(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "sent"})
+	})
+
+	mux.HandleFunc("GET /outbound", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Outbound())
+	})
+	mux.HandleFunc("GET /outbound/log", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.OutboundLog())
+	})
+	mux.HandleFunc("POST /outbound/{name}/send", func(w http.ResponseWriter, r *http.Request) {
+		sent, err := s.SendOutbound(r.PathValue("name"), r.URL.Query().Get("conn"))
+		if err != nil {
+			s.adminResult(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]int{"sent": sent})
+	})
+
+	return mux
+}
+
+// StartAdmin serves the HTTP control API on addr (e.g. "127.0.0.1:8081") until Stop is called
+func (s *Server) StartAdmin(addr string) error {
+	srv, err := ServeAdmin(addr, s.AdminHandler())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	previous := s.admin
+	s.admin = srv
+	s.mu.Unlock()
+	StopAdmin(previous)
+	return nil
+}
+
+func (s *Server) stopAdmin() {
+	s.mu.Lock()
+	srv := s.admin
+	s.admin = nil
+	s.mu.Unlock()
+	StopAdmin(srv)
+}
+
+// ListenerAdminHandler returns the control API of a multi-listener mock server. Each listener's
+// API (see AdminHandler) is mounted under /listeners/{name}/, and GET /listeners lists them.
+func ListenerAdminHandler(servers map[string]*Server) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /listeners", func(w http.ResponseWriter, r *http.Request) {
+		type listenerInfo struct {
+			Name        string `json:"name"`
+			Port        string `json:"port"`
+			Header      string `json:"header"`
+			Connections int    `json:"connections"`
+		}
+		names := make([]string, 0, len(servers))
+		for name := range servers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		infos := make([]listenerInfo, 0, len(names))
+		for _, name := range names {
+			s := servers[name]
+			infos = append(infos, listenerInfo{
+				Name:        name,
+				Port:        s.GetPort(),
+				Header:      s.GetHeaderType(),
+				Connections: s.ActiveConnections(),
+			})
+		}
+		writeJSON(w, http.StatusOK, infos)
+	})
+	for name, s := range servers {
+		prefix := "/listeners/" + name
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, s.AdminHandler()))
+	}
+	return mux
+}
+
+// ServeAdmin serves an admin handler on addr in the background. Stop it with StopAdmin.
+func ServeAdmin(addr string, h http.Handler) (*http.Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("\n[SERVER] ❌ Admin API stopped: %v\n", err)
+		}
+	}()
+	return srv, nil
+}
+
+// StopAdmin shuts down an admin API started with ServeAdmin. A nil server is ignored.
+func StopAdmin(srv *http.Server) {
+	if srv == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+}
+
+// buildMessage composes a message from an MTI and field values using the active spec
+func (s *Server) buildMessage(mti string, fields map[string]interface{}) (*iso8583.Message, error) {
+	if mti == "" {
+		return nil, fmt.Errorf("mti is required")
+	}
+	spec, _ := s.current()
+	msg := iso8583.NewMessage(spec)
+	msg.MTI(mti)
+	for key, val := range fields {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field ID %q", key)
+		}
+		if err := setResponseFieldValue(msg, spec, id, val); err != nil {
+			return nil, fmt.Errorf("field %d: %w", id, err)
+		}
+	}
+	return msg, nil
+}
+
+// adminResult reports the outcome of a mutation: 404 for unknown routes/connections, 400 otherwise
+func (s *Server) adminResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case strings.Contains(err.Error(), "not found"):
+		writeError(w, http.StatusNotFound, err)
+	default:
+		writeError(w, http.StatusBadRequest, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
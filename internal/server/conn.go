@@ -0,0 +1,201 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"jiso/internal/utils"
+
+	"github.com/moov-io/iso8583"
+)
+
+// serverConn is an accepted client connection together with its write lock and counters
+type serverConn struct {
+	id          string
+	ordinal     int64
+	conn        net.Conn
+	connectedAt time.Time
+	writeMu     sync.Mutex
+	requests    int64
+	pendingMu   sync.Mutex
+	pending     map[string]*OutboundResult // Server-initiated messages awaiting a response, by STAN
+}
+
+// ConnectionInfo describes an active client connection
+type ConnectionInfo struct {
+	ID          string    `json:"id"`
+	RemoteAddr  string    `json:"remote_addr"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Requests    int64     `json:"requests"`
+	ClientCN    string    `json:"client_cn,omitempty"`
+}
+
+// ConnContext describes the client connection a request arrived on. It is exposed to routes
+// through match_conn and as conn.* in expressions.
+type ConnContext struct {
+	ID         string            // Connection ID, e.g. "c3"
+	Ordinal    int64             // 1 for the first connection accepted by the server, 2 for the second, ...
+	RemoteAddr string            // Client address as host:port
+	RemoteIP   string            // Client host
+	LocalPort  string            // Listening port the client connected to
+	StationID  string            // VISA source station ID from the request header, if any
+	RequestNum int64             // 1 for the first request on this connection, 2 for the second, ...
+	ClientCN   string            // Common name of the verified TLS client certificate, if any
+	Header     map[string]string // Request header fields by header.<name> key, for header types that have them
+}
+
+// Lookup resolves a connection attribute by its match_conn / conn.* name
+func (cc *ConnContext) Lookup(name string) (string, bool) {
+	if cc == nil {
+		return "", false
+	}
+	switch name {
+	case "id":
+		return cc.ID, true
+	case "ordinal":
+		return strconv.FormatInt(cc.Ordinal, 10), true
+	case "remote_addr":
+		return cc.RemoteAddr, true
+	case "remote_ip":
+		return cc.RemoteIP, true
+	case "port":
+		return cc.LocalPort, true
+	case "station_id":
+		return cc.StationID, cc.StationID != ""
+	case "request_num":
+		return strconv.FormatInt(cc.RequestNum, 10), true
+	case "client_cn":
+		return cc.ClientCN, cc.ClientCN != ""
+	}
+	return "", false
+}
+
+// HeaderField resolves a field of the request header by its header.<name> name
+func (cc *ConnContext) HeaderField(name string) (string, bool) {
+	if cc == nil {
+		return "", false
+	}
+	v, ok := cc.Header[name]
+	return v, ok
+}
+
+// context builds the routing context for the requestNum-th request on c
+func (c *serverConn) context(requestNum int64, stationID string) *ConnContext {
+	cc := &ConnContext{
+		ID:         c.id,
+		Ordinal:    c.ordinal,
+		RemoteAddr: c.conn.RemoteAddr().String(),
+		StationID:  stationID,
+		RequestNum: requestNum,
+	}
+	cc.RemoteIP, _, _ = net.SplitHostPort(cc.RemoteAddr)
+	_, cc.LocalPort, _ = net.SplitHostPort(c.conn.LocalAddr().String())
+	cc.ClientCN = c.clientCN()
+	return cc
+}
+
+// clientCN returns the common name of the client's verified TLS certificate
+func (c *serverConn) clientCN() string {
+	tc, ok := c.conn.(*tls.Conn)
+	if !ok {
+		return ""
+	}
+	if chains := tc.ConnectionState().VerifiedChains; len(chains) > 0 && len(chains[0]) > 0 {
+		return chains[0][0].Subject.CommonName
+	}
+	return ""
+}
+
+func (c *serverConn) info() ConnectionInfo {
+	return ConnectionInfo{
+		ID:          c.id,
+		RemoteAddr:  c.conn.RemoteAddr().String(),
+		ConnectedAt: c.connectedAt,
+		Requests:    atomic.LoadInt64(&c.requests),
+		ClientCN:    c.clientCN(),
+	}
+}
+
+// Connections lists the active client connections, oldest first
+func (s *Server) Connections() []ConnectionInfo {
+	s.connsMu.Lock()
+	infos := make([]ConnectionInfo, 0, len(s.conns))
+	for _, c := range s.conns {
+		infos = append(infos, c.info())
+	}
+	s.connsMu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ConnectedAt.Before(infos[j].ConnectedAt)
+	})
+	return infos
+}
+
+// Send pushes a server-initiated message to the client connection with the given ID.
+// Like a defined outbound message it is tracked by STAN, so the client's answer is
+// recorded in the outbound log instead of being routed as a request.
+func (s *Server) Send(connID string, msg *iso8583.Message) error {
+	c := s.findConn(connID)
+	if c == nil {
+		return fmt.Errorf("connection %s not found", connID)
+	}
+	return s.push(c, adHocOutbound, msg)
+}
+
+// write frames msg with the listener's header and writes it to c
+func (s *Server) write(c *serverConn, msg *iso8583.Message) error {
+	packed, err := msg.Pack()
+	if err != nil {
+		return fmt.Errorf("packing message: %w", err)
+	}
+	header, err := utils.SelectServerHeader(s.GetHeaderType())
+	if err != nil {
+		return err
+	}
+	header.SetLength(len(packed))
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := header.WriteTo(c.conn); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+	if th, ok := header.(utils.TrailerHeader); ok {
+		packed = append(packed, th.Trailer(packed)...)
+	}
+	if _, err := c.conn.Write(packed); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) findConn(connID string) *serverConn {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	for _, c := range s.conns {
+		if c.id == connID {
+			return c
+		}
+	}
+	return nil
+}
+
+// trackConn registers an accepted connection under the next sequential ID
+func (s *Server) trackConn(conn net.Conn) *serverConn {
+	ordinal := atomic.AddInt64(&s.connSeq, 1)
+	c := &serverConn{
+		id:          "c" + strconv.FormatInt(ordinal, 10),
+		ordinal:     ordinal,
+		conn:        conn,
+		connectedAt: time.Now(),
+	}
+	s.connsMu.Lock()
+	s.conns[conn] = c
+	s.connsMu.Unlock()
+	return c
+}
@@ -1,16 +1,22 @@
 package server
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"jiso/internal/config"
 	"jiso/internal/utils"
 
 	"github.com/moov-io/iso8583"
+	"github.com/moov-io/iso8583/network"
 )
 
 // Server represents an embedded ISO8583 Mock Server
@@ -24,11 +30,46 @@ type Server struct {
 	running    bool
 	port       string
 	stopChan   chan struct{}
-	conns      map[net.Conn]struct{}
+	conns      map[net.Conn]*serverConn
 	connsMu    sync.Mutex
+	connSeq    int64
 	stats      *ServerStats
+	admin      *http.Server
+	tlsConfig  *tls.Config // nil for plain TCP
+	frames     utils.FrameLimits
+
+	outbound     []config.MockOutboundConfig
+	outboundStop chan struct{}
+	outboundMu   sync.Mutex
+	outboundLog  []*OutboundResult
 }
 
+// DefaultFrameLimits accepts any frame a 2-byte length header can announce
+var DefaultFrameLimits = utils.FrameLimits{MinSize: 1, MaxSize: 0xFFFF}
+
+// ListenerFrameLimits returns the frame limits configured on a mock listener;
+// unset values keep DefaultFrameLimits
+func ListenerFrameLimits(l config.MockListenerConfig) (utils.FrameLimits, error) {
+	if err := l.ValidateFrameLimits(); err != nil {
+		return DefaultFrameLimits, err
+	}
+	limits := DefaultFrameLimits
+	if l.MinMessageSize > 0 {
+		limits.MinSize = l.MinMessageSize
+	}
+	if l.MaxMessageSize > 0 {
+		limits.MaxSize = l.MaxMessageSize
+	}
+	if limits.MinSize > limits.MaxSize {
+		return limits, fmt.Errorf("min_message_size %d exceeds max_message_size %d", limits.MinSize, limits.MaxSize)
+	}
+	limits.Skip = l.OnInvalidFrame == utils.InvalidFrameSkip
+	return limits, nil
+}
+
+// tlsHandshakeTimeout bounds the TLS handshake of an accepted client connection
+const tlsHandshakeTimeout = 10 * time.Second
+
 // NewServer creates a new Server instance
 func NewServer(spec *iso8583.MessageSpec, routes []config.MockRouteConfig, headerType string) *Server {
 	if spec == nil {
@@ -42,10 +83,79 @@ func NewServer(spec *iso8583.MessageSpec, routes []config.MockRouteConfig, heade
 		routes:     routes,
 		matcher:    NewMatcher(routes),
 		headerType: headerType,
-		conns:      make(map[net.Conn]struct{}),
+		conns:      make(map[net.Conn]*serverConn),
 		stopChan:   make(chan struct{}),
 		stats:      NewServerStats(),
+		frames:     DefaultFrameLimits,
+	}
+}
+
+// SetFrameLimits sets the accepted request sizes and what happens to invalid frames on new connections
+func (s *Server) SetFrameLimits(limits utils.FrameLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frames = limits
+}
+
+// GetFrameLimits returns the accepted request sizes and invalid frame policy
+func (s *Server) GetFrameLimits() utils.FrameLimits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.frames
+}
+
+// SetTLSConfig makes the server accept TLS connections from the next Start; nil selects plain TCP
+func (s *Server) SetTLSConfig(cfg *tls.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tlsConfig = cfg
+}
+
+// IsTLS reports whether the server accepts TLS connections
+func (s *Server) IsTLS() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tlsConfig != nil
+}
+
+// SetStateStore replaces the account ledger used by stateful mock routes
+func (s *Server) SetStateStore(store StateStore) {
+	_, m := s.current()
+	m.SetStateStore(store)
+}
+
+// GetStateStore returns the account ledger used by stateful mock routes
+func (s *Server) GetStateStore() StateStore {
+	_, m := s.current()
+	return m.GetStateStore()
+}
+
+// Reload atomically swaps the spec and mock routes. The listener, open TCP sessions and the
+// account ledger are kept; requests already being processed finish with the previous routes.
+// A nil spec keeps the current one.
+func (s *Server) Reload(spec *iso8583.MessageSpec, routes []config.MockRouteConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if spec != nil {
+		s.spec = spec
 	}
+	s.swapRoutesLocked(routes)
+}
+
+// swapRoutesLocked installs a new matcher for routes, keeping the account ledger. Callers hold s.mu.
+func (s *Server) swapRoutesLocked(routes []config.MockRouteConfig) {
+	m := NewMatcher(routes)
+	m.SetStateStore(s.matcher.GetStateStore())
+	s.routes = routes
+	s.matcher = m
+}
+
+// current returns the active spec and matcher
+func (s *Server) current() (*iso8583.MessageSpec, *Matcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spec, s.matcher
 }
 
 // SetHeaderType updates the TCP header format type for the server
@@ -83,7 +193,14 @@ func (s *Server) Start(port string) error {
 		s.mu.Unlock()
 		return fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
+	if s.tlsConfig != nil {
+		l = tls.NewListener(l, s.tlsConfig)
+	}
 
+	// Port "0" picks an ephemeral port; record the one the listener got
+	if _, actual, err := net.SplitHostPort(l.Addr().String()); err == nil {
+		port = actual
+	}
 	s.listener = l
 	s.port = port
 	s.running = true
@@ -92,10 +209,11 @@ func (s *Server) Start(port string) error {
 	s.mu.Unlock()
 
 	go s.acceptLoop()
+	s.startSchedules()
 	return nil
 }
 
-// Stop terminates the TCP listener and closes all active client connections
+// Stop terminates the TCP listener, closes all active client connections and stops the admin API
 func (s *Server) Stop() error {
 	s.mu.Lock()
 	if !s.running {
@@ -108,15 +226,18 @@ func (s *Server) Stop() error {
 		s.listener.Close()
 	}
 	s.mu.Unlock()
+	s.stopSchedules()
 
 	// Close all active connections
 	s.connsMu.Lock()
 	for conn := range s.conns {
 		conn.Close()
 	}
-	s.conns = make(map[net.Conn]struct{})
+	s.conns = make(map[net.Conn]*serverConn)
 	s.connsMu.Unlock()
 
+	s.stopAdmin()
+
 	return nil
 }
 
@@ -153,15 +274,55 @@ func (s *Server) acceptLoop() {
 			}
 		}
 
-		s.connsMu.Lock()
-		s.conns[conn] = struct{}{}
-		s.connsMu.Unlock()
+		go s.handleConn(s.trackConn(conn))
+	}
+}
 
-		go s.handleConn(conn)
+// replyHeader returns the header for a response to the request read with reqHeader.
+// A TPDU is answered with its destination and source NII swapped, a VISA header with
+// its station IDs swapped and its round-trip information echoed.
+func replyHeader(hType string, reqHeader network.Header) (network.Header, error) {
+	header, err := utils.SelectServerHeader(hType)
+	if err != nil {
+		return nil, err
+	}
+	switch req := reqHeader.(type) {
+	case *utils.FramingHeader:
+		if tpdu := req.ReplyTPDU(); tpdu != nil {
+			header.(*utils.FramingHeader).SetTPDU(tpdu)
+		}
+	case *utils.VisaHeader:
+		if peer := req.PeerFields(); peer.HeaderLength > 0 {
+			vh := header.(*utils.VisaHeader)
+			fields := vh.Fields()
+			fields.DestinationStation = peer.SourceStation
+			fields.SourceStation = peer.DestinationStation
+			fields.RoundTrip = peer.RoundTrip
+			vh.SetFields(fields)
+		}
 	}
+	return header, nil
 }
 
-func (s *Server) handleConn(conn net.Conn) {
+// applyResponseHeader sets the response_header fields of a route on header
+func applyResponseHeader(header network.Header, fields map[string]string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	fh, ok := header.(utils.FieldHeader)
+	if !ok {
+		return fmt.Errorf("header type has no fields")
+	}
+	for name, value := range fields {
+		if err := fh.SetHeaderField(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleConn(sc *serverConn) {
+	conn := sc.conn
 	defer func() {
 		conn.Close()
 		s.connsMu.Lock()
@@ -169,17 +330,25 @@ func (s *Server) handleConn(conn net.Conn) {
 		s.connsMu.Unlock()
 	}()
 
+	if tc, ok := conn.(*tls.Conn); ok {
+		_ = tc.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+		if err := tc.Handshake(); err != nil {
+			fmt.Printf("\n[SERVER] 🔒 TLS handshake with %s failed: %v\n", conn.RemoteAddr(), err)
+			return
+		}
+		_ = tc.SetDeadline(time.Time{})
+	}
+
 	s.mu.Lock()
 	hType := s.headerType
-	spec := s.spec
+	frames := s.frames
 	s.mu.Unlock()
 
 	header, err := utils.SelectServerHeader(hType)
 	if err != nil {
 		return
 	}
-
-	var writeMu sync.Mutex
+	readLength := utils.ReadFrameLengthWrapper(header, frames)
 
 	for {
 		select {
@@ -188,17 +357,13 @@ func (s *Server) handleConn(conn net.Conn) {
 		default:
 		}
 
-		// Read TCP header length
-		_, err := header.ReadFrom(conn)
+		// Read TCP header length; invalid frames close the connection unless the listener skips them
+		payloadLen, err := readLength(conn)
 		if err != nil {
-			if err == io.EOF || strings.Contains(err.Error(), "closed") {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || strings.Contains(err.Error(), "closed") {
 				return
 			}
-			return
-		}
-
-		payloadLen := header.Length()
-		if payloadLen <= 0 || payloadLen > 65535 {
+			fmt.Printf("\n[SERVER] ❌ Invalid frame from %s: %v\n", conn.RemoteAddr(), err)
 			return
 		}
 
@@ -207,6 +372,22 @@ func (s *Server) handleConn(conn net.Conn) {
 		if err != nil {
 			return
 		}
+		if th, ok := header.(utils.TrailerHeader); ok {
+			if err := th.ReadTrailer(conn, payload); err != nil {
+				fmt.Printf("\n[SERVER] ❌ Invalid frame from %s: %v\n", conn.RemoteAddr(), err)
+				if !frames.Skip {
+					return
+				}
+				continue
+			}
+		}
+		respHeader, err := replyHeader(hType, header)
+		if err != nil {
+			return
+		}
+
+		// Spec and matcher are resolved per message so a reload applies to open sessions
+		spec, matcher := s.current()
 
 		// Unpack request message
 		req := iso8583.NewMessage(spec)
@@ -214,12 +395,23 @@ func (s *Server) handleConn(conn net.Conn) {
 			fmt.Printf("\n[SERVER] ❌ Error unpacking request payload: %v\n", err)
 			continue
 		}
+		if s.matchOutboundResponse(sc, req) {
+			continue
+		}
+		stationID := ""
+		if vh, ok := header.(*utils.VisaHeader); ok {
+			stationID = vh.PeerStationID()
+		}
+		cc := sc.context(atomic.AddInt64(&sc.requests, 1), stationID)
+		if fh, ok := header.(utils.FieldHeader); ok {
+			cc.Header = fh.HeaderFields()
+		}
 
-		go func(req *iso8583.Message) {
+		go func(req *iso8583.Message, cc *ConnContext) {
 			mti, _ := req.GetMTI()
 
 			// Match and compose response (simulated latency/jitter sleep happens asynchronously)
-			matchedRoute, resp, err := s.matcher.MatchAndCompose(req, spec)
+			matchedRoute, resp, err := matcher.MatchAndComposeConn(req, spec, cc)
 			if err != nil || resp == nil {
 				fmt.Printf("\n[SERVER] ❌ Error matching/composing response for MTI %s: %v\n", mti, err)
 				return
@@ -233,6 +425,11 @@ func (s *Server) handleConn(conn net.Conn) {
 					conn.Close()
 					return
 				}
+				if matchedRoute.SwallowsRequest() {
+					fmt.Printf("\n[SERVER] 🔇 Matched Route '%s' for MTI %s -> Swallowing request (no response)\n", routeName, mti)
+					s.stats.RecordMessage(mti, routeName, "")
+					return
+				}
 			}
 
 			respCode := ""
@@ -250,28 +447,23 @@ func (s *Server) handleConn(conn net.Conn) {
 			// Record served message statistics
 			s.stats.RecordMessage(mti, routeName, respCode)
 
-			// Pack response
-			respPacked, err := resp.Pack()
-			if err != nil {
-				fmt.Printf("[SERVER] ❌ Error packing response for route '%s': %v\n", routeName, err)
-				return
+			if matchedRoute != nil {
+				if err := applyResponseHeader(respHeader, matchedRoute.ResponseHeader); err != nil {
+					fmt.Printf("\n[SERVER] ❌ Route '%s' response header: %v\n", routeName, err)
+				}
 			}
 
-			// Send response with TCP header
-			respHeader, err := utils.SelectServerHeader(hType)
-			if err != nil {
-				return
+			if matchedRoute != nil && matchedRoute.Fault != "" {
+				fmt.Printf("\n[SERVER] 💥 Injecting fault '%s' for route '%s'\n", matchedRoute.Fault, routeName)
 			}
-			respHeader.SetLength(len(respPacked))
 
-			writeMu.Lock()
-			defer writeMu.Unlock()
-			if _, err := respHeader.WriteTo(conn); err != nil {
-				return
+			keepOpen, err := writeResponse(conn, &sc.writeMu, respHeader, resp, matchedRoute)
+			if err != nil {
+				fmt.Printf("[SERVER] ❌ Error writing response for route '%s': %v\n", routeName, err)
 			}
-			if _, err := conn.Write(respPacked); err != nil {
-				return
+			if !keepOpen {
+				conn.Close()
 			}
-		}(req)
+		}(req, cc)
 	}
 }
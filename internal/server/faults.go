@@ -0,0 +1,120 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"jiso/internal/config"
+	"jiso/internal/utils"
+
+	"github.com/moov-io/iso8583"
+	"github.com/moov-io/iso8583/network"
+)
+
+// defaultStallMs is how long the "stall" fault pauses mid-write when stall_ms is not set
+const defaultStallMs = 5000
+
+// writeResponse packs resp and writes it to conn, applying the route's fault injection mode if any.
+// It returns false when the connection should be closed after the write.
+func writeResponse(conn net.Conn, writeMu *sync.Mutex, respHeader network.Header, resp *iso8583.Message, route *config.MockRouteConfig) (bool, error) {
+	fault := ""
+	stallMs := 0
+	if route != nil {
+		fault = route.Fault
+		stallMs = route.StallMs
+	}
+
+	if fault == config.FaultMismatchedStan {
+		mismatchStan(resp)
+	}
+
+	respPacked, err := resp.Pack()
+	if err != nil {
+		return true, fmt.Errorf("packing response: %w", err)
+	}
+
+	if fault == config.FaultCorruptBitmap {
+		corruptBitmap(resp, respPacked)
+	}
+
+	var trailer []byte
+	if th, ok := respHeader.(utils.TrailerHeader); ok {
+		trailer = th.Trailer(respPacked)
+	}
+	respHeader.SetLength(len(respPacked))
+	if fault == config.FaultWrongLength {
+		// Announce half the real payload so the client splits the frame at the wrong boundary
+		respHeader.SetLength(len(respPacked) / 2)
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	if _, err := respHeader.WriteTo(conn); err != nil {
+		return false, err
+	}
+
+	switch fault {
+	case config.FaultTruncated:
+		_, err := conn.Write(respPacked[:len(respPacked)/2])
+		return false, err
+
+	case config.FaultStall:
+		half := len(respPacked) / 2
+		if _, err := conn.Write(respPacked[:half]); err != nil {
+			return false, err
+		}
+		if stallMs <= 0 {
+			stallMs = defaultStallMs
+		}
+		time.Sleep(time.Duration(stallMs) * time.Millisecond)
+		if _, err := conn.Write(append(respPacked[half:], trailer...)); err != nil {
+			return false, err
+		}
+		return true, nil
+
+	case config.FaultDuplicate:
+		if _, err := conn.Write(append(respPacked, trailer...)); err != nil {
+			return false, err
+		}
+		if _, err := respHeader.WriteTo(conn); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := conn.Write(append(respPacked, trailer...)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// mismatchStan replaces DE 11 with the next STAN value so the response no longer correlates with its request
+func mismatchStan(resp *iso8583.Message) {
+	stan := "000000"
+	if f := resp.GetField(11); f != nil {
+		if v, err := f.String(); err == nil && v != "" {
+			stan = v
+		}
+	}
+	n, err := strconv.Atoi(stan)
+	if err != nil {
+		n = 0
+	}
+	_ = resp.Field(11, fmt.Sprintf("%06d", (n+1)%1000000))
+}
+
+// corruptBitmap flips every bit of the first primary bitmap byte, which directly follows the packed MTI
+func corruptBitmap(resp *iso8583.Message, packed []byte) {
+	offset := 4
+	if f := resp.GetField(0); f != nil {
+		if mti, err := f.Pack(); err == nil {
+			offset = len(mti)
+		}
+	}
+	if offset < len(packed) {
+		packed[offset] ^= 0xFF
+	}
+}
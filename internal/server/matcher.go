@@ -2,12 +2,15 @@ package server
 
 import (
 	"fmt"
+	"math/rand"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"jiso/internal/config"
+	"jiso/internal/expr"
 	"jiso/internal/utils"
 
 	"github.com/moov-io/iso8583"
@@ -16,15 +19,40 @@ import (
 
 // Matcher evaluates incoming ISO8583 messages against mock routes
 type Matcher struct {
-	routes []config.MockRouteConfig
+	routes    []config.MockRouteConfig
+	state     StateStore
+	randFloat func() float64 // Variant selection source, replaceable in tests
 }
 
+// NewMatcher creates a matcher evaluating routes by descending priority, then file order
 func NewMatcher(routes []config.MockRouteConfig) *Matcher {
-	return &Matcher{routes: routes}
+	return &Matcher{
+		routes:    sortRoutesByPriority(routes),
+		state:     NewMemoryStateStore(),
+		randFloat: rand.Float64,
+	}
+}
+
+// SetStateStore replaces the account ledger used by stateful routes
+func (m *Matcher) SetStateStore(store StateStore) {
+	if store != nil {
+		m.state = store
+	}
+}
+
+// GetStateStore returns the account ledger used by stateful routes
+func (m *Matcher) GetStateStore() StateStore {
+	return m.state
 }
 
 // MatchAndCompose matches request message against flexible mock route field criteria and composes response
 func (m *Matcher) MatchAndCompose(req *iso8583.Message, spec *iso8583.MessageSpec) (*config.MockRouteConfig, *iso8583.Message, error) {
+	return m.MatchAndComposeConn(req, spec, nil)
+}
+
+// MatchAndComposeConn is MatchAndCompose with the context of the connection the request arrived on.
+// Routes with match_conn conditions never match when cc is nil.
+func (m *Matcher) MatchAndComposeConn(req *iso8583.Message, spec *iso8583.MessageSpec, cc *ConnContext) (*config.MockRouteConfig, *iso8583.Message, error) {
 	if req == nil {
 		return nil, nil, fmt.Errorf("nil request message")
 	}
@@ -37,8 +65,8 @@ func (m *Matcher) MatchAndCompose(req *iso8583.Message, spec *iso8583.MessageSpe
 	var matchedRoute *config.MockRouteConfig
 	for i := range m.routes {
 		r := &m.routes[i]
-		if matchRoute(req, r) {
-			matchedRoute = r
+		if !r.Disabled && matchRoute(req, r, cc) {
+			matchedRoute = m.selectVariant(r)
 			break
 		}
 	}
@@ -78,16 +106,36 @@ func (m *Matcher) MatchAndCompose(req *iso8583.Message, spec *iso8583.MessageSpe
 			}
 		}
 
-		// Inject response fields (supporting auto/dynamic keywords like auth_code, stan, rrn, datetime, and composite fields)
+		// Inject response fields (supporting auto/dynamic keywords like auth_code, stan, rrn, datetime, and composite fields).
+		// Expression fields are applied last, in field order, so they can read literal response fields via resp.N.
+		var exprKeys []int
 		for fKey, fVal := range matchedRoute.ResponseFields {
-			if fNum, err := strconv.Atoi(fKey); err == nil {
-				_ = setResponseFieldValue(resp, spec, fNum, fVal)
+			fNum, err := strconv.Atoi(fKey)
+			if err != nil {
+				continue
 			}
+			if containsTemplate(fVal) {
+				exprKeys = append(exprKeys, fNum)
+				continue
+			}
+			_ = setResponseFieldValue(resp, spec, fNum, fVal)
+		}
+		sort.Ints(exprKeys)
+		env := messageEnv(req, resp, cc)
+		for _, fNum := range exprKeys {
+			resolved, err := resolveTemplates(matchedRoute.ResponseFields[strconv.Itoa(fNum)], env)
+			if err != nil {
+				fmt.Printf("\n[SERVER] ❌ Route '%s' field %d expression error: %v\n", matchedRoute.Name, fNum, err)
+				continue
+			}
+			_ = setResponseFieldValue(resp, spec, fNum, resolved)
 		}
 
 		if missingRequired {
 			// ISO Response Code "30" = Format Error / Missing Mandatory Field (Visa Standard)
 			resp.Field(39, "30")
+		} else if matchedRoute.State != nil {
+			m.applyState(req, resp, matchedRoute.State)
 		}
 
 		return matchedRoute, resp, nil
@@ -111,14 +159,32 @@ func (m *Matcher) MatchAndCompose(req *iso8583.Message, spec *iso8583.MessageSpe
 }
 
 // matchRoute checks if an incoming request satisfies all field match conditions in a mock route config
-func matchRoute(req *iso8583.Message, r *config.MockRouteConfig) bool {
-	if len(r.MatchFields) == 0 {
+func matchRoute(req *iso8583.Message, r *config.MockRouteConfig, cc *ConnContext) bool {
+	if len(r.MatchFields) == 0 && r.MatchExpr == "" && len(r.MatchConn) == 0 {
 		return false
 	}
 
 	for fieldKey, targetCondition := range r.MatchFields {
-		val, exists := extractFieldValue(req, fieldKey)
-		if !matchFieldValue(val, exists, targetCondition) {
+		val, exists := requestValue(req, cc, fieldKey)
+		if !matchCondition(req, cc, val, exists, targetCondition) {
+			return false
+		}
+	}
+
+	for connKey, targetCondition := range r.MatchConn {
+		val, exists := cc.Lookup(connKey)
+		if !matchCondition(req, cc, val, exists, targetCondition) {
+			return false
+		}
+	}
+
+	if r.MatchExpr != "" {
+		src := r.MatchExpr
+		if inner, ok := expr.Unwrap(src); ok {
+			src = inner
+		}
+		v, err := expr.Eval(src, messageEnv(req, nil, cc))
+		if err != nil || !expr.Truthy(v) {
 			return false
 		}
 	}
@@ -126,6 +192,135 @@ func matchRoute(req *iso8583.Message, r *config.MockRouteConfig) bool {
 	return true
 }
 
+// MatchMessage reports whether msg meets match_fields style conditions: exact values,
+// booleans for presence, rule objects and {{ }} expressions over req.*. header.<name>
+// keys are looked up in header.
+func MatchMessage(msg *iso8583.Message, header map[string]string, fields map[string]interface{}) bool {
+	cc := &ConnContext{Header: header}
+	for fieldKey, targetCondition := range fields {
+		val, exists := requestValue(msg, cc, fieldKey)
+		if !matchCondition(msg, cc, val, exists, targetCondition) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchCondition evaluates one match_fields / match_conn condition against a value
+func matchCondition(req *iso8583.Message, cc *ConnContext, val string, exists bool, condition interface{}) bool {
+	if cond, ok := condition.(string); ok && expr.IsTemplate(cond) {
+		return matchFieldExpr(req, cc, val, exists, cond)
+	}
+	return matchFieldValue(val, exists, condition)
+}
+
+// matchFieldExpr evaluates a {{ }} match condition; the field's own value is available as "value"
+func matchFieldExpr(req *iso8583.Message, cc *ConnContext, val string, exists bool, cond string) bool {
+	base := messageEnv(req, nil, cc)
+	env := expr.EnvFunc(func(name string) (interface{}, bool) {
+		if name == "value" {
+			if !exists {
+				return nil, false
+			}
+			return val, true
+		}
+		return base.Lookup(name)
+	})
+	v, err := expr.EvalTemplate(cond, env)
+	return err == nil && expr.Truthy(v)
+}
+
+// requestValue resolves a match_fields key: header.<name> for a request header field, else a request field
+func requestValue(req *iso8583.Message, cc *ConnContext, key string) (string, bool) {
+	if name, ok := strings.CutPrefix(key, "header."); ok {
+		return cc.HeaderField(name)
+	}
+	return extractFieldValue(req, key)
+}
+
+// messageEnv exposes request fields as req.N, response fields as resp.N (dot notation for subfields),
+// request header fields as header.<name> and connection attributes as conn.<name>
+func messageEnv(req, resp *iso8583.Message, cc *ConnContext) expr.Env {
+	return expr.EnvFunc(func(name string) (interface{}, bool) {
+		var msg *iso8583.Message
+		var key string
+		switch {
+		case strings.HasPrefix(name, "conn."):
+			if v, ok := cc.Lookup(strings.TrimPrefix(name, "conn.")); ok {
+				return v, true
+			}
+			return nil, false
+		case strings.HasPrefix(name, "header."):
+			if v, ok := cc.HeaderField(strings.TrimPrefix(name, "header.")); ok {
+				return v, true
+			}
+			return nil, false
+		case strings.HasPrefix(name, "req."):
+			msg, key = req, strings.TrimPrefix(name, "req.")
+		case strings.HasPrefix(name, "resp."):
+			msg, key = resp, strings.TrimPrefix(name, "resp.")
+		default:
+			return nil, false
+		}
+		if msg == nil {
+			return nil, false
+		}
+		val, ok := extractFieldValue(msg, key)
+		if !ok {
+			return nil, false
+		}
+		return val, true
+	})
+}
+
+// containsTemplate reports whether a response field value (or any composite subfield value) holds a {{ }} expression
+func containsTemplate(v interface{}) bool {
+	switch t := v.(type) {
+	case string:
+		return expr.IsTemplate(t)
+	case map[string]interface{}:
+		for _, sub := range t {
+			if containsTemplate(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveTemplates evaluates {{ }} expressions in a response field value, descending into composite maps
+func resolveTemplates(v interface{}, env expr.Env) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		if !expr.IsTemplate(t) {
+			return t, nil
+		}
+		res, err := expr.EvalTemplate(t, env)
+		if err != nil {
+			return nil, err
+		}
+		return expr.ToString(res), nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, sub := range t {
+			resolved, err := resolveTemplates(sub, env)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// FieldValue returns the value of a field or subfield of msg by the dot notation of
+// match_fields keys: "0" or "mti" for the MTI, "4", "55.9F26" or "34.01.C0"
+func FieldValue(msg *iso8583.Message, key string) (string, bool) {
+	return extractFieldValue(msg, key)
+}
+
 // extractFieldValue retrieves field/subfield values using dot notation (e.g., "0" for MTI, "3", "34.01.C0")
 func extractFieldValue(req *iso8583.Message, fieldKey string) (string, bool) {
 	if fieldKey == "0" || strings.EqualFold(fieldKey, "mti") {
@@ -244,4 +439,3 @@ func setResponseFieldValue(msg *iso8583.Message, spec *iso8583.MessageSpec, fiel
 		return msg.Field(fieldID, fmt.Sprintf("%v", v))
 	}
 }
-
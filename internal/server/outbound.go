@@ -0,0 +1,281 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"jiso/internal/config"
+	"jiso/internal/utils"
+
+	"github.com/moov-io/iso8583"
+)
+
+const (
+	// outboundLogSize bounds the number of server-initiated messages kept for inspection
+	outboundLogSize = 200
+	// outboundPendingTTL is how long an unanswered server-initiated message waits for its response
+	outboundPendingTTL = time.Minute
+	// adHocOutbound names messages pushed with Send rather than from a definition
+	adHocOutbound = "(ad hoc)"
+)
+
+// OutboundResult records a server-initiated message and the client's response to it
+type OutboundResult struct {
+	Name      string    `json:"name"`
+	ConnID    string    `json:"conn_id"`
+	MTI       string    `json:"mti"`
+	STAN      string    `json:"stan"`
+	SentAt    time.Time `json:"sent_at"`
+	Responded bool      `json:"responded"`
+	RespMTI   string    `json:"resp_mti,omitempty"`
+	RespCode  string    `json:"resp_code,omitempty"`
+	LatencyMs int64     `json:"latency_ms,omitempty"`
+}
+
+// SetOutbound replaces the server-initiated message definitions and restarts their schedules
+func (s *Server) SetOutbound(msgs []config.MockOutboundConfig) {
+	s.mu.Lock()
+	s.outbound = msgs
+	running := s.running
+	s.mu.Unlock()
+
+	if running {
+		s.stopSchedules()
+		s.startSchedules()
+	}
+}
+
+// Outbound returns the server-initiated message definitions
+func (s *Server) Outbound() []config.MockOutboundConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.outbound)
+}
+
+// OutboundLog returns the most recent server-initiated messages, oldest first
+func (s *Server) OutboundLog() []OutboundResult {
+	s.outboundMu.Lock()
+	defer s.outboundMu.Unlock()
+	out := make([]OutboundResult, len(s.outboundLog))
+	for i, r := range s.outboundLog {
+		out[i] = *r
+	}
+	return out
+}
+
+// SendOutbound sends the named server-initiated message to one connection, or to every
+// connected client when connID is empty. It returns the number of clients reached.
+func (s *Server) SendOutbound(name string, connID string) (int, error) {
+	s.mu.Lock()
+	idx := slices.IndexFunc(s.outbound, func(o config.MockOutboundConfig) bool { return o.Name == name })
+	var def config.MockOutboundConfig
+	if idx >= 0 {
+		def = s.outbound[idx]
+	}
+	s.mu.Unlock()
+	if idx < 0 {
+		return 0, fmt.Errorf("outbound message '%s' not found", name)
+	}
+
+	var targets []*serverConn
+	if connID != "" {
+		c := s.findConn(connID)
+		if c == nil {
+			return 0, fmt.Errorf("connection %s not found", connID)
+		}
+		targets = []*serverConn{c}
+	} else {
+		s.connsMu.Lock()
+		for _, c := range s.conns {
+			targets = append(targets, c)
+		}
+		s.connsMu.Unlock()
+	}
+
+	sent := 0
+	for _, c := range targets {
+		if err := s.sendOutbound(c, def); err != nil {
+			fmt.Printf("\n[SERVER] ❌ Outbound '%s' to %s failed: %v\n", def.Name, c.id, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// sendOutbound composes a fresh instance of def and pushes it to c
+func (s *Server) sendOutbound(c *serverConn, def config.MockOutboundConfig) error {
+	msg, err := s.composeOutbound(def)
+	if err != nil {
+		return err
+	}
+	if err := s.push(c, def.Name, msg); err != nil {
+		return err
+	}
+	mti, _ := msg.GetMTI()
+	stan, _ := extractFieldValue(msg, "11")
+	fmt.Printf("\n[SERVER] 📤 Sent outbound '%s' (MTI %s, STAN %s) to %s\n", def.Name, mti, stan, c.id)
+	return nil
+}
+
+// push writes a server-initiated message to c and logs it, remembering its STAN so
+// the client's response can be matched
+func (s *Server) push(c *serverConn, name string, msg *iso8583.Message) error {
+	mti, _ := msg.GetMTI()
+	stan, _ := extractFieldValue(msg, "11")
+
+	result := &OutboundResult{Name: name, ConnID: c.id, MTI: mti, STAN: stan, SentAt: time.Now()}
+	c.addPending(stan, result)
+
+	if err := s.write(c, msg); err != nil {
+		c.takePending(stan)
+		return err
+	}
+
+	s.outboundMu.Lock()
+	s.outboundLog = append(s.outboundLog, result)
+	if len(s.outboundLog) > outboundLogSize {
+		s.outboundLog = s.outboundLog[len(s.outboundLog)-outboundLogSize:]
+	}
+	s.outboundMu.Unlock()
+	s.stats.RecordOutbound(false)
+	return nil
+}
+
+// composeOutbound builds a message from an outbound definition. Field values accept response
+// keywords and {{ }} expressions; a STAN is generated when DE 11 is not set.
+func (s *Server) composeOutbound(def config.MockOutboundConfig) (*iso8583.Message, error) {
+	spec, _ := s.current()
+	msg := iso8583.NewMessage(spec)
+	env := messageEnv(nil, nil, nil)
+
+	for key, val := range def.Fields {
+		resolved, err := resolveTemplates(val, env)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", key, err)
+		}
+		if key == "0" {
+			msg.MTI(fmt.Sprintf("%v", resolved))
+			continue
+		}
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field ID %q", key)
+		}
+		if err := setResponseFieldValue(msg, spec, id, resolved); err != nil {
+			return nil, fmt.Errorf("field %d: %w", id, err)
+		}
+	}
+	if _, ok := def.Fields["11"]; !ok {
+		_ = msg.Field(11, utils.GetCounter().GetStan())
+	}
+	return msg, nil
+}
+
+// matchOutboundResponse records req as the client's answer to a server-initiated message.
+// It reports false when req is not such an answer and must be routed normally.
+func (s *Server) matchOutboundResponse(c *serverConn, req *iso8583.Message) bool {
+	mti, _ := req.GetMTI()
+	if !utils.IsResponseMTI(mti) {
+		return false
+	}
+	stan, _ := extractFieldValue(req, "11")
+	result := c.takePending(stan)
+	if result == nil {
+		return false
+	}
+
+	respCode, _ := extractFieldValue(req, "39")
+	s.outboundMu.Lock()
+	result.Responded = true
+	result.RespMTI = mti
+	result.RespCode = respCode
+	result.LatencyMs = time.Since(result.SentAt).Milliseconds()
+	latency := result.LatencyMs
+	s.outboundMu.Unlock()
+	s.stats.RecordOutbound(true)
+
+	fmt.Printf("\n[SERVER] 📥 %s answered outbound '%s' with %s (RC: %s) in %dms\n", c.id, result.Name, mti, respCode, latency)
+	return true
+}
+
+// startSchedules launches one goroutine per scheduled outbound message
+func (s *Server) startSchedules() {
+	s.mu.Lock()
+	stop := make(chan struct{})
+	s.outboundStop = stop
+	defs := slices.Clone(s.outbound)
+	s.mu.Unlock()
+
+	for _, def := range defs {
+		if def.IntervalMs <= 0 {
+			continue
+		}
+		go s.runSchedule(def, stop)
+	}
+}
+
+func (s *Server) stopSchedules() {
+	s.mu.Lock()
+	if s.outboundStop != nil {
+		close(s.outboundStop)
+		s.outboundStop = nil
+	}
+	s.mu.Unlock()
+}
+
+func (s *Server) runSchedule(def config.MockOutboundConfig, stop chan struct{}) {
+	if def.InitialDelayMs > 0 {
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Duration(def.InitialDelayMs) * time.Millisecond):
+		}
+	}
+
+	ticker := time.NewTicker(time.Duration(def.IntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+	// Only ticks that reached a client count, so a message is not used up before anyone connects
+	sent := 0
+	for {
+		n, err := s.SendOutbound(def.Name, "")
+		if err != nil {
+			return
+		}
+		if n > 0 {
+			sent++
+		}
+		if def.Count > 0 && sent >= def.Count {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *serverConn) addPending(stan string, r *OutboundResult) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if c.pending == nil {
+		c.pending = make(map[string]*OutboundResult)
+	}
+	for k, p := range c.pending {
+		if time.Since(p.SentAt) > outboundPendingTTL {
+			delete(c.pending, k)
+		}
+	}
+	c.pending[stan] = r
+}
+
+func (c *serverConn) takePending(stan string) *OutboundResult {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	r := c.pending[stan]
+	delete(c.pending, stan)
+	return r
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"jiso/internal/config"
+	"jiso/internal/expr"
+	"jiso/internal/utils"
+)
+
+// Routes returns a copy of the mock routes currently served, in configuration order
+func (s *Server) Routes() []config.MockRouteConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.routes)
+}
+
+// errRouteExists is returned by AddRoute for a route name already in use
+var errRouteExists = errors.New("already exists")
+
+// AddRoute appends a mock route. Route names must be unique, and the route is
+// validated like routes loaded from a transaction file.
+func (s *Server) AddRoute(route config.MockRouteConfig) error {
+	if route.Name == "" {
+		return fmt.Errorf("route name is required")
+	}
+	if err := ValidateRoute(route); err != nil {
+		return fmt.Errorf("route '%s': %w", route.Name, err)
+	}
+	return s.updateRoutes(func(routes []config.MockRouteConfig) ([]config.MockRouteConfig, error) {
+		if indexOfRoute(routes, route.Name) >= 0 {
+			return nil, fmt.Errorf("route '%s' %w", route.Name, errRouteExists)
+		}
+		return append(routes, route), nil
+	})
+}
+
+// RemoveRoute deletes the mock route with the given name
+func (s *Server) RemoveRoute(name string) error {
+	return s.updateRoutes(func(routes []config.MockRouteConfig) ([]config.MockRouteConfig, error) {
+		i := indexOfRoute(routes, name)
+		if i < 0 {
+			return nil, fmt.Errorf("route '%s' not found", name)
+		}
+		return slices.Delete(routes, i, i+1), nil
+	})
+}
+
+// SetRouteEnabled enables or disables the mock route with the given name
+func (s *Server) SetRouteEnabled(name string, enabled bool) error {
+	return s.updateRoute(name, func(r *config.MockRouteConfig) error {
+		r.Disabled = !enabled
+		return nil
+	})
+}
+
+// SetRouteFault switches the fault injection mode of the mock route with the given name.
+// An empty mode clears the fault.
+func (s *Server) SetRouteFault(name string, fault string, stallMs int) error {
+	if err := checkFaultMode(fault); err != nil {
+		return err
+	}
+	return s.updateRoute(name, func(r *config.MockRouteConfig) error {
+		r.Fault = fault
+		r.StallMs = stallMs
+		return nil
+	})
+}
+
+func (s *Server) updateRoute(name string, fn func(r *config.MockRouteConfig) error) error {
+	return s.updateRoutes(func(routes []config.MockRouteConfig) ([]config.MockRouteConfig, error) {
+		i := indexOfRoute(routes, name)
+		if i < 0 {
+			return nil, fmt.Errorf("route '%s' not found", name)
+		}
+		if err := fn(&routes[i]); err != nil {
+			return nil, err
+		}
+		return routes, nil
+	})
+}
+
+// updateRoutes applies fn to a copy of the route table and swaps the result in atomically
+func (s *Server) updateRoutes(fn func(routes []config.MockRouteConfig) ([]config.MockRouteConfig, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated, err := fn(slices.Clone(s.routes))
+	if err != nil {
+		return err
+	}
+	s.swapRoutesLocked(updated)
+	return nil
+}
+
+func indexOfRoute(routes []config.MockRouteConfig, name string) int {
+	return slices.IndexFunc(routes, func(r config.MockRouteConfig) bool { return r.Name == name })
+}
+
+func checkFaultMode(fault string) error {
+	if fault != "" && !slices.Contains(config.FaultModes, fault) {
+		return fmt.Errorf("unknown fault mode %q (valid: %s)", fault, strings.Join(config.FaultModes, ", "))
+	}
+	return nil
+}
+
+// ValidateRoute checks a mock route's expressions and response templates, fault modes
+// and header fields, so mistakes surface when the route is loaded or added rather than
+// as requests that quietly match nothing
+func ValidateRoute(route config.MockRouteConfig) error {
+	if err := validateRouteExpressions(route); err != nil {
+		return err
+	}
+	if err := validateRouteFaults(route); err != nil {
+		return err
+	}
+	return validateRouteHeader(route)
+}
+
+func validateRouteExpressions(route config.MockRouteConfig) error {
+	compile := func(v interface{}) error {
+		if s, ok := v.(string); ok {
+			return expr.CheckTemplate(s)
+		}
+		return nil
+	}
+
+	if route.MatchExpr != "" {
+		src := route.MatchExpr
+		if inner, ok := expr.Unwrap(src); ok {
+			src = inner
+		}
+		if _, err := expr.Compile(src); err != nil {
+			return fmt.Errorf("match_expr: %w", err)
+		}
+	}
+	for k, v := range route.MatchFields {
+		if err := compile(v); err != nil {
+			return fmt.Errorf("match field %s: %w", k, err)
+		}
+	}
+	for k, v := range route.MatchConn {
+		if !slices.Contains(config.MatchConnKeys, k) {
+			return fmt.Errorf("unknown match_conn key %q (valid: %s)", k, strings.Join(config.MatchConnKeys, ", "))
+		}
+		if err := compile(v); err != nil {
+			return fmt.Errorf("match_conn %s: %w", k, err)
+		}
+	}
+	if err := checkResponseFieldTemplates(route.ResponseFields, compile); err != nil {
+		return err
+	}
+	for i, v := range route.Responses {
+		if v.Weight < 0 {
+			return fmt.Errorf("response variant %d has negative weight", i)
+		}
+		if err := checkResponseFieldTemplates(v.ResponseFields, compile); err != nil {
+			return fmt.Errorf("response variant %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateRouteFaults(route config.MockRouteConfig) error {
+	if err := checkFaultMode(route.Fault); err != nil {
+		return err
+	}
+	for i, v := range route.Responses {
+		if err := checkFaultMode(v.Fault); err != nil {
+			return fmt.Errorf("response variant %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validateRouteHeader checks header.<name> match keys and response_header fields against the VISA header
+func validateRouteHeader(route config.MockRouteConfig) error {
+	for k := range route.MatchFields {
+		if name, ok := strings.CutPrefix(k, "header."); ok && !slices.Contains(utils.VisaHeaderFieldNames, name) {
+			return fmt.Errorf("unknown header field %q in match_fields (valid: %s)", name, strings.Join(utils.VisaHeaderFieldNames, ", "))
+		}
+	}
+	scratch, _ := utils.NewVisaHeader("000000")
+	for name, value := range route.ResponseHeader {
+		if err := scratch.SetHeaderField(name, value); err != nil {
+			return fmt.Errorf("response_header: %w", err)
+		}
+	}
+	return nil
+}
+
+func checkResponseFieldTemplates(fields map[string]interface{}, compile func(interface{}) error) error {
+	for k, v := range fields {
+		if sub, ok := v.(map[string]interface{}); ok {
+			for subKey, subVal := range sub {
+				if err := compile(subVal); err != nil {
+					return fmt.Errorf("response field %s.%s: %w", k, subKey, err)
+				}
+			}
+			continue
+		}
+		if err := compile(v); err != nil {
+			return fmt.Errorf("response field %s: %w", k, err)
+		}
+	}
+	return nil
+}
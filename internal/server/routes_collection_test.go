@@ -0,0 +1,70 @@
+package server_test
+
+import (
+	"os"
+	"testing"
+
+	"jiso/internal/server"
+	"jiso/internal/transactions"
+	"jiso/internal/utils"
+
+	"github.com/moov-io/iso8583"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockRoutesCollectionLoadingAndMatching(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec.json")
+	require.NoError(t, err)
+
+	dataBytes, err := os.ReadFile("../../transactions/transaction.json")
+	require.NoError(t, err)
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "mock_routes_*.json")
+	require.NoError(t, err)
+	_, err = tmpFile.Write(dataBytes)
+	require.NoError(t, err)
+	tmpFile.Close()
+
+	tcLoaded, err := transactions.NewTransactionCollection(tmpFile.Name(), spec)
+	require.NoError(t, err)
+	require.NotNil(t, tcLoaded)
+
+	routes := tcLoaded.GetMockRoutes()
+	require.NotEmpty(t, routes)
+
+	matcher := server.NewMatcher(routes)
+
+	// Test Network 0800 F70=1
+	msg0800_1 := iso8583.NewMessage(spec)
+	msg0800_1.MTI("0800")
+	msg0800_1.Field(7, "0725213831")
+	msg0800_1.Field(11, "008008")
+	msg0800_1.Field(70, "1")
+
+	matched1, resp1, err := matcher.MatchAndCompose(msg0800_1, spec)
+	require.NoError(t, err)
+	require.NotNil(t, matched1)
+	val39_1, _ := resp1.GetField(39).String()
+	assert.Equal(t, "00", val39_1)
+
+	// Test Financial 0200 matching & echoing card/track/fields
+	msg0200 := iso8583.NewMessage(spec)
+	msg0200.MTI("0200")
+	msg0200.Field(2, "9876543210987654")
+	msg0200.Field(3, "000000")
+	msg0200.Field(4, "2500")
+	msg0200.Field(7, "0725213835")
+	msg0200.Field(11, "008009")
+	msg0200.Field(14, "2601")
+	msg0200.Field(41, "77973588")
+	msg0200.Field(49, "634")
+
+	matched2, resp2, err := matcher.MatchAndCompose(msg0200, spec)
+	require.NoError(t, err)
+	require.NotNil(t, matched2)
+	val39_2, _ := resp2.GetField(39).String()
+	assert.Equal(t, "00", val39_2)
+	val2_2, _ := resp2.GetField(2).String()
+	assert.Equal(t, "9876543210987654", val2_2, "Card PAN DE 2 should be echoed from request")
+}
@@ -1,15 +1,29 @@
 package server
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/binary"
+	"encoding/pem"
+	"fmt"
 	"io"
+	"math/big"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"jiso/internal/config"
-	"jiso/internal/transactions"
+	"jiso/internal/connection"
 	"jiso/internal/utils"
 
 	"github.com/moov-io/iso8583"
@@ -268,60 +282,16 @@ func TestNilSpecServerFallback(t *testing.T) {
 	assert.Greater(t, respLen, uint16(0))
 }
 
-func TestMockRoutesCollectionLoadingAndMatching(t *testing.T) {
-	spec, err := utils.CreateSpecFromFile("../../specs/spec.json")
-	require.NoError(t, err)
-
-	dataBytes, err := os.ReadFile("../../transactions/transaction.json")
-	require.NoError(t, err)
-
-	tmpFile, err := os.CreateTemp(t.TempDir(), "mock_routes_*.json")
-	require.NoError(t, err)
-	_, err = tmpFile.Write(dataBytes)
-	require.NoError(t, err)
-	tmpFile.Close()
-
-	tcLoaded, err := transactions.NewTransactionCollection(tmpFile.Name(), spec)
-	require.NoError(t, err)
-	require.NotNil(t, tcLoaded)
-
-	routes := tcLoaded.GetMockRoutes()
-	require.NotEmpty(t, routes)
+func TestMatchMessage(t *testing.T) {
+	msg := iso8583.NewMessage(iso8583.Spec87)
+	msg.MTI("0620")
+	require.NoError(t, msg.Field(11, "000042"))
+	header := map[string]string{"source_station": "000001"}
 
-	matcher := NewMatcher(routes)
-
-	// Test Network 0800 F70=1
-	msg0800_1 := iso8583.NewMessage(spec)
-	msg0800_1.MTI("0800")
-	msg0800_1.Field(7, "0725213831")
-	msg0800_1.Field(11, "008008")
-	msg0800_1.Field(70, "1")
-
-	matched1, resp1, err := matcher.MatchAndCompose(msg0800_1, spec)
-	require.NoError(t, err)
-	require.NotNil(t, matched1)
-	val39_1, _ := resp1.GetField(39).String()
-	assert.Equal(t, "00", val39_1)
-
-	// Test Financial 0200 matching & echoing card/track/fields
-	msg0200 := iso8583.NewMessage(spec)
-	msg0200.MTI("0200")
-	msg0200.Field(2, "9876543210987654")
-	msg0200.Field(3, "000000")
-	msg0200.Field(4, "2500")
-	msg0200.Field(7, "0725213835")
-	msg0200.Field(11, "008009")
-	msg0200.Field(14, "2601")
-	msg0200.Field(41, "77973588")
-	msg0200.Field(49, "634")
-
-	matched2, resp2, err := matcher.MatchAndCompose(msg0200, spec)
-	require.NoError(t, err)
-	require.NotNil(t, matched2)
-	val39_2, _ := resp2.GetField(39).String()
-	assert.Equal(t, "00", val39_2)
-	val2_2, _ := resp2.GetField(2).String()
-	assert.Equal(t, "9876543210987654", val2_2, "Card PAN DE 2 should be echoed from request")
+	assert.True(t, MatchMessage(msg, header, map[string]interface{}{"0": "0620", "11": true, "header.source_station": "000001"}))
+	assert.True(t, MatchMessage(msg, nil, nil))
+	assert.False(t, MatchMessage(msg, header, map[string]interface{}{"0": "0800"}))
+	assert.False(t, MatchMessage(msg, nil, map[string]interface{}{"header.source_station": "000001"}))
 }
 
 func TestMatchAndComposeWithCompositeFields(t *testing.T) {
@@ -374,3 +344,857 @@ func TestMatchAndComposeWithCompositeFields(t *testing.T) {
 	assert.Equal(t, "8", str9f27)
 }
 
+func TestExpressionRoutes(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	routes := []config.MockRouteConfig{
+		{
+			Name:      "Tiered Purchase",
+			MatchExpr: `{{req.0 == "0200" && req.3 == "000000"}}`,
+			MatchFields: map[string]interface{}{
+				"4": `{{value > 0}}`,
+			},
+			EchoFields:  []int{4, 11},
+			ResponseMTI: "0210",
+			ResponseFields: map[string]interface{}{
+				"38": "A{{substr(req.11, 1)}}",
+				"39": `{{req.4 > 100000 ? "05" : "00"}}`,
+				"54": `{{"0002840C" + pad(req.4 * 2, 12)}}`,
+			},
+		},
+	}
+
+	matcher := NewMatcher(routes)
+
+	compose := func(amount string) *iso8583.Message {
+		msg := iso8583.NewMessage(spec)
+		msg.MTI("0200")
+		msg.Field(3, "000000")
+		msg.Field(4, amount)
+		msg.Field(11, "000777")
+		matched, resp, err := matcher.MatchAndCompose(msg, spec)
+		require.NoError(t, err)
+		require.NotNil(t, matched)
+		return resp
+	}
+
+	resp := compose("2500")
+	val39, _ := resp.GetField(39).String()
+	assert.Equal(t, "00", val39)
+	val38, _ := resp.GetField(38).String()
+	assert.Equal(t, "A00777", val38)
+	val54, _ := resp.GetField(54).String()
+	assert.Equal(t, "0002840C000000005000", val54)
+
+	resp = compose("250000")
+	val39, _ = resp.GetField(39).String()
+	assert.Equal(t, "05", val39)
+
+	// Field expression rejects a zero amount, so the request falls back to RC 12
+	msg := iso8583.NewMessage(spec)
+	msg.MTI("0200")
+	msg.Field(3, "000000")
+	msg.Field(4, "0")
+	matched, resp, err := matcher.MatchAndCompose(msg, spec)
+	require.NoError(t, err)
+	assert.Nil(t, matched)
+	val39, _ = resp.GetField(39).String()
+	assert.Equal(t, "12", val39)
+}
+
+func TestRoutePriorityAndWeightedVariants(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	routes := []config.MockRouteConfig{
+		{
+			Name:           "Generic Purchase",
+			MatchFields:    map[string]interface{}{"0": "0200"},
+			ResponseFields: map[string]interface{}{"39": "00"},
+			Responses: []config.MockResponseVariant{
+				{Name: "approve", Weight: 90},
+				{Name: "decline", Weight: 7, ResponseFields: map[string]interface{}{"39": "05"}},
+				{Name: "timeout", Weight: 3, NoResponse: true},
+			},
+		},
+		{
+			Name:           "Blocked Card",
+			Priority:       10,
+			MatchFields:    map[string]interface{}{"0": "0200", "2": "4000000000000002"},
+			ResponseFields: map[string]interface{}{"39": "62"},
+		},
+	}
+
+	matcher := NewMatcher(routes)
+
+	compose := func(pan string, roll float64) (*config.MockRouteConfig, string) {
+		matcher.randFloat = func() float64 { return roll }
+		msg := iso8583.NewMessage(spec)
+		msg.MTI("0200")
+		msg.Field(2, pan)
+		matched, resp, err := matcher.MatchAndCompose(msg, spec)
+		require.NoError(t, err)
+		require.NotNil(t, matched)
+		rc, _ := resp.GetField(39).String()
+		return matched, rc
+	}
+
+	// Higher priority route wins even though it is declared later
+	matched, rc := compose("4000000000000002", 0)
+	assert.Equal(t, "Blocked Card", matched.Name)
+	assert.Equal(t, "62", rc)
+
+	matched, rc = compose("4111111111111111", 0.5)
+	assert.Equal(t, "Generic Purchase [approve]", matched.Name)
+	assert.Equal(t, "00", rc)
+	assert.False(t, matched.NoResponse)
+
+	matched, rc = compose("4111111111111111", 0.92)
+	assert.Equal(t, "Generic Purchase [decline]", matched.Name)
+	assert.Equal(t, "05", rc)
+
+	matched, _ = compose("4111111111111111", 0.99)
+	assert.Equal(t, "Generic Purchase [timeout]", matched.Name)
+	assert.True(t, matched.NoResponse)
+
+	// Selecting a variant must not mutate the configured route
+	assert.Equal(t, "00", routes[0].ResponseFields["39"])
+}
+
+func TestFaultInjectionModes(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	newResp := func() *iso8583.Message {
+		msg := iso8583.NewMessage(spec)
+		msg.MTI("0210")
+		msg.Field(11, "000123")
+		msg.Field(39, "00")
+		return msg
+	}
+	cleanPacked, err := newResp().Pack()
+	require.NoError(t, err)
+
+	// write runs writeResponse against an in-memory pipe and returns every byte sent before the writer finished
+	write := func(route *config.MockRouteConfig) ([]byte, bool) {
+		client, srv := net.Pipe()
+		defer client.Close()
+
+		var keepOpen bool
+		done := make(chan struct{})
+		go func() {
+			var mu sync.Mutex
+			keepOpen, _ = writeResponse(srv, &mu, utils.NewBinary2BytesAdapter(), newResp(), route)
+			srv.Close()
+			close(done)
+		}()
+		data, _ := io.ReadAll(client)
+		<-done
+		return data, keepOpen
+	}
+
+	frameLen := func(b []byte) int { return int(binary.BigEndian.Uint16(b[0:2])) }
+
+	data, keepOpen := write(&config.MockRouteConfig{Fault: config.FaultDuplicate})
+	assert.True(t, keepOpen)
+	require.Len(t, data, 2*(2+len(cleanPacked)))
+	assert.Equal(t, data[:2+len(cleanPacked)], data[2+len(cleanPacked):])
+
+	data, _ = write(&config.MockRouteConfig{Fault: config.FaultMismatchedStan})
+	msg := iso8583.NewMessage(spec)
+	require.NoError(t, msg.Unpack(data[2:]))
+	stan, _ := msg.GetField(11).String()
+	assert.Equal(t, "000124", stan)
+
+	data, keepOpen = write(&config.MockRouteConfig{Fault: config.FaultTruncated})
+	assert.False(t, keepOpen)
+	assert.Equal(t, len(cleanPacked), frameLen(data))
+	assert.Len(t, data, 2+len(cleanPacked)/2)
+
+	data, _ = write(&config.MockRouteConfig{Fault: config.FaultWrongLength})
+	assert.Equal(t, len(cleanPacked)/2, frameLen(data))
+	assert.Len(t, data, 2+len(cleanPacked))
+
+	data, _ = write(&config.MockRouteConfig{Fault: config.FaultCorruptBitmap})
+	assert.NotEqual(t, cleanPacked, data[2:])
+	assert.Error(t, iso8583.NewMessage(spec).Unpack(data[2:]))
+
+	start := time.Now()
+	data, _ = write(&config.MockRouteConfig{Fault: config.FaultStall, StallMs: 50})
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, cleanPacked, data[2:])
+
+	assert.True(t, (&config.MockRouteConfig{Fault: config.FaultNoResponse}).SwallowsRequest())
+}
+
+func TestServerReloadKeepsConnections(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	signOn := func(rc string) []config.MockRouteConfig {
+		return []config.MockRouteConfig{{
+			Name:           "SignOn",
+			MatchFields:    map[string]interface{}{"0": "0800"},
+			ResponseMTI:    "0810",
+			EchoFields:     []int{11},
+			ResponseFields: map[string]interface{}{"39": rc},
+		}}
+	}
+
+	server := NewServer(spec, signOn("00"), "binary2")
+	require.NoError(t, server.Start("19893"))
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp", "localhost:19893")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	exchange := func() string {
+		req := iso8583.NewMessage(spec)
+		req.MTI("0800")
+		req.Field(11, "000001")
+		packed, err := req.Pack()
+		require.NoError(t, err)
+		frame := make([]byte, 2+len(packed))
+		binary.BigEndian.PutUint16(frame, uint16(len(packed)))
+		copy(frame[2:], packed)
+		_, err = conn.Write(frame)
+		require.NoError(t, err)
+
+		var respLen uint16
+		require.NoError(t, binary.Read(conn, binary.BigEndian, &respLen))
+		respBuf := make([]byte, respLen)
+		_, err = io.ReadFull(conn, respBuf)
+		require.NoError(t, err)
+		resp := iso8583.NewMessage(spec)
+		require.NoError(t, resp.Unpack(respBuf))
+		rc, _ := resp.GetField(39).String()
+		return rc
+	}
+
+	assert.Equal(t, "00", exchange())
+
+	ledger := server.GetStateStore()
+	server.Reload(nil, signOn("91"))
+
+	// Same TCP session, new routes, same ledger
+	assert.Equal(t, "91", exchange())
+	assert.Equal(t, 1, server.ActiveConnections())
+	assert.Same(t, ledger, server.GetStateStore())
+}
+
+func TestFileWatcherDetectsChanges(t *testing.T) {
+	file, err := os.CreateTemp("", "watched_routes.json")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.Close()
+
+	changed := make(chan string, 1)
+	w := NewFileWatcher([]string{file.Name(), ""}, 10*time.Millisecond, func(path string) {
+		changed <- path
+	})
+	w.Start()
+	defer w.Stop()
+
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(file.Name(), future, future))
+
+	select {
+	case path := <-changed:
+		assert.Equal(t, file.Name(), path)
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not report the modified file")
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	server := NewServer(spec, []config.MockRouteConfig{{
+		Name:           "SignOn",
+		MatchFields:    map[string]interface{}{"0": "0800"},
+		ResponseFields: map[string]interface{}{"39": "00"},
+	}}, "binary2")
+	require.NoError(t, server.Start("19894"))
+	defer server.Stop()
+
+	api := httptest.NewServer(server.AdminHandler())
+	defer api.Close()
+
+	do := func(method, path, body string) (int, string) {
+		req, err := http.NewRequest(method, api.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := do("POST", "/routes", `{"name": "Purchase", "match_fields": {"0": "0200"}, "response_fields": {"39": "00"}}`)
+	assert.Equal(t, http.StatusCreated, code, body)
+	code, _ = do("POST", "/routes", `{"name": "Purchase"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	// Routes are validated like those of a transaction file
+	code, body = do("POST", "/routes", `{"name": "Bad", "match_expr": "req.4 >"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "match_expr")
+	code, _ = do("POST", "/routes", `{"name": "Bad", "match_fields": {"header.nope": "1"}}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Error(t, server.AddRoute(config.MockRouteConfig{Name: "Bad", Fault: "explode"}))
+
+	code, body = do("GET", "/routes", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"Purchase"`)
+
+	code, _ = do("POST", "/routes/SignOn/disable", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, server.Routes()[0].Disabled)
+
+	code, _ = do("PUT", "/routes/Purchase/fault", `{"fault": "duplicate"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, config.FaultDuplicate, server.Routes()[1].Fault)
+	code, _ = do("PUT", "/routes/Purchase/fault", `{"fault": "explode"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = do("DELETE", "/routes/Purchase", "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = do("DELETE", "/routes/Purchase", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = do("GET", "/stats", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"total_served"`)
+
+	conn, err := net.Dial("tcp", "localhost:19894")
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return server.ActiveConnections() == 1 }, time.Second, 10*time.Millisecond)
+
+	connID := server.Connections()[0].ID
+	code, body = do("POST", "/connections/"+connID+"/send", `{"mti": "0800", "fields": {"11": "000042", "70": "301"}}`)
+	assert.Equal(t, http.StatusAccepted, code, body)
+
+	var msgLen uint16
+	require.NoError(t, binary.Read(conn, binary.BigEndian, &msgLen))
+	buf := make([]byte, msgLen)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	pushed := iso8583.NewMessage(spec)
+	require.NoError(t, pushed.Unpack(buf))
+	f70, _ := pushed.GetField(70).String()
+	assert.Equal(t, "301", f70)
+
+	// The client's answer to the push is matched, not routed as a request
+	resp := iso8583.NewMessage(spec)
+	resp.MTI("0810")
+	resp.Field(11, "000042")
+	resp.Field(39, "00")
+	packed, err := resp.Pack()
+	require.NoError(t, err)
+	frame := make([]byte, 2+len(packed))
+	binary.BigEndian.PutUint16(frame, uint16(len(packed)))
+	copy(frame[2:], packed)
+	_, err = conn.Write(frame)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		log := server.OutboundLog()
+		return len(log) == 1 && log[0].Responded
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "0810", server.OutboundLog()[0].RespMTI)
+	assert.Equal(t, int64(0), server.GetStats().Snapshot().TotalServed)
+
+	code, _ = do("POST", "/connections/c999/send", `{"mti": "0800"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// Restarting the admin API on another address stops the previous one
+	require.NoError(t, server.StartAdmin("127.0.0.1:19884"))
+	require.NoError(t, server.StartAdmin("127.0.0.1:19885"))
+	_, err = net.Dial("tcp", "127.0.0.1:19884")
+	assert.Error(t, err)
+	admin, err := net.Dial("tcp", "127.0.0.1:19885")
+	require.NoError(t, err)
+	admin.Close()
+}
+
+func TestServerInitiatedMessages(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	server := NewServer(spec, nil, "binary2")
+	server.SetOutbound([]config.MockOutboundConfig{
+		{Name: "Key Change", Fields: map[string]interface{}{"0": "0800", "70": "161"}},
+		{Name: "Echo Test", Fields: map[string]interface{}{"0": "0800", "70": "301"}, IntervalMs: 20, InitialDelayMs: 50, Count: 2},
+	})
+	require.NoError(t, server.Start("19895"))
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp", "localhost:19895")
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return server.ActiveConnections() == 1 }, time.Second, 10*time.Millisecond)
+
+	readMsg := func() *iso8583.Message {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		var msgLen uint16
+		require.NoError(t, binary.Read(conn, binary.BigEndian, &msgLen))
+		buf := make([]byte, msgLen)
+		_, err := io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		msg := iso8583.NewMessage(spec)
+		require.NoError(t, msg.Unpack(buf))
+		return msg
+	}
+	answer := func(req *iso8583.Message) {
+		stan, _ := req.GetField(11).String()
+		resp := iso8583.NewMessage(spec)
+		resp.MTI("0810")
+		resp.Field(11, stan)
+		resp.Field(39, "00")
+		packed, err := resp.Pack()
+		require.NoError(t, err)
+		frame := make([]byte, 2+len(packed))
+		binary.BigEndian.PutUint16(frame, uint16(len(packed)))
+		copy(frame[2:], packed)
+		_, err = conn.Write(frame)
+		require.NoError(t, err)
+	}
+
+	sent, err := server.SendOutbound("Key Change", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	_, err = server.SendOutbound("Unknown", "")
+	assert.Error(t, err)
+
+	keyChange := readMsg()
+	f70, _ := keyChange.GetField(70).String()
+	assert.Equal(t, "161", f70)
+	stan, _ := keyChange.GetField(11).String()
+	assert.NotEmpty(t, stan)
+	answer(keyChange)
+
+	// Two scheduled echo tests follow; only the first one is answered
+	echo := readMsg()
+	f70, _ = echo.GetField(70).String()
+	assert.Equal(t, "301", f70)
+	answer(echo)
+	readMsg()
+
+	require.Eventually(t, func() bool {
+		log := server.OutboundLog()
+		return len(log) == 3 && log[0].Responded && log[1].Responded
+	}, time.Second, 10*time.Millisecond)
+
+	log := server.OutboundLog()
+	assert.Equal(t, "Key Change", log[0].Name)
+	assert.Equal(t, "0810", log[0].RespMTI)
+	assert.Equal(t, "00", log[0].RespCode)
+	assert.False(t, log[2].Responded)
+
+	snap := server.GetStats().Snapshot()
+	assert.Equal(t, int64(3), snap.OutboundSent)
+	assert.Equal(t, int64(2), snap.OutboundAnswered)
+	// Answers to server-initiated messages are not routed as requests
+	assert.Equal(t, int64(0), snap.TotalServed)
+}
+
+func TestScheduledOutboundWaitsForClients(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	server := NewServer(spec, nil, "binary2")
+	server.SetOutbound([]config.MockOutboundConfig{
+		{Name: "Key Change", Fields: map[string]interface{}{"0": "0800", "70": "161"}, IntervalMs: 20, Count: 1},
+	})
+	require.NoError(t, server.Start("0"))
+	defer server.Stop()
+
+	// Ticks with nobody connected do not use up the single send
+	time.Sleep(100 * time.Millisecond)
+	conn, err := net.Dial("tcp", "localhost:"+server.GetPort())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var msgLen uint16
+	require.NoError(t, binary.Read(conn, binary.BigEndian, &msgLen))
+	buf := make([]byte, msgLen)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	msg := iso8583.NewMessage(spec)
+	require.NoError(t, msg.Unpack(buf))
+	f70, _ := msg.GetField(70).String()
+	assert.Equal(t, "161", f70)
+
+	require.Eventually(t, func() bool { return len(server.OutboundLog()) == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, server.OutboundLog(), 1)
+}
+
+func TestConnectionContextRouting(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	routes := []config.MockRouteConfig{
+		{
+			Name:           "Third Request Times Out",
+			Priority:       10,
+			MatchFields:    map[string]interface{}{"0": "0200"},
+			MatchConn:      map[string]interface{}{"request_num": "3"},
+			ResponseFields: map[string]interface{}{"39": "91"},
+		},
+		{
+			Name:           "Acquirer B",
+			MatchConn:      map[string]interface{}{"station_id": "654321", "remote_ip": map[string]interface{}{"prefix": "10."}},
+			ResponseFields: map[string]interface{}{"39": "05", "44": "{{conn.id}}"},
+		},
+		{
+			Name:           "Second Connection",
+			MatchExpr:      `{{conn.ordinal == 2}}`,
+			ResponseFields: map[string]interface{}{"39": "01"},
+		},
+		{
+			Name:           "Default",
+			MatchFields:    map[string]interface{}{"0": "0200"},
+			ResponseFields: map[string]interface{}{"39": "00"},
+		},
+	}
+	matcher := NewMatcher(routes)
+
+	compose := func(cc *ConnContext) (string, string) {
+		msg := iso8583.NewMessage(spec)
+		msg.MTI("0200")
+		matched, resp, err := matcher.MatchAndComposeConn(msg, spec, cc)
+		require.NoError(t, err)
+		require.NotNil(t, matched)
+		rc, _ := resp.GetField(39).String()
+		return matched.Name, rc
+	}
+
+	base := ConnContext{ID: "c1", Ordinal: 1, RemoteAddr: "192.168.1.5:40000", RemoteIP: "192.168.1.5", LocalPort: "9999", RequestNum: 1}
+
+	name, rc := compose(&base)
+	assert.Equal(t, "Default", name)
+	assert.Equal(t, "00", rc)
+
+	third := base
+	third.RequestNum = 3
+	name, rc = compose(&third)
+	assert.Equal(t, "Third Request Times Out", name)
+	assert.Equal(t, "91", rc)
+
+	acqB := base
+	acqB.RemoteIP, acqB.StationID = "10.0.0.7", "654321"
+	name, rc = compose(&acqB)
+	assert.Equal(t, "Acquirer B", name)
+	assert.Equal(t, "05", rc)
+
+	second := base
+	second.Ordinal = 2
+	name, _ = compose(&second)
+	assert.Equal(t, "Second Connection", name)
+
+	// Without a connection context only field-based routes can match
+	name, _ = compose(nil)
+	assert.Equal(t, "Default", name)
+
+	// The engine counts requests per socket
+	server := NewServer(spec, routes[:1], "binary2")
+	require.NoError(t, server.Start("19896"))
+	defer server.Stop()
+	conn, err := net.Dial("tcp", "localhost:19896")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var codes []string
+	for i := 0; i < 3; i++ {
+		req := iso8583.NewMessage(spec)
+		req.MTI("0200")
+		req.Field(11, fmt.Sprintf("%06d", i+1))
+		packed, err := req.Pack()
+		require.NoError(t, err)
+		frame := make([]byte, 2+len(packed))
+		binary.BigEndian.PutUint16(frame, uint16(len(packed)))
+		copy(frame[2:], packed)
+		_, err = conn.Write(frame)
+		require.NoError(t, err)
+
+		var respLen uint16
+		require.NoError(t, binary.Read(conn, binary.BigEndian, &respLen))
+		buf := make([]byte, respLen)
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		resp := iso8583.NewMessage(spec)
+		require.NoError(t, resp.Unpack(buf))
+		rc, _ := resp.GetField(39).String()
+		codes = append(codes, rc)
+	}
+	assert.Equal(t, []string{"12", "12", "91"}, codes)
+}
+
+func TestVisaHeaderRouting(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	routes := []config.MockRouteConfig{
+		{
+			Name:           "Reject Station",
+			MatchFields:    map[string]interface{}{"0": "0100", "header.source_station": "123456"},
+			ResponseFields: map[string]interface{}{"39": "00", "44": "{{header.round_trip}}"},
+			ResponseHeader: map[string]string{"reject_code": "0511"},
+		},
+	}
+	server := NewServer(spec, routes, "visa")
+	require.NoError(t, server.Start("19900"))
+	defer server.Stop()
+	conn, err := net.Dial("tcp", "localhost:19900")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	send := func(station string) (*iso8583.Message, utils.VisaHeaderFields) {
+		header, err := utils.NewVisaHeader(station)
+		require.NoError(t, err)
+		require.NoError(t, header.SetHeaderField("destination_station", "000100"))
+		require.NoError(t, header.SetHeaderField("round_trip", "02"))
+
+		req := iso8583.NewMessage(spec)
+		req.MTI("0100")
+		req.Field(11, "000001")
+		packed, err := req.Pack()
+		require.NoError(t, err)
+		header.SetLength(len(packed))
+		_, err = header.WriteTo(conn)
+		require.NoError(t, err)
+		_, err = conn.Write(packed)
+		require.NoError(t, err)
+
+		respHeader, _ := utils.NewVisaHeader("000000")
+		_, err = respHeader.ReadFrom(conn)
+		require.NoError(t, err)
+		buf := make([]byte, respHeader.Length())
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		resp := iso8583.NewMessage(spec)
+		require.NoError(t, resp.Unpack(buf))
+		return resp, respHeader.PeerFields()
+	}
+
+	resp, header := send("123456")
+	rc, _ := resp.GetField(39).String()
+	assert.Equal(t, "00", rc)
+	roundTrip, _ := resp.GetField(44).String()
+	assert.Equal(t, "02", roundTrip)
+	assert.Equal(t, utils.VisaRejectHeaderLength, header.HeaderLength)
+	assert.Equal(t, "0511", header.RejectCode)
+	assert.Equal(t, "123456", header.DestinationStation)
+	assert.Equal(t, "000100", header.SourceStation)
+	assert.Equal(t, byte(0x02), header.RoundTrip)
+
+	resp, header = send("654321")
+	rc, _ = resp.GetField(39).String()
+	assert.Equal(t, "12", rc)
+	assert.Equal(t, utils.VisaHeaderLength, header.HeaderLength)
+	assert.Equal(t, "654321", header.DestinationStation)
+}
+
+func TestMultipleListeners(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	visa := NewServer(spec, []config.MockRouteConfig{
+		{Name: "VISA Approve", MatchFields: map[string]interface{}{"0": "0200"}, ResponseFields: map[string]interface{}{"39": "00"}},
+	}, "binary2")
+	naps := NewServer(spec, []config.MockRouteConfig{
+		{Name: "NAPS Decline", MatchFields: map[string]interface{}{"0": "0200"}, ResponseFields: map[string]interface{}{"39": "05"}},
+	}, "ascii4")
+	require.NoError(t, visa.Start("19897"))
+	defer visa.Stop()
+	require.NoError(t, naps.Start("19898"))
+	defer naps.Stop()
+
+	exchange := func(port string, headerType string) string {
+		conn, err := net.Dial("tcp", "localhost:"+port)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		req := iso8583.NewMessage(spec)
+		req.MTI("0200")
+		req.Field(11, "000001")
+		packed, err := req.Pack()
+		require.NoError(t, err)
+		header, err := utils.SelectServerHeader(headerType)
+		require.NoError(t, err)
+		header.SetLength(len(packed))
+		_, err = header.WriteTo(conn)
+		require.NoError(t, err)
+		_, err = conn.Write(packed)
+		require.NoError(t, err)
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, err = header.ReadFrom(conn)
+		require.NoError(t, err)
+		buf := make([]byte, header.Length())
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		resp := iso8583.NewMessage(spec)
+		require.NoError(t, resp.Unpack(buf))
+		rc, _ := resp.GetField(39).String()
+		return rc
+	}
+
+	assert.Equal(t, "00", exchange("19897", "binary2"))
+	assert.Equal(t, "05", exchange("19898", "ascii4"))
+	assert.Equal(t, "05", exchange("19898", "ascii4"))
+
+	// Each listener keeps its own statistics
+	assert.Equal(t, int64(1), visa.GetStats().Snapshot().TotalServed)
+	assert.Equal(t, int64(2), naps.GetStats().Snapshot().TotalServed)
+
+	api := httptest.NewServer(ListenerAdminHandler(map[string]*Server{"visa": visa, "naps": naps}))
+	defer api.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(api.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	code, body := get("/listeners")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"name":"naps","port":"19898","header":"ascii4"`)
+	assert.Contains(t, body, `"name":"visa","port":"19897","header":"binary2"`)
+
+	code, body = get("/listeners/naps/routes")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"NAPS Decline"`)
+	assert.NotContains(t, body, `"VISA Approve"`)
+
+	code, _ = get("/listeners/amex/routes")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// writeTestCertificates generates a CA plus server and client certificates signed by it and
+// writes them as PEM files into dir
+func writeTestCertificates(t *testing.T, dir string) (caFile, serverCert, serverKey, clientCert, clientKey string) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "jiso test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+		return path
+	}
+	issue := func(serial int64, cn string, usage x509.ExtKeyUsage) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return writePEM(cn+".crt", "CERTIFICATE", der), writePEM(cn+".key", "EC PRIVATE KEY", keyDER)
+	}
+
+	caFile = writePEM("ca.crt", "CERTIFICATE", caDER)
+	serverCert, serverKey = issue(2, "localhost", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey = issue(3, "acquirer-a", x509.ExtKeyUsageClientAuth)
+	return
+}
+
+func TestMutualTLS(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+	caFile, serverCert, serverKey, clientCert, clientKey := writeTestCertificates(t, t.TempDir())
+
+	serverTLS, err := utils.ServerTLSConfig(config.TLSConfig{CertFile: serverCert, KeyFile: serverKey, CAFile: caFile, ClientAuth: true})
+	require.NoError(t, err)
+
+	server := NewServer(spec, []config.MockRouteConfig{
+		{
+			Name:           "Acquirer A",
+			MatchFields:    map[string]interface{}{"0": "0200"},
+			MatchConn:      map[string]interface{}{"client_cn": "acquirer-a"},
+			EchoFields:     []int{11},
+			ResponseFields: map[string]interface{}{"39": "00"},
+		},
+	}, "binary2")
+	server.SetTLSConfig(serverTLS)
+	require.NoError(t, server.Start("19899"))
+	defer server.Stop()
+	assert.True(t, server.IsTLS())
+
+	clientTLS, err := utils.ClientTLSConfig(config.TLSConfig{CertFile: clientCert, KeyFile: clientKey, CAFile: caFile, ServerName: "localhost"})
+	require.NoError(t, err)
+
+	manager := connection.NewManager("127.0.0.1", "19899", spec, false, 0, 2*time.Second, 5*time.Second, nil)
+	manager.SetTLSConfig(clientTLS)
+	header, err := utils.SelectLength("binary2")
+	require.NoError(t, err)
+	require.NoError(t, manager.Connect(false, header))
+	defer manager.Close()
+
+	req := iso8583.NewMessage(spec)
+	req.MTI("0200")
+	req.Field(11, "000001")
+	resp, err := manager.Send(req)
+	require.NoError(t, err)
+	rc, _ := resp.GetField(39).String()
+	assert.Equal(t, "00", rc)
+
+	conns := server.Connections()
+	require.Len(t, conns, 1)
+	assert.Equal(t, "acquirer-a", conns[0].ClientCN)
+
+	// Without a client certificate the server rejects the handshake
+	anonTLS, err := utils.ClientTLSConfig(config.TLSConfig{CAFile: caFile, ServerName: "localhost"})
+	require.NoError(t, err)
+	conn, err := tls.Dial("tcp", "127.0.0.1:19899", anonTLS)
+	if err == nil {
+		defer conn.Close()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, err = conn.Read(make([]byte, 1))
+	}
+	assert.Error(t, err)
+
+	// A plain TCP client gets no ISO8583 response
+	plain, err := net.Dial("tcp", "127.0.0.1:19899")
+	require.NoError(t, err)
+	defer plain.Close()
+	packed, err := req.Pack()
+	require.NoError(t, err)
+	frame := make([]byte, 2+len(packed))
+	binary.BigEndian.PutUint16(frame, uint16(len(packed)))
+	copy(frame[2:], packed)
+	_, _ = plain.Write(frame)
+	require.NoError(t, plain.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var respLen uint16
+	assert.Error(t, binary.Read(plain, binary.BigEndian, &respLen))
+}
@@ -0,0 +1,334 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"jiso/internal/config"
+
+	"github.com/moov-io/iso8583"
+)
+
+// Response codes produced by the stateful mock ledger
+const (
+	StateApproved          = "00"
+	StateInvalidAccount    = "14"
+	StateOriginalNotFound  = "25"
+	StateInsufficientFunds = "51"
+	StateDuplicate         = "94"
+)
+
+// Supported mock route state actions
+const (
+	StateActionAuthorize = "authorize"
+	StateActionPurchase  = "purchase"
+	StateActionComplete  = "complete"
+	StateActionReverse   = "reverse"
+	StateActionInquiry   = "inquiry"
+)
+
+// StateStore tracks account balances and authorization holds across mock server requests.
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Seed sets the available balance for an account, creating it if needed
+	Seed(account string, balance int64)
+	// Balance returns the available and held amounts for an account
+	Balance(account string) (available int64, held int64, ok bool)
+	// Authorize places a hold on the account, indexed by every key in keys
+	Authorize(account string, keys []string, amount int64) string
+	// Purchase debits the account immediately, indexed by every key in keys
+	Purchase(account string, keys []string, amount int64) string
+	// Complete settles the hold referenced by any of origKeys for the given amount
+	Complete(origKeys []string, amount int64) string
+	// Reverse releases the hold or refunds the debit referenced by any of origKeys.
+	// A repeat (0401, 0421) of a reversal already applied is approved without changing balances.
+	Reverse(origKeys []string, repeat bool) string
+	// Reset clears all accounts and records
+	Reset()
+}
+
+// stateRecord is an authorization or debit remembered by the ledger
+type stateRecord struct {
+	account   string
+	amount    int64
+	held      bool
+	completed bool
+	reversed  bool
+}
+
+type stateAccount struct {
+	available int64
+	held      int64
+}
+
+// MemoryStateStore is the default in-process StateStore implementation
+type MemoryStateStore struct {
+	mu       sync.Mutex
+	accounts map[string]*stateAccount
+	records  map[string]*stateRecord
+}
+
+// NewMemoryStateStore creates an empty in-memory state store
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{
+		accounts: make(map[string]*stateAccount),
+		records:  make(map[string]*stateRecord),
+	}
+}
+
+func (s *MemoryStateStore) Seed(account string, balance int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if acc, ok := s.accounts[account]; ok {
+		acc.available = balance
+		return
+	}
+	s.accounts[account] = &stateAccount{available: balance}
+}
+
+func (s *MemoryStateStore) Balance(account string) (int64, int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.accounts[account]
+	if !ok {
+		return 0, 0, false
+	}
+	return acc.available, acc.held, true
+}
+
+func (s *MemoryStateStore) Authorize(account string, keys []string, amount int64) string {
+	return s.debit(account, keys, amount, true)
+}
+
+func (s *MemoryStateStore) Purchase(account string, keys []string, amount int64) string {
+	return s.debit(account, keys, amount, false)
+}
+
+func (s *MemoryStateStore) debit(account string, keys []string, amount int64, hold bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc, ok := s.accounts[account]
+	if !ok {
+		return StateInvalidAccount
+	}
+	if s.findLocked(keys) != nil {
+		return StateDuplicate
+	}
+	if amount > acc.available {
+		return StateInsufficientFunds
+	}
+
+	acc.available -= amount
+	if hold {
+		acc.held += amount
+	}
+
+	rec := &stateRecord{account: account, amount: amount, held: hold}
+	for _, k := range keys {
+		s.records[k] = rec
+	}
+	return StateApproved
+}
+
+func (s *MemoryStateStore) Complete(origKeys []string, amount int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := s.findLocked(origKeys)
+	if rec == nil || rec.reversed {
+		return StateOriginalNotFound
+	}
+	if rec.completed || !rec.held {
+		return StateDuplicate
+	}
+
+	acc := s.accounts[rec.account]
+	// Release the hold, then post the completion amount (which may differ from the hold)
+	acc.held -= rec.amount
+	acc.available += rec.amount
+	if amount > acc.available {
+		acc.available -= rec.amount
+		acc.held += rec.amount
+		return StateInsufficientFunds
+	}
+	acc.available -= amount
+	rec.amount = amount
+	rec.held = false
+	rec.completed = true
+	return StateApproved
+}
+
+func (s *MemoryStateStore) Reverse(origKeys []string, repeat bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := s.findLocked(origKeys)
+	if rec == nil {
+		return StateOriginalNotFound
+	}
+	if rec.reversed {
+		// The acquirer repeats a reversal whose response it lost; answer it again
+		if repeat {
+			return StateApproved
+		}
+		return StateDuplicate
+	}
+
+	acc := s.accounts[rec.account]
+	if rec.held {
+		acc.held -= rec.amount
+	}
+	acc.available += rec.amount
+	rec.held = false
+	rec.reversed = true
+	return StateApproved
+}
+
+func (s *MemoryStateStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts = make(map[string]*stateAccount)
+	s.records = make(map[string]*stateRecord)
+}
+
+func (s *MemoryStateStore) findLocked(keys []string) *stateRecord {
+	for _, k := range keys {
+		if rec, ok := s.records[k]; ok {
+			return rec
+		}
+	}
+	return nil
+}
+
+// SeedStateFromDataset seeds account balances from dataset rows.
+// accountKey names the column holding the PAN/account, balanceKey the column holding the balance in minor units.
+// Returns the number of accounts seeded.
+func SeedStateFromDataset(store StateStore, rows []map[string]string, accountKey, balanceKey string) int {
+	if store == nil {
+		return 0
+	}
+	if accountKey == "" {
+		accountKey = "2"
+	}
+	if balanceKey == "" {
+		balanceKey = "balance"
+	}
+
+	seeded := 0
+	for _, row := range rows {
+		account := strings.TrimSpace(row[accountKey])
+		if account == "" {
+			continue
+		}
+		balance, err := strconv.ParseInt(strings.TrimSpace(row[balanceKey]), 10, 64)
+		if err != nil {
+			continue
+		}
+		store.Seed(account, balance)
+		seeded++
+	}
+	return seeded
+}
+
+// applyState runs the route's ledger action and overrides DE 39 with the outcome.
+// Routes that already decline (DE 39 other than "00") leave the ledger untouched.
+func (m *Matcher) applyState(req, resp *iso8583.Message, sc *config.MockStateConfig) {
+	if m.state == nil {
+		return
+	}
+	if f39 := resp.GetField(39); f39 != nil {
+		if code, _ := f39.String(); code != "" && code != StateApproved {
+			return
+		}
+	}
+
+	accountField := sc.AccountField
+	if accountField == "" {
+		accountField = "2"
+	}
+	amountField := sc.AmountField
+	if amountField == "" {
+		amountField = "4"
+	}
+
+	account, _ := extractFieldValue(req, accountField)
+	account = strings.TrimSpace(account)
+	amount := int64(0)
+	if amtStr, ok := extractFieldValue(req, amountField); ok {
+		amount, _ = strconv.ParseInt(strings.TrimSpace(amtStr), 10, 64)
+	}
+
+	var code string
+	switch strings.ToLower(sc.Action) {
+	case StateActionAuthorize:
+		code = m.state.Authorize(account, requestStateKeys(req), amount)
+	case StateActionPurchase:
+		code = m.state.Purchase(account, requestStateKeys(req), amount)
+	case StateActionComplete:
+		code = m.state.Complete(originalStateKeys(req), amount)
+	case StateActionReverse:
+		code = m.state.Reverse(originalStateKeys(req), isRepeatMTI(req))
+	case StateActionInquiry:
+		available, _, ok := m.state.Balance(account)
+		if !ok {
+			code = StateInvalidAccount
+			break
+		}
+		code = StateApproved
+		currency, ok := extractFieldValue(req, "49")
+		if !ok || currency == "" {
+			currency = "840"
+		}
+		// DE 54: account type, amount type 02 (available), currency, sign, 12-digit amount
+		_ = resp.Field(54, fmt.Sprintf("0002%sC%012d", currency, available))
+	default:
+		return
+	}
+
+	_ = resp.Field(39, code)
+}
+
+// requestStateKeys returns the ledger keys identifying a new authorization (STAN+DE 7 and RRN)
+func requestStateKeys(req *iso8583.Message) []string {
+	var keys []string
+	stan, _ := extractFieldValue(req, "11")
+	dateTime, _ := extractFieldValue(req, "7")
+	if stan != "" {
+		keys = append(keys, stanStateKey(stan, dateTime))
+	}
+	if rrn, ok := extractFieldValue(req, "37"); ok && strings.TrimSpace(rrn) != "" {
+		keys = append(keys, "rrn:"+strings.TrimSpace(rrn))
+	}
+	return keys
+}
+
+// originalStateKeys returns the ledger keys referencing the original authorization of a
+// completion or reversal: original STAN and date/time from DE 90, then the RRN (DE 37)
+func originalStateKeys(req *iso8583.Message) []string {
+	var keys []string
+	if de90, ok := extractFieldValue(req, "90"); ok && len(de90) >= 20 {
+		// DE 90: original MTI (4), STAN (6), transmission date/time (10), acquirer (11), forwarder (11)
+		keys = append(keys, stanStateKey(de90[4:10], de90[10:20]))
+	}
+	if rrn, ok := extractFieldValue(req, "37"); ok && strings.TrimSpace(rrn) != "" {
+		keys = append(keys, "rrn:"+strings.TrimSpace(rrn))
+	}
+	return keys
+}
+
+// isRepeatMTI reports whether req is a repeat, i.e. its MTI origin digit is 1 (0401, 0421)
+func isRepeatMTI(req *iso8583.Message) bool {
+	mti, _ := req.GetMTI()
+	return len(mti) == 4 && mti[3] == '1'
+}
+
+func stanStateKey(stan, dateTime string) string {
+	stan = strings.TrimSpace(stan)
+	if len(stan) < 6 {
+		stan = strings.Repeat("0", 6-len(stan)) + stan
+	}
+	return "stan:" + stan + "|" + strings.TrimSpace(dateTime)
+}
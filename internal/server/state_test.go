@@ -0,0 +1,136 @@
+package server
+
+import (
+	"testing"
+
+	"jiso/internal/config"
+	"jiso/internal/utils"
+
+	"github.com/moov-io/iso8583"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStateStoreHoldsAndReversals(t *testing.T) {
+	store := NewMemoryStateStore()
+	store.Seed("4111111111111111", 10000)
+
+	assert.Equal(t, StateInvalidAccount, store.Authorize("4000000000000000", []string{"k0"}, 100))
+	assert.Equal(t, StateInsufficientFunds, store.Authorize("4111111111111111", []string{"k1"}, 20000))
+	assert.Equal(t, StateApproved, store.Authorize("4111111111111111", []string{"k1"}, 2500))
+	assert.Equal(t, StateDuplicate, store.Authorize("4111111111111111", []string{"k1"}, 2500))
+
+	available, held, ok := store.Balance("4111111111111111")
+	require.True(t, ok)
+	assert.Equal(t, int64(7500), available)
+	assert.Equal(t, int64(2500), held)
+
+	assert.Equal(t, StateOriginalNotFound, store.Reverse([]string{"missing"}, false))
+	assert.Equal(t, StateApproved, store.Reverse([]string{"k1"}, false))
+	assert.Equal(t, StateDuplicate, store.Reverse([]string{"k1"}, false))
+	// A repeat of the reversal is approved again without releasing anything twice
+	assert.Equal(t, StateApproved, store.Reverse([]string{"k1"}, true))
+
+	available, held, _ = store.Balance("4111111111111111")
+	assert.Equal(t, int64(10000), available)
+	assert.Equal(t, int64(0), held)
+
+	assert.Equal(t, StateApproved, store.Authorize("4111111111111111", []string{"k2"}, 3000))
+	assert.Equal(t, StateApproved, store.Complete([]string{"k2"}, 2000))
+	assert.Equal(t, StateDuplicate, store.Complete([]string{"k2"}, 2000))
+
+	available, held, _ = store.Balance("4111111111111111")
+	assert.Equal(t, int64(8000), available)
+	assert.Equal(t, int64(0), held)
+}
+
+func TestSeedStateFromDataset(t *testing.T) {
+	store := NewMemoryStateStore()
+	rows := []map[string]string{
+		{"2": "4111111111111111", "balance": "5000"},
+		{"2": "4222222222222222", "balance": "not-a-number"},
+		{"balance": "100"},
+	}
+	assert.Equal(t, 1, SeedStateFromDataset(store, rows, "", ""))
+
+	available, _, ok := store.Balance("4111111111111111")
+	require.True(t, ok)
+	assert.Equal(t, int64(5000), available)
+}
+
+func TestStatefulRoutesReversalReleasesHold(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	routes := []config.MockRouteConfig{
+		{
+			Name:           "Stateful Purchase",
+			MatchFields:    map[string]interface{}{"0": "0200"},
+			ResponseMTI:    "0210",
+			ResponseFields: map[string]interface{}{"39": "00"},
+			State:          &config.MockStateConfig{Action: StateActionAuthorize},
+		},
+		{
+			Name:           "Stateful Reversal",
+			MatchExpr:      `{{req.0 == "0400" || req.0 == "0401"}}`,
+			ResponseMTI:    "0410",
+			ResponseFields: map[string]interface{}{"39": "00"},
+			State:          &config.MockStateConfig{Action: StateActionReverse},
+		},
+	}
+
+	matcher := NewMatcher(routes)
+	matcher.GetStateStore().Seed("9876543210987654", 3000)
+
+	purchase := func(stan string, amount string) string {
+		msg := iso8583.NewMessage(spec)
+		msg.MTI("0200")
+		msg.Field(2, "9876543210987654")
+		msg.Field(4, amount)
+		msg.Field(7, "0725213835")
+		msg.Field(11, stan)
+		_, resp, err := matcher.MatchAndCompose(msg, spec)
+		require.NoError(t, err)
+		code, _ := resp.GetField(39).String()
+		return code
+	}
+
+	assert.Equal(t, StateApproved, purchase("000101", "2500"))
+	assert.Equal(t, StateDuplicate, purchase("000101", "2500"))
+	assert.Equal(t, StateInsufficientFunds, purchase("000102", "1000"))
+
+	reverse := func(mti string) string {
+		msg := iso8583.NewMessage(spec)
+		msg.MTI(mti)
+		msg.Field(2, "9876543210987654")
+		msg.Field(4, "2500")
+		msg.Field(11, "000103")
+		msg.Field(90, "020000010107252138350000000000000000000000")
+		_, resp, err := matcher.MatchAndCompose(msg, spec)
+		require.NoError(t, err)
+		code, _ := resp.GetField(39).String()
+		return code
+	}
+	assert.Equal(t, StateApproved, reverse("0400"))
+
+	available, held, _ := matcher.GetStateStore().Balance("9876543210987654")
+	assert.Equal(t, int64(3000), available)
+	assert.Equal(t, int64(0), held)
+
+	// A repeated reversal is approved without changing the balance; a plain duplicate is not
+	assert.Equal(t, StateApproved, reverse("0401"))
+	assert.Equal(t, StateDuplicate, reverse("0400"))
+	available, held, _ = matcher.GetStateStore().Balance("9876543210987654")
+	assert.Equal(t, int64(3000), available)
+	assert.Equal(t, int64(0), held)
+	assert.Equal(t, StateApproved, purchase("000104", "1000"))
+
+	orphan := iso8583.NewMessage(spec)
+	orphan.MTI("0400")
+	orphan.Field(11, "000105")
+	orphan.Field(90, "020099999907252138350000000000000000000000")
+	_, resp, err := matcher.MatchAndCompose(orphan, spec)
+	require.NoError(t, err)
+	code, _ := resp.GetField(39).String()
+	assert.Equal(t, StateOriginalNotFound, code)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"maps"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,6 +20,9 @@ type ServerStats struct {
 	lastTime    time.Time
 	instantTps  float64
 	peakInstTps float64
+
+	outboundSent     int64
+	outboundAnswered int64
 }
 
 func NewServerStats() *ServerStats {
@@ -66,6 +70,15 @@ func (s *ServerStats) RecordMessage(mti string, routeName string, responseCode s
 	}
 }
 
+// RecordOutbound counts a server-initiated message sent to a client, or the client's answer to one
+func (s *ServerStats) RecordOutbound(answered bool) {
+	if answered {
+		atomic.AddInt64(&s.outboundAnswered, 1)
+	} else {
+		atomic.AddInt64(&s.outboundSent, 1)
+	}
+}
+
 func (s *ServerStats) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -75,11 +88,56 @@ func (s *ServerStats) Reset() {
 	s.lastTotal = 0
 	s.instantTps = 0
 	s.peakInstTps = 0
+	atomic.StoreInt64(&s.outboundSent, 0)
+	atomic.StoreInt64(&s.outboundAnswered, 0)
 	s.mtiStats = make(map[string]int64)
 	s.routeStats = make(map[string]int64)
 	s.codeStats = make(map[string]int64)
 }
 
+// StatsSnapshot is a point-in-time copy of ServerStats, suitable for JSON encoding
+type StatsSnapshot struct {
+	StartTime   time.Time        `json:"start_time"`
+	UptimeSec   float64          `json:"uptime_sec"`
+	TotalServed int64            `json:"total_served"`
+	InstantTps  float64          `json:"instant_tps"`
+	PeakTps     float64          `json:"peak_tps"`
+	AvgTps      float64          `json:"avg_tps"`
+	ByRoute     map[string]int64 `json:"by_route"`
+	ByMTI       map[string]int64 `json:"by_mti"`
+	ByCode      map[string]int64 `json:"by_response_code"`
+
+	OutboundSent     int64 `json:"outbound_sent"`
+	OutboundAnswered int64 `json:"outbound_answered"`
+}
+
+// Snapshot returns a copy of the current statistics
+func (s *ServerStats) Snapshot() StatsSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	total := atomic.LoadInt64(&s.totalServed)
+	elapsed := time.Since(s.startTime)
+	avgTps := 0.0
+	if elapsed.Seconds() > 0 {
+		avgTps = float64(total) / elapsed.Seconds()
+	}
+	return StatsSnapshot{
+		StartTime:   s.startTime,
+		UptimeSec:   elapsed.Seconds(),
+		TotalServed: total,
+		InstantTps:  s.instantTps,
+		PeakTps:     s.peakInstTps,
+		AvgTps:      avgTps,
+		ByRoute:     maps.Clone(s.routeStats),
+		ByMTI:       maps.Clone(s.mtiStats),
+		ByCode:      maps.Clone(s.codeStats),
+
+		OutboundSent:     atomic.LoadInt64(&s.outboundSent),
+		OutboundAnswered: atomic.LoadInt64(&s.outboundAnswered),
+	}
+}
+
 func (s *ServerStats) PrintSummary(port string, headerType string, activeConns int) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -99,6 +157,9 @@ func (s *ServerStats) PrintSummary(port string, headerType string, activeConns i
 	fmt.Printf("Active TCP Connections: %d\n", activeConns)
 	fmt.Printf("Total Served Messages:  %d\n", total)
 	fmt.Printf("Throughput Performance: Instant TPS: %.1f | Peak TPS: %.1f | Avg TPS: %.1f\n", s.instantTps, s.peakInstTps, avgTps)
+	if sent := atomic.LoadInt64(&s.outboundSent); sent > 0 {
+		fmt.Printf("Server-Initiated:       Sent: %d | Answered: %d\n", sent, atomic.LoadInt64(&s.outboundAnswered))
+	}
 	fmt.Println("--------------------------------------------------------------------------------")
 	fmt.Println("SERVED BY MOCK ROUTE")
 	fmt.Println("--------------------------------------------------------------------------------")
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"sort"
+
+	"jiso/internal/config"
+)
+
+// sortRoutesByPriority returns a copy of routes ordered by descending priority.
+// Routes with equal priority keep their file order.
+func sortRoutesByPriority(routes []config.MockRouteConfig) []config.MockRouteConfig {
+	sorted := make([]config.MockRouteConfig, len(routes))
+	copy(sorted, routes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	return sorted
+}
+
+// selectVariant picks one of the route's weighted response variants and returns the effective
+// route with the variant merged in. Routes without variants are returned unchanged.
+func (m *Matcher) selectVariant(r *config.MockRouteConfig) *config.MockRouteConfig {
+	if len(r.Responses) == 0 {
+		return r
+	}
+
+	total := 0.0
+	for _, v := range r.Responses {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total <= 0 {
+		return r
+	}
+
+	pick := m.randFloat() * total
+	chosen := &r.Responses[len(r.Responses)-1]
+	for i := range r.Responses {
+		v := &r.Responses[i]
+		if v.Weight <= 0 {
+			continue
+		}
+		if pick < v.Weight {
+			chosen = v
+			break
+		}
+		pick -= v.Weight
+	}
+
+	return mergeVariant(r, chosen)
+}
+
+// mergeVariant overlays a response variant on a copy of its route
+func mergeVariant(r *config.MockRouteConfig, v *config.MockResponseVariant) *config.MockRouteConfig {
+	eff := *r
+	eff.Responses = nil
+	if v.Name != "" {
+		eff.Name = r.Name + " [" + v.Name + "]"
+	}
+	if v.ResponseMTI != "" {
+		eff.ResponseMTI = v.ResponseMTI
+	}
+	if len(v.ResponseFields) > 0 {
+		eff.ResponseFields = make(map[string]interface{}, len(r.ResponseFields)+len(v.ResponseFields))
+		for k, val := range r.ResponseFields {
+			eff.ResponseFields[k] = val
+		}
+		for k, val := range v.ResponseFields {
+			eff.ResponseFields[k] = val
+		}
+	}
+	if v.DelayMs > 0 || v.LatencyMs > 0 || v.JitterMs > 0 {
+		eff.DelayMs = v.DelayMs
+		eff.LatencyMs = v.LatencyMs
+		eff.JitterMs = v.JitterMs
+	}
+	if v.Fault != "" {
+		eff.Fault = v.Fault
+		eff.StallMs = v.StallMs
+	}
+	eff.NoResponse = r.NoResponse || v.NoResponse
+	eff.DropConnection = r.DropConnection || v.DropConnection
+	return &eff
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// FileWatcher polls a set of files and reports modifications. Polling keeps the
+// watcher dependency-free and works on network mounts where inotify does not.
+type FileWatcher struct {
+	paths    []string
+	interval time.Duration
+	onChange func(path string)
+	modTimes map[string]time.Time
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFileWatcher creates a watcher calling onChange whenever one of paths is modified.
+// Empty paths are ignored.
+func NewFileWatcher(paths []string, interval time.Duration, onChange func(path string)) *FileWatcher {
+	w := &FileWatcher{
+		interval: interval,
+		onChange: onChange,
+		modTimes: make(map[string]time.Time),
+		stopChan: make(chan struct{}),
+	}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		w.paths = append(w.paths, p)
+		w.modTimes[p] = modTime(p)
+	}
+	return w
+}
+
+// Start begins polling in the background
+func (w *FileWatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stopChan:
+				return
+			case <-ticker.C:
+				w.poll()
+			}
+		}
+	}()
+}
+
+// Stop ends polling
+func (w *FileWatcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stopChan) })
+}
+
+func (w *FileWatcher) poll() {
+	for _, p := range w.paths {
+		mt := modTime(p)
+		if mt.IsZero() || mt.Equal(w.modTimes[p]) {
+			continue
+		}
+		w.modTimes[p] = mt
+		w.onChange(p)
+	}
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
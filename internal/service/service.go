@@ -2,8 +2,11 @@ package service
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"jiso/internal/config"
 	"jiso/internal/connection"
 	"jiso/internal/metrics"
 	"jiso/internal/utils"
@@ -17,9 +20,11 @@ type Service struct {
 	Address      string
 	Connection   *moovconnection.Connection
 	MessageSpec  *iso8583.MessageSpec
-	connManager  *connection.Manager
+	connManager  *connection.Manager // Primary socket; settings are copied to the rest of the pool
+	pool         *connection.Pool
 	debugMode    bool
 	networkStats *metrics.NetworkingStats
+	reversal     *connection.ReversalPolicy // nil when unanswered requests are not reversed
 }
 
 func NewService(
@@ -60,6 +65,7 @@ func NewService(
 		MessageSpec:  spec,
 		Address:      fmt.Sprintf("%s:%s", host, port),
 		connManager:  connManager,
+		pool:         connection.NewPool(connManager, 1, connection.PoolRoundRobin),
 		debugMode:    debugMode,
 		networkStats: metrics.NewNetworkingStats(),
 	}
@@ -70,16 +76,87 @@ func NewService(
 	return service, nil
 }
 
+// managers returns the connection manager of every pooled socket
+func (s *Service) managers() []*connection.Manager {
+	if s.pool == nil {
+		return nil
+	}
+	return s.pool.Managers()
+}
+
+// ConfigurePool sets how many sockets Connect opens to the target and how requests
+// are spread over them. It must be called while disconnected.
+func (s *Service) ConfigurePool(size int, strategy string) error {
+	if size < 1 || size > connection.MaxPoolSize {
+		return fmt.Errorf("pool size must be between 1 and %d, got %d", connection.MaxPoolSize, size)
+	}
+	poolStrategy, err := connection.ParsePoolStrategy(strategy)
+	if err != nil {
+		return err
+	}
+	if s.IsConnected() {
+		return fmt.Errorf("disconnect before changing the connection pool")
+	}
+	if s.connManager != nil {
+		s.pool = connection.NewPool(s.connManager, size, poolStrategy)
+	}
+	return nil
+}
+
+// PoolSize returns the number of sockets opened to the target
+func (s *Service) PoolSize() int {
+	if s.pool == nil {
+		return 1
+	}
+	return s.pool.Size()
+}
+
+// PoolStrategy returns how requests are spread over the pooled sockets
+func (s *Service) PoolStrategy() connection.PoolStrategy {
+	if s.pool == nil {
+		return connection.PoolRoundRobin
+	}
+	return s.pool.Strategy()
+}
+
+// Socket returns a service that sends over the i-th pooled socket only and sees only
+// the inbound messages of that socket, so concurrent users of one connection pool do
+// not share a pending table. Disconnecting the pool is left to s.
+func (s *Service) Socket(i int) *Service {
+	managers := s.managers()
+	if i < 0 || i >= len(managers) {
+		return s
+	}
+	return &Service{
+		Address:      s.Address,
+		Connection:   s.Connection,
+		MessageSpec:  s.MessageSpec,
+		connManager:  managers[i],
+		pool:         connection.NewPool(managers[i], 1, connection.PoolRoundRobin),
+		debugMode:    s.debugMode,
+		networkStats: s.networkStats,
+		reversal:     s.reversal,
+	}
+}
+
+// GetSocketStats returns per-socket statistics of the connection pool
+func (s *Service) GetSocketStats() []connection.SocketStats {
+	if s.pool == nil {
+		return nil
+	}
+	return s.pool.Stats()
+}
+
 // Connect establishes a connection to the server
 func (s *Service) Connect(naps bool, header network.Header) error {
-	err := s.connManager.Connect(naps, header)
+	err := s.pool.Connect(naps, header)
 	if err != nil {
 		return err
 	}
 
 	// Maintain backward compatibility with existing code
 	// by exposing the Connection field
-	s.Connection = s.connManager.Connection
+	s.Connection = s.pool.Connection()
 
 	// Give the connection a moment to stabilize
 	// This prevents false "connected" status before the connection is truly ready
@@ -97,7 +174,8 @@ func (s *Service) Connect(naps bool, header network.Header) error {
 
 // Disconnect closes the connection to the server
 func (s *Service) Disconnect() error {
-	err := s.connManager.Close()
+	s.signOff()
+	err := s.pool.Close()
 	if err != nil {
 		return fmt.Errorf("failed to close connection: %w", err)
 	}
@@ -109,7 +187,7 @@ func (s *Service) Disconnect() error {
 
 // IsConnected returns whether the service is connected
 func (s *Service) IsConnected() bool {
-	return s.connManager.IsConnected()
+	return s.pool != nil && s.pool.IsConnected()
 }
 
 // GetSpec returns the current ISO8583 message specification
@@ -120,34 +198,248 @@ func (s *Service) GetSpec() *iso8583.MessageSpec {
 // SetSpec updates the current ISO8583 message specification
 func (s *Service) SetSpec(spec *iso8583.MessageSpec) {
 	s.MessageSpec = spec
-	if s.connManager != nil {
-		s.connManager.SetSpec(spec)
+	for _, m := range s.managers() {
+		m.SetSpec(spec)
 	}
 }
 
 // SetTarget updates the target endpoint for the service and its connection manager
 func (s *Service) SetTarget(host, port string) {
 	s.Address = fmt.Sprintf("%s:%s", host, port)
+	for _, m := range s.managers() {
+		m.SetAddress(host, port)
+	}
+}
+
+// SetSecondaryTargets sets the standby "host:port" targets used when the primary fails
+func (s *Service) SetSecondaryTargets(addresses []string) {
+	for _, m := range s.managers() {
+		m.SetSecondaryTargets(addresses)
+	}
+}
+
+// SetFailbackInterval sets how often the primary is echo tested while on a secondary
+func (s *Service) SetFailbackInterval(interval time.Duration) {
+	for _, m := range s.managers() {
+		m.SetFailbackInterval(interval)
+	}
+}
+
+// Failover moves every pooled socket to the next reachable target of the group
+func (s *Service) Failover(reason string) error {
+	var firstErr error
+	for _, m := range s.managers() {
+		if err := m.Failover(reason); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		s.Connection = s.pool.Connection()
+	}
+	return firstErr
+}
+
+// HasSecondaryTargets reports whether the service can fail over
+func (s *Service) HasSecondaryTargets() bool {
+	return s.connManager != nil && s.connManager.HasSecondaryTargets()
+}
+
+// GetTargets returns the target group, primary first
+func (s *Service) GetTargets() []string {
+	if s.connManager == nil {
+		return []string{s.Address}
+	}
+	return s.connManager.Targets()
+}
+
+// GetActiveTarget returns the target the primary socket is connected to
+func (s *Service) GetActiveTarget() string {
+	if s.connManager == nil {
+		return s.Address
+	}
+	return s.connManager.GetAddress()
+}
+
+// ConfigureTLS applies TLS settings to subsequent connections. Inactive settings select plain TCP.
+func (s *Service) ConfigureTLS(c config.TLSConfig) error {
+	tlsConfig, err := utils.ClientTLSConfig(c)
+	if err != nil {
+		return err
+	}
+	for _, m := range s.managers() {
+		m.SetTLSConfig(tlsConfig)
+	}
+	return nil
+}
+
+// ConfigureCorrelation sets how responses are paired with pending requests and what
+// happens when a request's key is already pending. Empty values keep STAN and fail.
+func (s *Service) ConfigureCorrelation(key, duplicatePolicy string) error {
+	correlationKey, err := connection.ParseCorrelationKey(key)
+	if err != nil {
+		return err
+	}
+	policy, err := connection.ParseDuplicatePolicy(duplicatePolicy)
+	if err != nil {
+		return err
+	}
+	for _, m := range s.managers() {
+		m.SetCorrelationKey(correlationKey)
+		m.SetDuplicatePolicy(policy)
+	}
+	return nil
+}
+
+// ConfigureFrames sets the accepted message sizes and the invalid frame policy
+// (close or skip) for subsequent connections. Zero sizes keep the defaults.
+func (s *Service) ConfigureFrames(minSize, maxSize int, onInvalid string) error {
+	limits, err := utils.NewFrameLimits(minSize, maxSize, onInvalid)
+	if err != nil {
+		return err
+	}
+	for _, m := range s.managers() {
+		m.SetFrameLimits(limits)
+	}
+	return nil
+}
+
+// GetFrameLimits returns the accepted message sizes and invalid frame policy
+func (s *Service) GetFrameLimits() utils.FrameLimits {
+	return s.connManager.GetFrameLimits()
+}
+
+// GetCorrelationKey returns the fields used to pair responses with requests
+func (s *Service) GetCorrelationKey() connection.CorrelationKey {
 	if s.connManager != nil {
-		s.connManager.SetAddress(host, port)
+		return s.connManager.GetCorrelationKey()
+	}
+	return connection.DefaultCorrelationKey
+}
+
+// DuplicateKeys returns how many sends found their correlation key already pending
+func (s *Service) DuplicateKeys() int64 {
+	var total int64
+	for _, m := range s.managers() {
+		total += m.DuplicateKeys()
+	}
+	return total
+}
+
+// ConfigureSession sets the network management messages sent on every socket.
+// "auto" selects the built-in 0800 for the step, "auto:<code>" the built-in 0800
+// with that DE70 code, any other name is built with compose, and an empty name
+// skips the step. A zero echo interval disables echo
+// tests; with no messages either the session is turned off.
+func (s *Service) ConfigureSession(
+	signOn, echo, signOff string,
+	echoInterval time.Duration,
+	compose func(name string) (*iso8583.Message, error),
+) error {
+	if echoInterval < 0 {
+		return fmt.Errorf("echo interval must not be negative: %v", echoInterval)
+	}
+	for _, name := range []string{signOn, echo, signOff} {
+		if code, ok := strings.CutPrefix(name, "auto:"); ok && !isNetworkCode(code) {
+			return fmt.Errorf("invalid network management code '%s': expected 3 digits", code)
+		}
+	}
+	var session *connection.Session
+	if signOn != "" || signOff != "" || echoInterval > 0 {
+		session = &connection.Session{
+			SignOn:       sessionMessage(signOn, connection.NetworkSignOn, compose),
+			Echo:         sessionMessage(echo, connection.NetworkEcho, compose),
+			SignOff:      sessionMessage(signOff, connection.NetworkSignOff, compose),
+			EchoInterval: echoInterval,
+		}
+	}
+	for _, m := range s.managers() {
+		m.SetSession(session)
+	}
+	return nil
+}
+
+// sessionMessage resolves a session step name to a message factory
+func sessionMessage(
+	name, code string,
+	compose func(name string) (*iso8583.Message, error),
+) connection.MessageFactory {
+	if custom, ok := strings.CutPrefix(name, "auto:"); ok {
+		name, code = "auto", custom
+	}
+	switch {
+	case name == "":
+		return nil
+	case name == "auto" || compose == nil:
+		return func(spec *iso8583.MessageSpec) (*iso8583.Message, error) {
+			return connection.NewNetworkMessage(spec, code), nil
+		}
+	default:
+		return func(*iso8583.MessageSpec) (*iso8583.Message, error) {
+			return compose(name)
+		}
 	}
 }
 
+// isNetworkCode reports whether code is a 3-digit DE70 network management code
+func isNetworkCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ConfigureReversal sets the auto-reversal policy: 0400 or 0420 reverses requests
+// that get no response, repeating the reversal up to repeats times; empty or off
+// disables it
+func (s *Service) ConfigureReversal(policy string, repeats int) error {
+	reversal, err := connection.ParseReversalPolicy(policy, repeats)
+	if err != nil {
+		return err
+	}
+	s.reversal = reversal
+	return nil
+}
+
+// ReversalEnabled reports whether unanswered requests are reversed
+func (s *Service) ReversalEnabled() bool {
+	return s.reversal != nil
+}
+
+// Reverse sends the reversal of orig, which got no response, and returns its
+// outcome; it returns nil when auto-reversal is off
+func (s *Service) Reverse(orig *iso8583.Message) *connection.ReversalResult {
+	if s.reversal == nil || orig == nil {
+		return nil
+	}
+	return connection.SendReversal(orig, s.reversal, s.Send)
+}
+
 // SetMockMatcher configures a mock matcher for processing unsolicited incoming messages
 func (s *Service) SetMockMatcher(matcher connection.RouteMatcher) {
-	if s.connManager != nil {
-		s.connManager.SetMockMatcher(matcher)
+	for _, m := range s.managers() {
+		m.SetMockMatcher(matcher)
 	}
 }
 
 // Send sends an ISO8583 message and returns the response
 func (s *Service) Send(msg *iso8583.Message) (*iso8583.Message, error) {
-	return s.connManager.Send(msg)
+	return s.pool.Send(msg)
+}
+
+// SendWithHeader sends an ISO8583 message with header field overrides and returns the
+// response with its header fields
+func (s *Service) SendWithHeader(msg *iso8583.Message, headerFields map[string]string) (*iso8583.Message, map[string]string, error) {
+	return s.pool.SendWithHeader(msg, headerFields)
 }
 
 // BackgroundSend sends an ISO8583 message without debug logging
 func (s *Service) BackgroundSend(msg *iso8583.Message) (*iso8583.Message, error) {
-	return s.connManager.BackgroundSend(msg)
+	return s.pool.BackgroundSend(msg)
 }
 
 // SendAsync sends a message asynchronously and returns a channel for the response
@@ -155,15 +447,69 @@ func (s *Service) SendAsync(
 	msg *iso8583.Message,
 	transactionName string,
 ) (<-chan *iso8583.Message, error) {
-	return s.connManager.SendAsync(msg, transactionName)
+	return s.pool.SendAsync(msg, transactionName)
+}
+
+// SubscribeInbound subscribes to the inbound messages no pending request took on every
+// pooled socket, such as unsolicited advices and network management requests from the
+// target. The returned function ends the subscription and closes the channel.
+func (s *Service) SubscribeInbound(buffer int) (<-chan *connection.InboundMessage, func()) {
+	out := make(chan *connection.InboundMessage, buffer)
+	done := make(chan struct{})
+	var cancels []func()
+	var wg sync.WaitGroup
+	for _, m := range s.managers() {
+		in, cancel := m.SubscribeInbound(buffer)
+		cancels = append(cancels, cancel)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case msg, ok := <-in:
+					if !ok {
+						return
+					}
+					select {
+					case out <- msg:
+					default:
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(done)
+			for _, cancel := range cancels {
+				cancel()
+			}
+			wg.Wait()
+			close(out)
+		})
+	}
 }
 
 // Close closes the connection when service is shut down
 func (s *Service) Close() error {
-	if s.connManager == nil {
+	if s.pool == nil {
 		return nil
 	}
-	return s.connManager.Close()
+	s.signOff()
+	return s.pool.Close()
+}
+
+// signOff signs every connected socket off before it is closed
+func (s *Service) signOff() {
+	for _, m := range s.managers() {
+		if err := m.SignOff(); err != nil {
+			fmt.Printf("Sign-off from %s failed: %v\n", m.GetAddress(), err)
+		}
+	}
 }
 
 // GetNetworkingStats returns the networking statistics
@@ -179,15 +525,15 @@ func (s *Service) GetDebugMode() bool {
 // SetDebugMode sets whether debug mode is enabled on service and connection manager
 func (s *Service) SetDebugMode(debug bool) {
 	s.debugMode = debug
-	if s.connManager != nil {
-		s.connManager.SetDebugMode(debug)
+	for _, m := range s.managers() {
+		m.SetDebugMode(debug)
 	}
 }
 
-// SetMaxPendingRequests sets the maximum number of pending requests on the connection manager
+// SetMaxPendingRequests sets the maximum number of pending requests on each pooled socket
 func (s *Service) SetMaxPendingRequests(max int) {
-	if s.connManager != nil {
-		s.connManager.SetMaxPendingRequests(max)
+	for _, m := range s.managers() {
+		m.SetMaxPendingRequests(max)
 	}
 }
 
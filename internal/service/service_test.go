@@ -146,6 +146,30 @@ func TestServiceGetters(t *testing.T) {
 	}
 }
 
+func TestServiceSocket(t *testing.T) {
+	service, err := NewService("localhost", "8080", "", false, 0, time.Second, time.Second, time.Second)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	if err := service.ConfigurePool(3, "round_robin"); err != nil {
+		t.Fatalf("ConfigurePool failed: %v", err)
+	}
+
+	socket := service.Socket(2)
+	if socket.PoolSize() != 1 {
+		t.Errorf("Expected a single socket, got %d", socket.PoolSize())
+	}
+	if socket.managers()[0] != service.managers()[2] {
+		t.Error("Expected the socket view to use the third pooled socket")
+	}
+	if socket.GetSpec() != service.GetSpec() {
+		t.Error("Expected the socket view to share the spec")
+	}
+	if service.Socket(3) != service {
+		t.Error("Expected an out of range socket to return the service itself")
+	}
+}
+
 func TestServiceIsConnected(t *testing.T) {
 	specFile := createTempSpecFile(t)
 	defer os.Remove(specFile)
@@ -335,3 +359,35 @@ func (s *testServer) Close() {
 	close(s.done)
 	s.listener.Close()
 }
+
+func TestSessionMessageNetworkCode(t *testing.T) {
+	specFile := createTempSpecFile(t)
+	defer os.Remove(specFile)
+
+	spec, err := utils.CreateSpecFromFile(specFile)
+	if err != nil {
+		t.Fatalf("Failed to load spec: %v", err)
+	}
+
+	for name, expected := range map[string]string{"auto": "301", "auto:270": "270"} {
+		msg, err := sessionMessage(name, "301", nil)(spec)
+		if err != nil {
+			t.Fatalf("%s: building message failed: %v", name, err)
+		}
+		code, err := msg.GetString(70)
+		if err != nil {
+			t.Fatalf("%s: reading DE70 failed: %v", name, err)
+		}
+		if code != expected {
+			t.Errorf("%s: expected DE70 %s, got %s", name, expected, code)
+		}
+	}
+
+	service, err := NewService("localhost", "8080", specFile, false, 3, 5*time.Second, 10*time.Second, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	if err := service.ConfigureSession("auto:61", "", "", 0, nil); err == nil {
+		t.Error("Expected an error for a 2-digit network management code")
+	}
+}
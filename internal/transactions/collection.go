@@ -17,6 +17,30 @@ import (
 	"github.com/moov-io/iso8583"
 )
 
+// GetMockOutbound returns the server-initiated messages defined for the embedded mock server
+func (tc *TransactionCollection) GetMockOutbound() []cfg.MockOutboundConfig {
+	if tc == nil {
+		return nil
+	}
+	return tc.mockOutbound
+}
+
+// GetMockListeners returns the listeners of a multi-listener mock server
+func (tc *TransactionCollection) GetMockListeners() []cfg.MockListenerConfig {
+	if tc == nil {
+		return nil
+	}
+	return tc.mockListeners
+}
+
+// GetFramings returns the custom wire framings defined in the file
+func (tc *TransactionCollection) GetFramings() []cfg.FramingConfig {
+	if tc == nil {
+		return nil
+	}
+	return tc.framings
+}
+
 func (tc *TransactionCollection) GetMockRoutes() []cfg.MockRouteConfig {
 	if tc == nil {
 		return nil
@@ -122,26 +146,85 @@ func NewTransactionCollection(
 				Description: item.Description,
 				DatasetName: item.DatasetName,
 				Steps:       item.Steps,
+				Finally:     item.Finally,
+				Tags:        item.Tags,
+				Mock:        item.Mock,
+				Setup:       item.Setup,
+				Teardown:    item.Teardown,
 			}
 			tc.scenarios[item.Name] = &s
 		case "mock_route":
 			r := cfg.MockRouteConfig{
 				Name:           item.Name,
 				MatchFields:    item.MatchFields,
+				MatchExpr:      item.MatchExpr,
+				MatchConn:      item.MatchConn,
 				RequiredFields: item.RequiredFields,
 				EchoFields:     item.EchoFields,
 				ResponseMTI:    item.ResponseMTI,
 				ResponseFields: item.ResponseFields,
+				ResponseHeader: item.ResponseHeader,
 				DelayMs:        item.DelayMs,
 				LatencyMs:      item.LatencyMs,
 				JitterMs:       item.JitterMs,
 				DropConnection: item.DropConnection,
+				NoResponse:     item.NoResponse,
+				Fault:          item.Fault,
+				StallMs:        item.StallMs,
+				Priority:       item.Priority,
+				Disabled:       item.Disabled,
+				Responses:      item.Responses,
+				State:          item.State,
+				Listener:       item.Listener,
 			}
 			tc.mockRoutes = append(tc.mockRoutes, r)
+		case "mock_outbound":
+			o := cfg.MockOutboundConfig{
+				Name:           item.Name,
+				Description:    item.Description,
+				IntervalMs:     item.IntervalMs,
+				InitialDelayMs: item.InitialDelayMs,
+				Count:          item.Count,
+				Listener:       item.Listener,
+			}
+			if len(item.Fields) > 0 {
+				if err := json.Unmarshal(item.Fields, &o.Fields); err != nil {
+					return nil, fmt.Errorf("mock outbound '%s': invalid fields: %w", item.Name, err)
+				}
+			}
+			tc.mockOutbound = append(tc.mockOutbound, o)
+		case "mock_listener":
+			specPath := item.Spec
+			if specPath == "" {
+				specPath = item.SpecFile
+			}
+			tc.mockListeners = append(tc.mockListeners, cfg.MockListenerConfig{
+				Name:        item.Name,
+				Description: item.Description,
+				Port:        item.Port,
+				Header:      item.Header,
+				Spec:        specPath,
+
+				MinMessageSize: item.MinMessageSize,
+				MaxMessageSize: item.MaxMessageSize,
+				OnInvalidFrame: item.OnInvalidFrame,
+			})
+		case "framing":
+			tc.framings = append(tc.framings, cfg.FramingConfig{
+				Name:                 item.Name,
+				Description:          item.Description,
+				LengthBytes:          item.LengthBytes,
+				LengthEncoding:       item.LengthEncoding,
+				LengthIncludesHeader: item.LengthIncludesHeader,
+				Prefix:               item.Prefix,
+				PrefixHex:            item.PrefixHex,
+				TPDU:                 item.TPDU,
+				Trailer:              item.Trailer,
+			})
 		}
 	}
 
-	if len(tc.transactions) == 0 && len(tc.scenarios) == 0 && len(tc.mockRoutes) == 0 {
+	if len(tc.transactions) == 0 && len(tc.scenarios) == 0 && len(tc.mockRoutes) == 0 && len(tc.mockOutbound) == 0 && len(tc.mockListeners) == 0 && len(tc.framings) == 0 {
 		return nil, errors.New("no transactions, scenarios, or mock routes found in the file")
 	}
 
@@ -155,6 +238,13 @@ func NewTransactionCollection(
 		return nil, fmt.Errorf("transaction validation failed: %w", err)
 	}
 
+	// Custom framings become selectable by name for connect, serve and analyze
+	for _, f := range tc.framings {
+		if err := utils.RegisterFraming(f); err != nil {
+			return nil, err
+		}
+	}
+
 	// Set the persistence directory to the same as used by the STAN counter
 	_ = tc.SetPersistenceDirectory(utils.GetPersistenceDirectory())
 
@@ -386,7 +386,6 @@ func (tc *TransactionCollection) setCompositeFieldValue(
 	return utils.SetCompositeFieldValue(msg, spec, fieldID, value)
 }
 
-
 func (tc *TransactionCollection) handleAutoFieldsWithKeyword(i int, msg *iso8583.Message, keyword string) {
 	cleanKey := strings.TrimSpace(strings.ToLower(keyword))
 	switch cleanKey {
@@ -2,6 +2,8 @@ package transactions
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -41,43 +43,140 @@ type Scenario struct {
 	Description string         `json:"description"`
 	DatasetName string         `json:"dataset_name"`
 	Steps       []ScenarioStep `json:"steps"`
+	Finally     []ScenarioStep `json:"finally,omitempty"`  // Cleanup steps run after the steps, even when one failed
+	Tags        []string       `json:"tags,omitempty"`     // Labels run-suite selects scenarios by
+	Mock        *ScenarioMock  `json:"mock,omitempty"`     // Mock server the scenario runs against instead of the target
+	Setup       []ScenarioStep `json:"setup,omitempty"`    // Steps run before the steps; a failure skips them
+	Teardown    []ScenarioStep `json:"teardown,omitempty"` // Steps run last, whatever happened before
+}
+
+// ScenarioMock is a mock server the runner starts on an ephemeral port for one
+// scenario and connects to, making the scenario a self-contained test
+type ScenarioMock struct {
+	Routes    []cfg.MockRouteConfig `json:"routes,omitempty"`     // Inline routes
+	UseRoutes []string              `json:"use_routes,omitempty"` // Names or globs of mock_route items of the file
+	Header    string                `json:"header,omitempty"`     // Length header of the mock and the connection; defaults to ascii4
 }
 
 type ScenarioStep struct {
-	Name             string                 `json:"name"`
-	UseTransactionId string                 `json:"use_transaction_id"`
-	Fields           map[string]interface{} `json:"fields"`
-	Extract          map[string]string      `json:"extract"`
-	Validate         []Assertion            `json:"validate"`
+	Name              string                 `json:"name"`
+	UseTransactionId  string                 `json:"use_transaction_id"`
+	Fields            map[string]interface{} `json:"fields"`
+	Extract           map[string]string      `json:"extract"`
+	Validate          []Assertion            `json:"validate"`
+	When              string                 `json:"when,omitempty"`                // Condition on rc, failed, context.* and data.*; the step is skipped when false
+	Repeat            int                    `json:"repeat,omitempty"`              // Runs the step this many times (per foreach row)
+	Foreach           string                 `json:"foreach,omitempty"`             // Dataset whose rows the step runs once each for
+	Retry             *StepRetry             `json:"retry,omitempty"`               // Reruns a failed step with backoff
+	ContinueOnFailure bool                   `json:"continue_on_failure,omitempty"` // A failure is reported but neither stops nor fails the scenario
+	MaxLatencyMs      int                    `json:"max_latency_ms,omitempty"`      // Fails the step when its response takes longer
+	Kind              string                 `json:"kind,omitempty"`                // send (default), send_only, receive or expect_none
+	Match             map[string]interface{} `json:"match,omitempty"`               // match_fields style criteria an inbound message must meet
+	TimeoutMs         int                    `json:"timeout_ms,omitempty"`          // How long receive, expect_none and extra responses wait; defaults to the response timeout
+	Responses         int                    `json:"responses,omitempty"`           // Responses a send step expects for its request; defaults to 1
+}
+
+// Scenario step kinds
+const (
+	StepSend       = "send"        // Sends a request and waits for its response
+	StepSendOnly   = "send_only"   // Sends a request, such as an advice, without waiting
+	StepReceive    = "receive"     // Waits for an inbound message matching the step
+	StepExpectNone = "expect_none" // Fails if a matching message arrives within the timeout
+)
+
+// sends reports whether the step sends a request
+func (s ScenarioStep) sends() bool {
+	return s.Kind != StepReceive && s.Kind != StepExpectNone
+}
+
+// StepRetry reruns a failed scenario step, doubling the delay after every attempt
+type StepRetry struct {
+	Attempts     int `json:"attempts"`                 // Reruns after the first failure
+	BackoffMs    int `json:"backoff_ms,omitempty"`     // Delay before the first rerun
+	MaxBackoffMs int `json:"max_backoff_ms,omitempty"` // Upper bound of the delay; zero leaves it unbounded
 }
 
 type Assertion struct {
-	Field  string `json:"field"`
-	Expect string `json:"expect,omitempty"`
-	Regex  string `json:"regex,omitempty"`
-	Exists *bool  `json:"exists,omitempty"`
+	Field     string    `json:"field"`
+	Expect    string    `json:"expect,omitempty"`
+	Regex     string    `json:"regex,omitempty"`
+	Exists    *bool     `json:"exists,omitempty"`
+	NotEquals string    `json:"not_equals,omitempty"`
+	In        []Operand `json:"in,omitempty"`      // The value must equal one of these
+	Gt        Operand   `json:"gt,omitempty"`      // Numeric lower bound, exclusive
+	Lt        Operand   `json:"lt,omitempty"`      // Numeric upper bound, exclusive
+	Between   []Operand `json:"between,omitempty"` // Numeric [min, max], inclusive
+}
+
+// Operand is a value an assertion compares against, written as a JSON string or
+// number. It may reference {{context.X}}, {{data.X}} and request fields as {{req.N}}.
+type Operand string
+
+func (o *Operand) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*o = Operand(s)
+		return nil
+	}
+	if _, err := strconv.ParseFloat(string(b), 64); err != nil {
+		return fmt.Errorf("assertion operand must be a string or number, got %s", b)
+	}
+	*o = Operand(b)
+	return nil
 }
 
 type ConfigItem struct {
-	Type           string                 `json:"type"`
-	Name           string                 `json:"name"`
-	Description    string                 `json:"description"`
-	Spec           string                 `json:"spec,omitempty"`
-	SpecFile       string                 `json:"spec_file,omitempty"`
-	Fields         json.RawMessage        `json:"fields,omitempty"`
-	Dataset        []map[int]string       `json:"dataset,omitempty"`
-	Data           []map[string]string    `json:"data,omitempty"`
-	DatasetName    string                 `json:"dataset_name,omitempty"`
-	Steps          []ScenarioStep         `json:"steps,omitempty"`
-	MatchFields    map[string]interface{} `json:"match_fields,omitempty"`
-	RequiredFields []string               `json:"required_fields,omitempty"`
-	EchoFields     []int                  `json:"echo_fields,omitempty"`
-	ResponseMTI    string                 `json:"response_mti,omitempty"`
-	ResponseFields map[string]interface{} `json:"response_fields,omitempty"`
-	DelayMs        int                    `json:"delay_ms,omitempty"`
-	LatencyMs      int                    `json:"latency_ms,omitempty"`
-	JitterMs       int                    `json:"jitter_ms,omitempty"`
-	DropConnection bool                   `json:"drop_connection,omitempty"`
+	Type           string                    `json:"type"`
+	Name           string                    `json:"name"`
+	Description    string                    `json:"description"`
+	Spec           string                    `json:"spec,omitempty"`
+	SpecFile       string                    `json:"spec_file,omitempty"`
+	Fields         json.RawMessage           `json:"fields,omitempty"`
+	Dataset        []map[int]string          `json:"dataset,omitempty"`
+	Data           []map[string]string       `json:"data,omitempty"`
+	DatasetName    string                    `json:"dataset_name,omitempty"`
+	Steps          []ScenarioStep            `json:"steps,omitempty"`
+	Finally        []ScenarioStep            `json:"finally,omitempty"`
+	Tags           []string                  `json:"tags,omitempty"`
+	Mock           *ScenarioMock             `json:"mock,omitempty"`
+	Setup          []ScenarioStep            `json:"setup,omitempty"`
+	Teardown       []ScenarioStep            `json:"teardown,omitempty"`
+	MatchFields    map[string]interface{}    `json:"match_fields,omitempty"`
+	MatchExpr      string                    `json:"match_expr,omitempty"`
+	MatchConn      map[string]interface{}    `json:"match_conn,omitempty"`
+	RequiredFields []string                  `json:"required_fields,omitempty"`
+	EchoFields     []int                     `json:"echo_fields,omitempty"`
+	ResponseMTI    string                    `json:"response_mti,omitempty"`
+	ResponseFields map[string]interface{}    `json:"response_fields,omitempty"`
+	ResponseHeader map[string]string         `json:"response_header,omitempty"`
+	DelayMs        int                       `json:"delay_ms,omitempty"`
+	LatencyMs      int                       `json:"latency_ms,omitempty"`
+	JitterMs       int                       `json:"jitter_ms,omitempty"`
+	DropConnection bool                      `json:"drop_connection,omitempty"`
+	NoResponse     bool                      `json:"no_response,omitempty"`
+	Fault          string                    `json:"fault,omitempty"`
+	StallMs        int                       `json:"stall_ms,omitempty"`
+	Priority       int                       `json:"priority,omitempty"`
+	Disabled       bool                      `json:"disabled,omitempty"`
+	Responses      []cfg.MockResponseVariant `json:"responses,omitempty"`
+	State          *cfg.MockStateConfig      `json:"state,omitempty"`
+	IntervalMs     int                       `json:"interval_ms,omitempty"`
+	InitialDelayMs int                       `json:"initial_delay_ms,omitempty"`
+	Count          int                       `json:"count,omitempty"`
+	Listener       string                    `json:"listener,omitempty"`
+	Port           string                    `json:"port,omitempty"`
+	Header         string                    `json:"header,omitempty"`
+	MinMessageSize int                       `json:"min_message_size,omitempty"`
+	MaxMessageSize int                       `json:"max_message_size,omitempty"`
+	OnInvalidFrame string                    `json:"on_invalid_frame,omitempty"`
+
+	LengthBytes          int    `json:"length_bytes,omitempty"`
+	LengthEncoding       string `json:"length_encoding,omitempty"`
+	LengthIncludesHeader bool   `json:"length_includes_header,omitempty"`
+	Prefix               string `json:"prefix,omitempty"`
+	PrefixHex            string `json:"prefix_hex,omitempty"`
+	TPDU                 string `json:"tpdu,omitempty"`
+	Trailer              string `json:"trailer,omitempty"`
 }
 
 // TransactionState stores information about transaction state
@@ -101,7 +200,10 @@ type TransactionCollection struct {
 	datasets     map[string]*Dataset
 	scenarios    map[string]*Scenario
 
-	mockRoutes []cfg.MockRouteConfig
+	mockRoutes    []cfg.MockRouteConfig
+	mockOutbound  []cfg.MockOutboundConfig
+	mockListeners []cfg.MockListenerConfig
+	framings      []cfg.FramingConfig
 
 	// State management
 	state         TransactionState
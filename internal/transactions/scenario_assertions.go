@@ -0,0 +1,163 @@
+package transactions
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"jiso/internal/server"
+
+	"github.com/moov-io/iso8583"
+)
+
+var requestRegex = regexp.MustCompile(`\{\{\s*req\.([\w.]+)\s*\}\}`)
+
+// needsValue reports whether the assertion fails when its field is absent. Only
+// exists and not_equals can pass without a value.
+func (a Assertion) needsValue() bool {
+	return a.Expect != "" || a.Regex != "" || len(a.In) > 0 || a.Gt != "" || a.Lt != "" || len(a.Between) > 0
+}
+
+// String lists the assertion's value rules, such as "expect=00 in=[00 10]"
+func (a Assertion) String() string {
+	var rules []string
+	add := func(name, v string) {
+		if v != "" {
+			rules = append(rules, name+"="+v)
+		}
+	}
+	join := func(ops []Operand) string {
+		if len(ops) == 0 {
+			return ""
+		}
+		parts := make([]string, len(ops))
+		for i, o := range ops {
+			parts[i] = string(o)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	}
+	add("expect", a.Expect)
+	add("regex", a.Regex)
+	add("not_equals", a.NotEquals)
+	add("in", join(a.In))
+	add("gt", string(a.Gt))
+	add("lt", string(a.Lt))
+	add("between", join(a.Between))
+	return strings.Join(rules, " ")
+}
+
+// resolveOperand interpolates an assertion operand: dataset and context variables,
+// then request fields referenced as {{req.N}} or {{req.55.9F26}}. A request field
+// that is not set resolves to empty; without a request the reference is kept.
+func (sr *ScenarioRunner) resolveOperand(val string, datasetName string, req *iso8583.Message) string {
+	val = sr.injectVariables(val, datasetName)
+	if req == nil {
+		return val
+	}
+	return requestRegex.ReplaceAllStringFunc(val, func(m string) string {
+		v, _ := server.FieldValue(req, requestRegex.FindStringSubmatch(m)[1])
+		return v
+	})
+}
+
+// compareValue applies the assertion's not_equals, in, gt, lt and between rules to
+// a present value, returning the first one that fails
+func (sr *ScenarioRunner) compareValue(a Assertion, value stepValue, datasetName string, req *iso8583.Message) *ValidationError {
+	resolve := func(o Operand) string {
+		return sr.resolveOperand(string(o), datasetName, req)
+	}
+	failure := func(expected, rule string) *ValidationError {
+		return &ValidationError{
+			Field:    a.Field,
+			Expected: expected,
+			Actual:   value.value,
+			Message:  fmt.Sprintf("%s %s assertion failed", value.label, rule),
+		}
+	}
+
+	if a.NotEquals != "" {
+		if other := resolve(Operand(a.NotEquals)); value.value == other {
+			return failure("not "+other, "not_equals")
+		}
+	}
+	if len(a.In) > 0 {
+		allowed := make([]string, len(a.In))
+		for i, o := range a.In {
+			allowed[i] = resolve(o)
+		}
+		if !slices.Contains(allowed, value.value) {
+			return failure("one of "+strings.Join(allowed, ", "), "in")
+		}
+	}
+
+	if a.Gt == "" && a.Lt == "" && len(a.Between) == 0 {
+		return nil
+	}
+	actual, err := parseNumber(value.value)
+	if err != nil {
+		return &ValidationError{Field: a.Field, Actual: value.value, Message: fmt.Sprintf("%s is not numeric", value.label)}
+	}
+	bound := func(rule string, o Operand) (float64, *ValidationError) {
+		v := resolve(o)
+		n, err := parseNumber(v)
+		if err != nil {
+			return 0, &ValidationError{Field: a.Field, Expected: v, Message: fmt.Sprintf("%s operand '%s' is not numeric", rule, v)}
+		}
+		return n, nil
+	}
+
+	if a.Gt != "" {
+		limit, valErr := bound("gt", a.Gt)
+		if valErr != nil {
+			return valErr
+		}
+		if actual <= limit {
+			return failure("> "+resolve(a.Gt), "gt")
+		}
+	}
+	if a.Lt != "" {
+		limit, valErr := bound("lt", a.Lt)
+		if valErr != nil {
+			return valErr
+		}
+		if actual >= limit {
+			return failure("< "+resolve(a.Lt), "lt")
+		}
+	}
+	if len(a.Between) == 2 {
+		low, valErr := bound("between", a.Between[0])
+		if valErr != nil {
+			return valErr
+		}
+		high, valErr := bound("between", a.Between[1])
+		if valErr != nil {
+			return valErr
+		}
+		if actual < low || actual > high {
+			return failure(fmt.Sprintf("between %s and %s", resolve(a.Between[0]), resolve(a.Between[1])), "between")
+		}
+	}
+	return nil
+}
+
+// parseNumber parses a field value or operand as a number; amounts keep their
+// leading zeros, so "000000010000" is 10000
+func parseNumber(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
+
+// checkLatency fails the step when its response took longer than max_latency_ms
+func checkLatency(result *StepResult, step ScenarioStep) {
+	if step.MaxLatencyMs <= 0 || result.LatencyMs <= int64(step.MaxLatencyMs) {
+		return
+	}
+	result.Success = false
+	result.ValidationErrors = append(result.ValidationErrors, ValidationError{
+		Field:    "latency",
+		Expected: fmt.Sprintf("<= %d ms", step.MaxLatencyMs),
+		Actual:   fmt.Sprintf("%d ms", result.LatencyMs),
+		Message:  "Response latency assertion failed",
+	})
+}
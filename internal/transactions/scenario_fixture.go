@@ -0,0 +1,111 @@
+package transactions
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	cfg "jiso/internal/config"
+	"jiso/internal/server"
+	"jiso/internal/service"
+	"jiso/internal/utils"
+)
+
+// defaultMockHeader frames a scenario mock that does not name a header
+const defaultMockHeader = "ascii4"
+
+// scenarioMockRoutes returns the routes of a scenario mock: its inline routes followed
+// by the file's mock_route items its use_routes names or globs select
+func (tc *TransactionCollection) scenarioMockRoutes(m *ScenarioMock) ([]cfg.MockRouteConfig, error) {
+	routes := slices.Clone(m.Routes)
+	for _, pattern := range m.UseRoutes {
+		matched := false
+		for _, route := range tc.mockRoutes {
+			ok, err := path.Match(pattern, route.Name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mock route pattern '%s': %w", pattern, err)
+			}
+			if ok || route.Name == pattern {
+				routes = append(routes, route)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no mock route matches '%s'", pattern)
+		}
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("mock has no routes")
+	}
+	return routes, nil
+}
+
+// ScenariosMocked reports whether every named scenario runs against a mock of its own,
+// so that running them needs no connection to the target
+func (tc *TransactionCollection) ScenariosMocked(names []string) bool {
+	for _, name := range names {
+		if s, ok := tc.scenarios[name]; !ok || s.Mock == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// startMock starts the scenario's mock server on an ephemeral port and points the runner
+// at a connection to it. The returned function disconnects, stops the mock and restores
+// the runner's own service.
+func (sr *ScenarioRunner) startMock(m *ScenarioMock) (func(), error) {
+	routes, err := sr.tc.scenarioMockRoutes(m)
+	if err != nil {
+		return nil, err
+	}
+	headerType := m.Header
+	if headerType == "" {
+		headerType = defaultMockHeader
+	}
+	header, err := utils.SelectLength(headerType)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := sr.tc.spec
+	responseTimeout := cfg.GetConfig().GetResponseTimeout()
+	if sr.svc != nil {
+		spec = sr.svc.GetSpec()
+		responseTimeout = sr.svc.GetResponseTimeout()
+	}
+
+	mock := server.NewServer(spec, routes, headerType)
+	if err := mock.Start("0"); err != nil {
+		return nil, fmt.Errorf("starting mock: %w", err)
+	}
+
+	svc, err := service.NewService(
+		"127.0.0.1",
+		mock.GetPort(),
+		"",
+		false,
+		0,
+		cfg.GetConfig().GetConnectTimeout(),
+		cfg.GetConfig().GetTotalConnectTimeout(),
+		responseTimeout,
+	)
+	if err == nil {
+		if spec != nil {
+			svc.SetSpec(spec)
+		}
+		err = svc.Connect(headerType == "NAPS", header)
+	}
+	if err != nil {
+		_ = mock.Stop()
+		return nil, fmt.Errorf("connecting to mock: %w", err)
+	}
+
+	target := sr.svc
+	sr.svc = svc
+	return func() {
+		sr.svc = target
+		_ = svc.Disconnect()
+		_ = mock.Stop()
+	}, nil
+}
@@ -0,0 +1,72 @@
+package transactions
+
+import (
+	"slices"
+	"time"
+
+	"jiso/internal/connection"
+)
+
+const (
+	inboxBuffer = 64  // Inbound messages queued before the runner reads them
+	inboxHeld   = 256 // Unmatched messages kept for later receive steps
+)
+
+// scenarioInbox collects the inbound messages of a running scenario. Messages one
+// step does not match are held, so a later receive step can still take them.
+type scenarioInbox struct {
+	ch   <-chan *connection.InboundMessage
+	held []*connection.InboundMessage
+}
+
+// next returns the first message received at or after since that match accepts,
+// waiting until deadline, or nil when none arrives. The message is consumed.
+func (in *scenarioInbox) next(deadline, since time.Time, match func(*connection.InboundMessage) bool) *connection.InboundMessage {
+	for i, msg := range in.held {
+		if !msg.Received.Before(since) && match(msg) {
+			in.held = append(in.held[:i], in.held[i+1:]...)
+			return msg
+		}
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		select {
+		case msg, ok := <-in.ch:
+			if !ok {
+				return nil
+			}
+			if !msg.Received.Before(since) && match(msg) {
+				return msg
+			}
+			in.hold(msg)
+		case <-timer.C:
+			return nil
+		}
+	}
+}
+
+// hold keeps msg for later steps, dropping the oldest message when full
+func (in *scenarioInbox) hold(msg *connection.InboundMessage) {
+	if len(in.held) >= inboxHeld {
+		in.held = in.held[1:]
+	}
+	in.held = append(in.held, msg)
+}
+
+// scenarioWatchesInbound reports whether a step of the scenario waits for inbound
+// messages beyond the single response of a request
+func scenarioWatchesInbound(s *Scenario) bool {
+	for _, step := range s.allSteps() {
+		if !step.sends() || step.Responses > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// allSteps returns the scenario's setup, main, finally and teardown steps
+func (s *Scenario) allSteps() []ScenarioStep {
+	return slices.Concat(s.Setup, s.Steps, s.Finally, s.Teardown)
+}
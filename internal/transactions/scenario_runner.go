@@ -3,6 +3,7 @@ package transactions
 import (
 	"time"
 
+	"jiso/internal/connection"
 	"jiso/internal/service"
 )
 
@@ -19,11 +20,63 @@ type TestReport struct {
 type StepResult struct {
 	StepName         string            `json:"step_name"`
 	Success          bool              `json:"success"`
+	Skipped          bool              `json:"skipped,omitempty"`              // The step's when condition was false
+	ContinuedOnFail  bool              `json:"continued_on_failure,omitempty"` // Failed without stopping or failing the scenario
+	Finally          bool              `json:"finally,omitempty"`              // A cleanup step of the scenario's finally list
+	Fixture          string            `json:"fixture,omitempty"`              // setup or teardown for the scenario's fixture steps
+	Attempts         int               `json:"attempts,omitempty"`             // Runs of a step with retry
+	Messages         int               `json:"messages,omitempty"`             // Messages received by a receive step or a send step expecting several responses
 	LatencyMs        int64             `json:"latency_ms"`
+	ResponseCode     string            `json:"response_code,omitempty"`
 	RequestPayload   string            `json:"request_payload,omitempty"`
 	ResponsePayload  string            `json:"response_payload,omitempty"`
 	Error            string            `json:"error,omitempty"`
 	ValidationErrors []ValidationError `json:"validation_errors,omitempty"`
+	Reversal         *ReversalReport   `json:"reversal,omitempty"`
+}
+
+// Fixture phases a step result can belong to
+const (
+	FixtureSetup    = "setup"
+	FixtureTeardown = "teardown"
+)
+
+// ReversalReport is the outcome of the automatic reversal of a step that got no response
+type ReversalReport struct {
+	MTI             string `json:"mti"`
+	Attempts        int    `json:"attempts"`
+	Acknowledged    bool   `json:"acknowledged"`
+	ResponseCode    string `json:"response_code,omitempty"`
+	Error           string `json:"error,omitempty"`
+	RequestPayload  string `json:"request_payload,omitempty"`
+	ResponsePayload string `json:"response_payload,omitempty"`
+}
+
+// newReversalReport reports a reversal outcome, or returns nil when none was sent
+func newReversalReport(r *connection.ReversalResult) *ReversalReport {
+	if r == nil {
+		return nil
+	}
+	report := &ReversalReport{
+		MTI:          r.MTI,
+		Attempts:     r.Attempts,
+		Acknowledged: r.Acknowledged(),
+		ResponseCode: r.ResponseCode,
+	}
+	if r.Err != nil {
+		report.Error = r.Err.Error()
+	}
+	if r.Request != nil {
+		if packed, err := r.Request.Pack(); err == nil {
+			report.RequestPayload = string(packed)
+		}
+	}
+	if r.Response != nil {
+		if packed, err := r.Response.Pack(); err == nil {
+			report.ResponsePayload = string(packed)
+		}
+	}
+	return report
 }
 
 type ValidationError struct {
@@ -38,6 +91,13 @@ type ScenarioRunner struct {
 	tc               *TransactionCollection
 	sessionState     map[string]string
 	selectedDatasets map[string]map[string]string
+
+	// inbox holds the inbound messages no request took while a scenario with receive,
+	// expect_none or multi-response steps runs
+	inbox *scenarioInbox
+
+	// exec runs a single step; nil selects runStep
+	exec func(step ScenarioStep, datasetName string, row map[string]string) StepResult
 }
 
 func NewScenarioRunner(svc *service.Service, tc *TransactionCollection) *ScenarioRunner {
@@ -63,17 +123,80 @@ func (sr *ScenarioRunner) RunScenario(name string) (*TestReport, error) {
 		Steps:        make([]StepResult, 0, len(scenario.Steps)),
 	}
 
+	// A scenario with its own mock runs against it instead of the target
+	if scenario.Mock != nil {
+		stop, err := sr.startMock(scenario.Mock)
+		if err != nil {
+			endTime := time.Now()
+			report.Steps = append(report.Steps, StepResult{StepName: "Start mock", Fixture: FixtureSetup, Error: err.Error()})
+			report.EndTime = endTime
+			report.DurationMs = endTime.Sub(startTime).Milliseconds()
+			return report, nil
+		}
+		defer stop()
+	}
+
+	if sr.svc != nil && scenarioWatchesInbound(scenario) {
+		ch, cancel := sr.svc.SubscribeInbound(inboxBuffer)
+		sr.inbox = &scenarioInbox{ch: ch}
+		defer func() {
+			cancel()
+			sr.inbox = nil
+		}()
+	}
+
+	flow := &stepFlow{}
 	allSuccess := true
+
+	// A failing setup step skips the steps; finally and teardown steps still run
+	setupOK := true
+	for _, step := range scenario.Setup {
+		results, ok := sr.runFlowStep(step, scenario.DatasetName, flow)
+		for i := range results {
+			results[i].Fixture = FixtureSetup
+		}
+		report.Steps = append(report.Steps, results...)
+		if !ok {
+			allSuccess, setupOK = false, false
+			break
+		}
+	}
+
 	for _, step := range scenario.Steps {
-		res := sr.runStep(step, scenario.DatasetName)
-		report.Steps = append(report.Steps, res)
+		if !setupOK {
+			break
+		}
+		results, ok := sr.runFlowStep(step, scenario.DatasetName, flow)
+		report.Steps = append(report.Steps, results...)
 
-		if !res.Success {
+		if !ok {
 			allSuccess = false
 			break // Fail-fast on scenario assertion errors
 		}
 	}
 
+	// Cleanup steps always run, and a failing one does not stop the others
+	for _, step := range scenario.Finally {
+		results, ok := sr.runFlowStep(step, scenario.DatasetName, flow)
+		for i := range results {
+			results[i].Finally = true
+		}
+		report.Steps = append(report.Steps, results...)
+		if !ok {
+			allSuccess = false
+		}
+	}
+	for _, step := range scenario.Teardown {
+		results, ok := sr.runFlowStep(step, scenario.DatasetName, flow)
+		for i := range results {
+			results[i].Fixture = FixtureTeardown
+		}
+		report.Steps = append(report.Steps, results...)
+		if !ok {
+			allSuccess = false
+		}
+	}
+
 	endTime := time.Now()
 	report.EndTime = endTime
 	report.DurationMs = endTime.Sub(startTime).Milliseconds()
@@ -0,0 +1,133 @@
+package transactions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"jiso/internal/expr"
+)
+
+// stepFlow carries what a step's when condition can see of the steps before it
+type stepFlow struct {
+	rc     string // DE39 of the last response received
+	failed bool   // Whether a step has failed the scenario
+}
+
+// runFlowStep runs a scenario step with its control flow: once per foreach row and
+// repeat, skipped when its when condition is false, and rerun on failure with retry.
+// It reports whether the scenario may go on.
+func (sr *ScenarioRunner) runFlowStep(step ScenarioStep, scenarioDatasetName string, flow *stepFlow) ([]StepResult, bool) {
+	rows := []map[string]string{nil}
+	datasetName := scenarioDatasetName
+	if step.Foreach != "" {
+		dataset, err := sr.tc.GetDataset(step.Foreach)
+		if err != nil {
+			flow.failed = flow.failed || !step.ContinueOnFailure
+			res := StepResult{StepName: step.Name, Error: err.Error(), ContinuedOnFail: step.ContinueOnFailure}
+			return []StepResult{res}, step.ContinueOnFailure
+		}
+		rows = dataset.Data
+		datasetName = step.Foreach
+	}
+	repeat := max(step.Repeat, 1)
+	iterations := len(rows) * repeat
+
+	results := make([]StepResult, 0, iterations)
+	n := 0
+	for _, row := range rows {
+		for i := 0; i < repeat; i++ {
+			n++
+			res := sr.runIteration(step, datasetName, row, flow)
+			if iterations > 1 {
+				res.StepName = fmt.Sprintf("%s #%d", step.Name, n)
+			}
+			if res.Skipped {
+				results = append(results, res)
+				continue
+			}
+			flow.rc = res.ResponseCode
+			if !res.Success {
+				if step.ContinueOnFailure {
+					res.ContinuedOnFail = true
+				} else {
+					flow.failed = true
+					return append(results, res), false
+				}
+			}
+			results = append(results, res)
+		}
+	}
+	return results, true
+}
+
+// runIteration runs one iteration of a step: it checks the when condition, then
+// runs the step until it passes or its retries are used up
+func (sr *ScenarioRunner) runIteration(step ScenarioStep, datasetName string, row map[string]string, flow *stepFlow) StepResult {
+	if step.When != "" {
+		run, err := sr.evalWhen(step.When, row, flow)
+		if err != nil {
+			return StepResult{StepName: step.Name, Error: err.Error()}
+		}
+		if !run {
+			return StepResult{StepName: step.Name, Success: true, Skipped: true}
+		}
+	}
+
+	if step.Retry == nil {
+		return sr.execute(step, datasetName, row)
+	}
+	backoff := time.Duration(step.Retry.BackoffMs) * time.Millisecond
+	maxBackoff := time.Duration(step.Retry.MaxBackoffMs) * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		res := sr.execute(step, datasetName, row)
+		res.Attempts = attempt
+		if res.Success || attempt > step.Retry.Attempts {
+			return res
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if maxBackoff > 0 && backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// execute runs a single step with the runner's step executor
+func (sr *ScenarioRunner) execute(step ScenarioStep, datasetName string, row map[string]string) StepResult {
+	if sr.exec != nil {
+		return sr.exec(step, datasetName, row)
+	}
+	return sr.runStep(step, datasetName, row)
+}
+
+// evalWhen evaluates a step's when condition. It sees rc (DE39 of the last response),
+// failed (whether an earlier step failed the scenario), context.* and the foreach
+// row as data.*. The condition may be wrapped in {{ }}.
+func (sr *ScenarioRunner) evalWhen(cond string, row map[string]string, flow *stepFlow) (bool, error) {
+	if inner, ok := expr.Unwrap(cond); ok {
+		cond = inner
+	}
+	env := expr.EnvFunc(func(name string) (interface{}, bool) {
+		switch name {
+		case "rc":
+			return flow.rc, true
+		case "failed":
+			return flow.failed, true
+		}
+		if key, ok := strings.CutPrefix(name, "context."); ok {
+			v, found := sr.sessionState[key]
+			return v, found
+		}
+		if key, ok := strings.CutPrefix(name, "data."); ok {
+			v, found := row[key]
+			return v, found
+		}
+		return nil, false
+	})
+	v, err := expr.Eval(cond, env)
+	if err != nil {
+		return false, fmt.Errorf("when condition: %w", err)
+	}
+	return expr.Truthy(v), nil
+}
@@ -1,12 +1,17 @@
 package transactions
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"jiso/internal/connection"
+	"jiso/internal/server"
+
 	"github.com/moov-io/iso8583"
 	"github.com/moov-io/iso8583/field"
 )
@@ -17,13 +22,112 @@ var (
 	contextRegex = regexp.MustCompile(`\{\{\s*context\.(\w+)\s*\}\}`)
 )
 
-func (sr *ScenarioRunner) runStep(step ScenarioStep, scenarioDatasetName string) StepResult {
+// runStep runs one step by its kind: it sends a request and checks its responses, or
+// waits for inbound messages. A non-nil row is the foreach dataset row that data.*
+// variables resolve against; otherwise a random row is picked.
+func (sr *ScenarioRunner) runStep(step ScenarioStep, scenarioDatasetName string, row map[string]string) StepResult {
+	datasetName := sr.selectStepDataset(step, scenarioDatasetName, row)
+
+	result := StepResult{
+		StepName: step.Name,
+		Success:  true,
+	}
+
+	if sr.svc == nil || !sr.svc.IsConnected() {
+		result.Success = false
+		result.Error = "connection is offline"
+		return result
+	}
+
+	if !step.sends() {
+		return sr.runInboundStep(step, datasetName, result)
+	}
+
+	reqMsg, headerFields, err := sr.composeStep(step, datasetName)
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+		return result
+	}
+
+	// Populate Request Payload for reporting
+	reqPacked, err := reqMsg.Pack()
+	if err == nil {
+		result.RequestPayload = string(reqPacked)
+	}
+
+	// 4. Send over network and wait for response
+	startTime := time.Now()
+	if step.Kind == StepSendOnly {
+		_, err := sr.svc.BackgroundSend(reqMsg)
+		result.LatencyMs = time.Since(startTime).Milliseconds()
+		if err != nil {
+			result.Success = false
+			result.Error = fmt.Errorf("network send failed: %w", err).Error()
+		}
+		return result
+	}
+	respMsg, respHeader, err := sr.svc.SendWithHeader(reqMsg, headerFields)
+	result.LatencyMs = time.Since(startTime).Milliseconds()
+
+	if err != nil {
+		result.Success = false
+		result.Error = fmt.Errorf("network send failed: %w", err).Error()
+		if errors.Is(err, connection.ErrResponseTimeout) {
+			result.Reversal = newReversalReport(sr.svc.Reverse(reqMsg))
+		}
+		return result
+	}
+
+	if respMsg == nil {
+		result.Success = false
+		result.Error = "received empty response"
+		return result
+	}
+
+	// 5. Assert validation rules
+	sr.checkMessage(&result, step, respMsg, respHeader, reqMsg, datasetName)
+	checkLatency(&result, step)
+
+	// Further responses to the same request arrive as inbound messages with its correlation key
+	if step.Responses > 1 && sr.inbox != nil {
+		result.Messages = 1
+		key := sr.svc.GetCorrelationKey()
+		reqKey := key.Of(reqMsg)
+		deadline := time.Now().Add(sr.stepTimeout(step))
+		for result.Messages < step.Responses {
+			in := sr.inbox.next(deadline, startTime, func(in *connection.InboundMessage) bool {
+				return key.Of(in.Message) == reqKey
+			})
+			if in == nil {
+				result.Success = false
+				result.Error = fmt.Sprintf("received %d of %d responses", result.Messages, step.Responses)
+				return result
+			}
+			result.Messages++
+			respMsg, respHeader = in.Message, in.Header
+			sr.checkMessage(&result, step, respMsg, respHeader, reqMsg, datasetName)
+		}
+	}
+
+	// 6. Extract fields if step succeeded
+	sr.extractValues(result, step, respMsg, respHeader)
+	return result
+}
+
+// selectStepDataset resolves the dataset the step's data.* variables read and selects
+// its row: the foreach row when there is one, otherwise a random row
+func (sr *ScenarioRunner) selectStepDataset(step ScenarioStep, scenarioDatasetName string, row map[string]string) string {
 	// Re-initialize selectedDatasets map on every step run to ensure random selection per step
 	sr.selectedDatasets = make(map[string]map[string]string)
 
 	// Resolve dataset name for this step
 	var datasetName string
-	if step.UseTransactionId != "" {
+	if row != nil {
+		datasetName = scenarioDatasetName
+		sr.selectedDatasets[datasetName] = row
+	}
+	if datasetName == "" && step.UseTransactionId != "" {
 		if t, err := sr.tc.findTransaction(step.UseTransactionId); err == nil {
 			datasetName = t.DatasetName
 		}
@@ -42,34 +146,26 @@ func (sr *ScenarioRunner) runStep(step ScenarioStep, scenarioDatasetName string)
 		}
 	}
 
-	if datasetName != "" {
+	if datasetName != "" && row == nil {
 		dataset, err := sr.tc.GetDataset(datasetName)
 		if err == nil && len(dataset.Data) > 0 {
 			randomIndex := rand.Intn(len(dataset.Data))
 			sr.selectedDatasets[datasetName] = dataset.Data[randomIndex]
 		}
 	}
+	return datasetName
+}
 
-	result := StepResult{
-		StepName: step.Name,
-		Success:  true,
-	}
-
-	if sr.svc == nil || !sr.svc.IsConnected() {
-		result.Success = false
-		result.Error = "connection is offline"
-		return result
-	}
-
+// composeStep builds the step's request from its transaction template and field
+// overrides, returning the message and the header fields to send it with
+func (sr *ScenarioRunner) composeStep(step ScenarioStep, datasetName string) (*iso8583.Message, map[string]string, error) {
 	// 1. Compose base request message
 	var msg *iso8583.Message
 	if step.UseTransactionId != "" {
 		var err error
 		msg, err = sr.tc.ComposeRaw(step.UseTransactionId)
 		if err != nil {
-			result.Success = false
-			result.Error = fmt.Errorf("failed to compose template '%s': %w", step.UseTransactionId, err).Error()
-			return result
+			return nil, nil, fmt.Errorf("failed to compose template '%s': %w", step.UseTransactionId, err)
 		}
 	} else {
 		msg = iso8583.NewMessage(sr.svc.GetSpec())
@@ -91,9 +187,15 @@ func (sr *ScenarioRunner) runStep(step ScenarioStep, scenarioDatasetName string)
 	}
 
 	reqMsg := iso8583.NewMessage(sr.svc.GetSpec())
+	headerFields := make(map[string]string)
 
-	// 3. Interpolate variables, resolve auto fields, and apply to request message
+	// 3. Interpolate variables, resolve auto fields, and apply to request message.
+	// header.<name> keys set fields of the message header instead.
 	for k, v := range mergedFields {
+		if name, ok := strings.CutPrefix(k, "header."); ok {
+			headerFields[name] = sr.injectVariables(fmt.Sprintf("%v", v), datasetName)
+			continue
+		}
 		var fieldID int
 		if _, err := fmt.Sscanf(k, "%d", &fieldID); err != nil {
 			continue
@@ -115,102 +217,136 @@ func (sr *ScenarioRunner) runStep(step ScenarioStep, scenarioDatasetName string)
 			_ = reqMsg.Field(fieldID, fmt.Sprintf("%v", val))
 		}
 	}
+	return reqMsg, headerFields, nil
+}
 
-	// Populate Request Payload for reporting
-	reqPacked, err := reqMsg.Pack()
-	if err == nil {
-		result.RequestPayload = string(reqPacked)
+// runInboundStep runs a receive or expect_none step. A receive step takes the first
+// matching message, including one that arrived before the step started, and checks it
+// like a response. An expect_none step fails when a matching message arrives while it waits.
+func (sr *ScenarioRunner) runInboundStep(step ScenarioStep, datasetName string, result StepResult) StepResult {
+	if sr.inbox == nil {
+		result.Success = false
+		result.Error = "inbound messages are not being watched"
+		return result
+	}
+
+	criteria := make(map[string]interface{}, len(step.Match))
+	for k, v := range step.Match {
+		if s, ok := v.(string); ok {
+			v = sr.injectVariables(s, datasetName)
+		}
+		criteria[k] = v
+	}
+	match := func(in *connection.InboundMessage) bool {
+		return server.MatchMessage(in.Message, in.Header, criteria)
 	}
 
-	// 4. Send over network and wait for response
 	startTime := time.Now()
-	respMsg, err := sr.svc.Send(reqMsg)
+	timeout := sr.stepTimeout(step)
+	since := time.Time{}
+	if step.Kind == StepExpectNone {
+		since = startTime
+	}
+	in := sr.inbox.next(startTime.Add(timeout), since, match)
 	result.LatencyMs = time.Since(startTime).Milliseconds()
 
-	if err != nil {
-		result.Success = false
-		result.Error = fmt.Errorf("network send failed: %w", err).Error()
+	if step.Kind == StepExpectNone {
+		if in != nil {
+			mti, _ := in.Message.GetMTI()
+			result.Success = false
+			result.Messages = 1
+			result.Error = fmt.Sprintf("unexpected %s message received within %s", mti, timeout)
+			if packed, err := in.Message.Pack(); err == nil {
+				result.ResponsePayload = string(packed)
+			}
+		}
 		return result
 	}
 
-	if respMsg == nil {
+	if in == nil {
 		result.Success = false
-		result.Error = "received empty response"
+		result.Error = fmt.Sprintf("no matching message received within %s", timeout)
 		return result
 	}
+	result.Messages = 1
+	sr.checkMessage(&result, step, in.Message, in.Header, nil, datasetName)
+	checkLatency(&result, step)
+	sr.extractValues(result, step, in.Message, in.Header)
+	return result
+}
+
+// stepTimeout returns how long the step waits for inbound messages
+func (sr *ScenarioRunner) stepTimeout(step ScenarioStep) time.Duration {
+	if step.TimeoutMs > 0 {
+		return time.Duration(step.TimeoutMs) * time.Millisecond
+	}
+	return sr.svc.GetResponseTimeout()
+}
 
+// checkMessage records a received message in result and asserts the step's
+// validation rules on it. reqMsg is the step's request, nil for a receive step.
+func (sr *ScenarioRunner) checkMessage(result *StepResult, step ScenarioStep, respMsg *iso8583.Message, respHeader map[string]string, reqMsg *iso8583.Message, datasetName string) {
 	// Populate Response Payload for reporting
 	respPacked, err := respMsg.Pack()
 	if err == nil {
 		result.ResponsePayload = string(respPacked)
 	}
+	result.ResponseCode, _ = respMsg.GetString(39)
 
-	// 5. Assert validation rules
 	for _, assertion := range step.Validate {
-		var fieldID int
-		if _, err := fmt.Sscanf(assertion.Field, "%d", &fieldID); err != nil {
+		value, err := responseValue(respMsg, respHeader, assertion.Field)
+		if err != nil {
 			result.Success = false
 			valErr := ValidationError{
 				Field:   assertion.Field,
-				Message: fmt.Sprintf("invalid field format: %s", assertion.Field),
+				Message: err.Error(),
 			}
 			result.ValidationErrors = append(result.ValidationErrors, valErr)
 			continue
 		}
 
-		fieldObj := respMsg.GetField(fieldID)
-
 		// Check existence assertion
 		if assertion.Exists != nil {
-			exists := hasValue(fieldObj)
+			exists := value.present && value.value != ""
 			if exists != *assertion.Exists {
 				result.Success = false
 				valErr := ValidationError{
 					Field:    assertion.Field,
 					Expected: fmt.Sprintf("exists=%t", *assertion.Exists),
 					Actual:   fmt.Sprintf("exists=%t", exists),
-					Message:  fmt.Sprintf("Field %d existence assertion failed", fieldID),
+					Message:  fmt.Sprintf("%s existence assertion failed", value.label),
 				}
 				result.ValidationErrors = append(result.ValidationErrors, valErr)
 				continue
 			}
 		}
 
-		if fieldObj == nil {
-			if assertion.Expect != "" || assertion.Regex != "" {
+		if !value.present {
+			if assertion.needsValue() {
 				result.Success = false
 				valErr := ValidationError{
 					Field:    assertion.Field,
-					Expected: fmt.Sprintf("expect=%s regex=%s", assertion.Expect, assertion.Regex),
+					Expected: assertion.String(),
 					Actual:   "nil",
-					Message:  fmt.Sprintf("Field %d does not exist in response", fieldID),
+					Message:  fmt.Sprintf("%s does not exist in response", value.label),
 				}
 				result.ValidationErrors = append(result.ValidationErrors, valErr)
 			}
 			continue
 		}
 
-		actualValue, err := fieldObj.String()
-		if err != nil {
-			result.Success = false
-			valErr := ValidationError{
-				Field:   assertion.Field,
-				Message: fmt.Sprintf("failed to get string value of field %d: %v", fieldID, err),
-			}
-			result.ValidationErrors = append(result.ValidationErrors, valErr)
-			continue
-		}
+		actualValue := value.value
 
 		// Exact match assertion
 		if assertion.Expect != "" {
-			expectedInterp := sr.injectVariables(assertion.Expect, datasetName)
+			expectedInterp := sr.resolveOperand(assertion.Expect, datasetName, reqMsg)
 			if actualValue != expectedInterp {
 				result.Success = false
 				valErr := ValidationError{
 					Field:    assertion.Field,
 					Expected: expectedInterp,
 					Actual:   actualValue,
-					Message:  fmt.Sprintf("Field %d exact match assertion failed", fieldID),
+					Message:  fmt.Sprintf("%s exact match assertion failed", value.label),
 				}
 				result.ValidationErrors = append(result.ValidationErrors, valErr)
 				continue
@@ -219,7 +355,7 @@ func (sr *ScenarioRunner) runStep(step ScenarioStep, scenarioDatasetName string)
 
 		// Regex match assertion
 		if assertion.Regex != "" {
-			regexInterp := sr.injectVariables(assertion.Regex, datasetName)
+			regexInterp := sr.resolveOperand(assertion.Regex, datasetName, reqMsg)
 			re, err := regexp.Compile(regexInterp)
 			if err != nil {
 				result.Success = false
@@ -236,32 +372,32 @@ func (sr *ScenarioRunner) runStep(step ScenarioStep, scenarioDatasetName string)
 					Field:    assertion.Field,
 					Expected: fmt.Sprintf("regex(%s)", regexInterp),
 					Actual:   actualValue,
-					Message:  fmt.Sprintf("Field %d regex assertion failed", fieldID),
+					Message:  fmt.Sprintf("%s regex assertion failed", value.label),
 				}
 				result.ValidationErrors = append(result.ValidationErrors, valErr)
 				continue
 			}
 		}
-	}
 
-	// 6. Extract fields if step succeeded
-	if result.Success {
-		for varName, fieldStr := range step.Extract {
-			var fieldID int
-			if _, err := fmt.Sscanf(fieldStr, "%d", &fieldID); err != nil {
-				continue
-			}
-			fieldObj := respMsg.GetField(fieldID)
-			if fieldObj != nil {
-				val, err := fieldObj.String()
-				if err == nil {
-					sr.sessionState[varName] = val
-				}
-			}
+		// Exclusion, list and numeric assertions
+		if valErr := sr.compareValue(assertion, value, datasetName, reqMsg); valErr != nil {
+			result.Success = false
+			result.ValidationErrors = append(result.ValidationErrors, *valErr)
 		}
 	}
+}
 
-	return result
+// extractValues stores the step's extracted values in the session if the step succeeded
+func (sr *ScenarioRunner) extractValues(result StepResult, step ScenarioStep, respMsg *iso8583.Message, respHeader map[string]string) {
+	if !result.Success {
+		return
+	}
+	for varName, fieldStr := range step.Extract {
+		value, err := responseValue(respMsg, respHeader, fieldStr)
+		if err == nil && value.present {
+			sr.sessionState[varName] = value.value
+		}
+	}
 }
 
 func (sr *ScenarioRunner) injectVariables(val string, datasetName string) string {
@@ -322,6 +458,30 @@ func (sr *ScenarioRunner) injectVariables(val string, datasetName string) string
 	return val
 }
 
+// stepValue is a response value resolved for an assertion or extraction
+type stepValue struct {
+	label   string // "Field 39" or "Header field reject_code", for messages
+	value   string
+	present bool
+}
+
+// responseValue resolves a validate/extract key against the response: a field number,
+// a subfield path such as 55.9F26, or header.<name> for a field of the response message header
+func responseValue(resp *iso8583.Message, header map[string]string, key string) (stepValue, error) {
+	if name, ok := strings.CutPrefix(key, "header."); ok {
+		v, present := header[name]
+		return stepValue{label: fmt.Sprintf("Header field %s", name), value: v, present: present}, nil
+	}
+
+	top, _, _ := strings.Cut(key, ".")
+	if _, err := strconv.Atoi(top); err != nil && !strings.EqualFold(key, "mti") {
+		return stepValue{}, fmt.Errorf("invalid field format: %s", key)
+	}
+	v := stepValue{label: fmt.Sprintf("Field %s", key)}
+	v.value, v.present = server.FieldValue(resp, key)
+	return v, nil
+}
+
 func hasValue(f field.Field) bool {
 	if f == nil {
 		return false
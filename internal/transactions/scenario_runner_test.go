@@ -1,13 +1,22 @@
 package transactions
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"regexp"
 	"testing"
+	"time"
+
+	cfg "jiso/internal/config"
+	"jiso/internal/connection"
+	"jiso/internal/server"
+	"jiso/internal/service"
+	"jiso/internal/utils"
 
 	"github.com/moov-io/iso8583"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInjectVariables(t *testing.T) {
@@ -385,3 +394,390 @@ func TestComposeInterpolationRandom(t *testing.T) {
 	// Verify that we saw multiple different randomly-selected values
 	assert.Greater(t, len(seen), 1, "Should select different items randomly across 50 invocations")
 }
+
+// flowRunner runs scenarios with a fake step executor answering each step with the
+// response code returned by respond
+func flowRunner(scenario *Scenario, respond func(step ScenarioStep, row map[string]string) string) (*ScenarioRunner, *[]string) {
+	tc := &TransactionCollection{
+		scenarios: map[string]*Scenario{scenario.Name: scenario},
+		datasets: map[string]*Dataset{
+			"cards": {Name: "cards", Data: []map[string]string{{"pan": "4111"}, {"pan": "5500"}, {"pan": "3700"}}},
+		},
+	}
+	var sent []string
+	sr := NewScenarioRunner(nil, tc)
+	sr.exec = func(step ScenarioStep, _ string, row map[string]string) StepResult {
+		sent = append(sent, step.Name)
+		rc := respond(step, row)
+		return StepResult{StepName: step.Name, Success: rc == "00", ResponseCode: rc}
+	}
+	return sr, &sent
+}
+
+func TestRunScenarioWhenAndContinueOnFailure(t *testing.T) {
+	scenario := &Scenario{
+		Name: "Wrong PIN",
+		Steps: []ScenarioStep{
+			{Name: "Purchase", ContinueOnFailure: true},
+			{Name: "Resend with PIN", When: `rc == "55"`},
+			{Name: "Only on 05", When: `{{ rc == "05" }}`},
+		},
+	}
+	calls := 0
+	sr, sent := flowRunner(scenario, func(ScenarioStep, map[string]string) string {
+		calls++
+		if calls == 1 {
+			return "55"
+		}
+		return "00"
+	})
+
+	report, err := sr.RunScenario("Wrong PIN")
+	assert.NoError(t, err)
+	assert.True(t, report.Success)
+	assert.Equal(t, []string{"Purchase", "Resend with PIN"}, *sent)
+	assert.Len(t, report.Steps, 3)
+	assert.True(t, report.Steps[0].ContinuedOnFail)
+	assert.True(t, report.Steps[2].Skipped)
+}
+
+func TestRunScenarioForeachRepeatAndRetry(t *testing.T) {
+	scenario := &Scenario{
+		Name: "Loops",
+		Steps: []ScenarioStep{
+			{Name: "Card", Foreach: "cards", When: `data.pan != "3700"`},
+			{Name: "Echo", Repeat: 2},
+			{Name: "Flaky", Retry: &StepRetry{Attempts: 2, BackoffMs: 1}},
+		},
+	}
+	flaky := 0
+	sr, sent := flowRunner(scenario, func(step ScenarioStep, row map[string]string) string {
+		if step.Name == "Flaky" {
+			flaky++
+			if flaky < 3 {
+				return "91"
+			}
+		}
+		return "00"
+	})
+
+	report, err := sr.RunScenario("Loops")
+	assert.NoError(t, err)
+	assert.True(t, report.Success)
+	assert.Equal(t, []string{"Card", "Card", "Echo", "Echo", "Flaky", "Flaky", "Flaky"}, *sent)
+	assert.Equal(t, "Card #3", report.Steps[2].StepName)
+	assert.True(t, report.Steps[2].Skipped)
+	assert.Equal(t, 3, report.Steps[len(report.Steps)-1].Attempts)
+}
+
+func TestRunScenarioFinallyRunsAfterFailure(t *testing.T) {
+	scenario := &Scenario{
+		Name: "Cleanup",
+		Steps: []ScenarioStep{
+			{Name: "Purchase"},
+			{Name: "Not reached"},
+		},
+		Finally: []ScenarioStep{
+			{Name: "Reversal", When: "failed"},
+			{Name: "Sign Off"},
+		},
+	}
+	sr, sent := flowRunner(scenario, func(step ScenarioStep, _ map[string]string) string {
+		if step.Name == "Purchase" {
+			return "05"
+		}
+		return "00"
+	})
+
+	report, err := sr.RunScenario("Cleanup")
+	assert.NoError(t, err)
+	assert.False(t, report.Success)
+	assert.Equal(t, []string{"Purchase", "Reversal", "Sign Off"}, *sent)
+	assert.True(t, report.Steps[1].Finally)
+}
+
+func TestValidateStepFlow(t *testing.T) {
+	tc := &TransactionCollection{datasets: map[string]*Dataset{"cards": {Name: "cards"}}}
+	assert.NoError(t, tc.validateStepFlow(ScenarioStep{When: `rc == "55"`, Foreach: "cards", Retry: &StepRetry{Attempts: 1}}))
+	assert.ErrorContains(t, tc.validateStepFlow(ScenarioStep{Foreach: "missing"}), "foreach dataset 'missing' not found")
+	assert.ErrorContains(t, tc.validateStepFlow(ScenarioStep{When: `rc ==`}), "when")
+	assert.Error(t, tc.validateStepFlow(ScenarioStep{Retry: &StepRetry{Attempts: -1}}))
+	assert.NoError(t, tc.validateStepFlow(ScenarioStep{Kind: StepReceive, Match: map[string]interface{}{"0": "0620"}, TimeoutMs: 500}))
+	assert.ErrorContains(t, tc.validateStepFlow(ScenarioStep{Kind: "wait"}), "unknown kind 'wait'")
+	assert.ErrorContains(t, tc.validateStepFlow(ScenarioStep{Kind: StepSendOnly, Responses: 2}), "send steps only")
+	assert.Error(t, tc.validateStepFlow(ScenarioStep{Kind: StepExpectNone, TimeoutMs: -1}))
+}
+
+func TestScenarioInbox(t *testing.T) {
+	ch := make(chan *connection.InboundMessage, 4)
+	inbox := &scenarioInbox{ch: ch}
+	start := time.Now()
+
+	inbound := func(mti string, received time.Time) *connection.InboundMessage {
+		msg := iso8583.NewMessage(iso8583.Spec87)
+		msg.MTI(mti)
+		return &connection.InboundMessage{Message: msg, Received: received}
+	}
+	isMTI := func(mti string) func(*connection.InboundMessage) bool {
+		return func(in *connection.InboundMessage) bool {
+			got, _ := in.Message.GetMTI()
+			return got == mti
+		}
+	}
+
+	ch <- inbound("0800", start.Add(-time.Second))
+	ch <- inbound("0620", start)
+
+	// The 0800 read on the way to the 0620 is held for a later step
+	in := inbox.next(start.Add(time.Second), time.Time{}, isMTI("0620"))
+	require.NotNil(t, in)
+	assert.Len(t, inbox.held, 1)
+
+	// A held message received before since does not count
+	assert.Nil(t, inbox.next(time.Now().Add(20*time.Millisecond), start, isMTI("0800")))
+
+	in = inbox.next(time.Now().Add(20*time.Millisecond), time.Time{}, isMTI("0800"))
+	require.NotNil(t, in)
+	assert.Empty(t, inbox.held)
+}
+
+func TestScenarioWatchesInbound(t *testing.T) {
+	assert.False(t, scenarioWatchesInbound(&Scenario{Steps: []ScenarioStep{{Name: "auth"}, {Name: "advice", Kind: StepSendOnly}}}))
+	assert.True(t, scenarioWatchesInbound(&Scenario{Steps: []ScenarioStep{{Name: "auth", Responses: 2}}}))
+	assert.True(t, scenarioWatchesInbound(&Scenario{Finally: []ScenarioStep{{Name: "quiet", Kind: StepExpectNone}}}))
+}
+
+func TestCheckMessageRicherAssertions(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/example_composed_emv.json")
+	require.NoError(t, err)
+
+	req := iso8583.NewMessage(spec)
+	req.MTI("0200")
+	require.NoError(t, req.Field(4, "000000010000"))
+
+	matcher := server.NewMatcher([]cfg.MockRouteConfig{{
+		Name:        "EMV Approval",
+		MatchFields: map[string]interface{}{"0": "0200"},
+		EchoFields:  []int{4},
+		ResponseMTI: "0210",
+		ResponseFields: map[string]interface{}{
+			"39": "00",
+			"55": map[string]interface{}{"9F26": "11223344"},
+		},
+	}})
+	_, resp, err := matcher.MatchAndCompose(req, spec)
+	require.NoError(t, err)
+
+	runner := &ScenarioRunner{
+		sessionState:     map[string]string{"cryptogram": "11223344"},
+		selectedDatasets: make(map[string]map[string]string),
+	}
+
+	passing := ScenarioStep{Validate: []Assertion{
+		{Field: "4", Expect: "{{req.4}}"},
+		{Field: "4", Gt: "9999", Lt: "10001"},
+		{Field: "4", Between: []Operand{"100", "{{req.4}}"}},
+		{Field: "39", In: []Operand{"00", "10"}},
+		{Field: "39", NotEquals: "05"},
+		{Field: "55.9F26", Expect: "{{context.cryptogram}}"},
+		{Field: "38", NotEquals: "000000"},
+	}}
+	result := StepResult{Success: true}
+	runner.checkMessage(&result, passing, resp, nil, req, "")
+	assert.True(t, result.Success, result.ValidationErrors)
+
+	failing := ScenarioStep{Validate: []Assertion{
+		{Field: "4", Gt: "{{req.4}}"},
+		{Field: "39", In: []Operand{"05", "51"}},
+		{Field: "39", Lt: "{{context.limit}}"},
+		{Field: "38", In: []Operand{"00"}},
+	}}
+	result = StepResult{Success: true}
+	runner.checkMessage(&result, failing, resp, nil, req, "")
+	assert.False(t, result.Success)
+	require.Len(t, result.ValidationErrors, 4)
+	assert.Equal(t, "Field 4 gt assertion failed", result.ValidationErrors[0].Message)
+	assert.Equal(t, "one of 05, 51", result.ValidationErrors[1].Expected)
+	assert.Contains(t, result.ValidationErrors[2].Message, "is not numeric")
+	assert.Equal(t, "nil", result.ValidationErrors[3].Actual)
+}
+
+func TestCheckLatency(t *testing.T) {
+	result := StepResult{Success: true, LatencyMs: 250}
+	checkLatency(&result, ScenarioStep{MaxLatencyMs: 300})
+	assert.True(t, result.Success)
+
+	checkLatency(&result, ScenarioStep{MaxLatencyMs: 200})
+	assert.False(t, result.Success)
+	require.Len(t, result.ValidationErrors, 1)
+	assert.Equal(t, "<= 200 ms", result.ValidationErrors[0].Expected)
+}
+
+func TestAssertionOperands(t *testing.T) {
+	var a Assertion
+	require.NoError(t, json.Unmarshal([]byte(`{"field": "4", "gt": 100, "between": ["{{req.4}}", 5000], "in": ["00", 10]}`), &a))
+	assert.Equal(t, Operand("100"), a.Gt)
+	assert.Equal(t, []Operand{"{{req.4}}", "5000"}, a.Between)
+	assert.Equal(t, []Operand{"00", "10"}, a.In)
+	assert.NoError(t, validateAssertions(ScenarioStep{Validate: []Assertion{a}}))
+	assert.Error(t, json.Unmarshal([]byte(`{"field": "4", "lt": true}`), &Assertion{}))
+
+	assert.ErrorContains(t, validateAssertions(ScenarioStep{Validate: []Assertion{{Field: "4", Between: []Operand{"1"}}}}), "between needs")
+	assert.ErrorContains(t, validateAssertions(ScenarioStep{Validate: []Assertion{{Field: "4", Gt: "ten"}}}), "not numeric")
+	assert.Error(t, validateAssertions(ScenarioStep{MaxLatencyMs: -1}))
+}
+
+func TestSelectScenarios(t *testing.T) {
+	tc := &TransactionCollection{scenarios: map[string]*Scenario{
+		"Purchase Approved": {Name: "Purchase Approved", Tags: []string{"smoke", "purchase"}},
+		"Purchase Declined": {Name: "Purchase Declined", Tags: []string{"purchase"}},
+		"Refund":            {Name: "Refund", Tags: []string{"smoke"}},
+		"Echo":              {Name: "Echo"},
+	}}
+
+	names, err := tc.SelectScenarios(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Echo", "Purchase Approved", "Purchase Declined", "Refund"}, names)
+
+	names, err = tc.SelectScenarios([]string{"Refund", "Purchase*"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Refund", "Purchase Approved", "Purchase Declined"}, names)
+
+	names, err = tc.SelectScenarios(nil, []string{"smoke"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Purchase Approved", "Refund"}, names)
+
+	_, err = tc.SelectScenarios([]string{"Balance*"}, nil)
+	assert.ErrorContains(t, err, "no scenario matches 'Balance*'")
+}
+
+func TestRunSuiteIsolatesScenarios(t *testing.T) {
+	tc := &TransactionCollection{scenarios: map[string]*Scenario{
+		"First":  {Name: "First", Steps: []ScenarioStep{{Name: "auth", Fields: map[string]interface{}{"0": "0100"}}}},
+		"Second": {Name: "Second", Steps: []ScenarioStep{{Name: "auth", Fields: map[string]interface{}{"0": "0100"}}}},
+	}}
+
+	// Without a connection every step fails, but each scenario still gets its report
+	suite := RunSuite(nil, tc, "ci", []string{"First", "Second", "Missing"}, 3)
+	require.Len(t, suite.Scenarios, 3)
+	assert.Equal(t, "First", suite.Scenarios[0].ScenarioName)
+	assert.Equal(t, "Second", suite.Scenarios[1].ScenarioName)
+	assert.Equal(t, "connection is offline", suite.Scenarios[1].Steps[0].Error)
+	assert.Contains(t, suite.Scenarios[2].Steps[0].Error, "scenario not found")
+	assert.False(t, suite.Success)
+	assert.Equal(t, 3, suite.Failed())
+}
+
+func TestSuiteParallelism(t *testing.T) {
+	tc := &TransactionCollection{scenarios: map[string]*Scenario{
+		"A":      {Name: "A"},
+		"B":      {Name: "B"},
+		"Mocked": {Name: "Mocked", Mock: &ScenarioMock{}},
+	}}
+	svc, err := service.NewService("localhost", "8080", "", false, 0, time.Second, time.Second, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, svc.ConfigurePool(2, "round_robin"))
+
+	// One socket per running scenario, unless every scenario brings its own mock
+	assert.Equal(t, 2, SuiteParallelism(svc, tc, []string{"A", "B", "Mocked"}, 8))
+	assert.Equal(t, 1, SuiteParallelism(svc, tc, []string{"A", "B"}, 1))
+	assert.Equal(t, 3, SuiteParallelism(svc, tc, []string{"Mocked", "Mocked", "Mocked"}, 8))
+	assert.Equal(t, 2, SuiteParallelism(nil, tc, []string{"A", "B"}, 8))
+}
+
+func TestRunScenarioSetupAndTeardown(t *testing.T) {
+	scenario := &Scenario{
+		Name:     "Fixtures",
+		Setup:    []ScenarioStep{{Name: "Sign On"}},
+		Steps:    []ScenarioStep{{Name: "Purchase"}},
+		Finally:  []ScenarioStep{{Name: "Reversal"}},
+		Teardown: []ScenarioStep{{Name: "Sign Off"}, {Name: "Cutover"}},
+	}
+	failing := "Purchase"
+	sr, sent := flowRunner(scenario, func(step ScenarioStep, _ map[string]string) string {
+		if step.Name == failing {
+			return "05"
+		}
+		return "00"
+	})
+
+	report, err := sr.RunScenario("Fixtures")
+	assert.NoError(t, err)
+	assert.False(t, report.Success)
+	assert.Equal(t, []string{"Sign On", "Purchase", "Reversal", "Sign Off", "Cutover"}, *sent)
+	assert.Equal(t, FixtureSetup, report.Steps[0].Fixture)
+	assert.Empty(t, report.Steps[1].Fixture)
+	assert.Equal(t, FixtureTeardown, report.Steps[4].Fixture)
+
+	// A failing setup step skips the steps, and every teardown step still runs
+	failing = "Sign On"
+	*sent = nil
+	report, err = sr.RunScenario("Fixtures")
+	assert.NoError(t, err)
+	assert.False(t, report.Success)
+	assert.Equal(t, []string{"Sign On", "Reversal", "Sign Off", "Cutover"}, *sent)
+}
+
+func TestScenarioMockRoutes(t *testing.T) {
+	tc := &TransactionCollection{mockRoutes: []cfg.MockRouteConfig{
+		{Name: "SignOn"}, {Name: "Purchase Approved"}, {Name: "Purchase Declined"},
+	}}
+
+	routes, err := tc.scenarioMockRoutes(&ScenarioMock{
+		Routes:    []cfg.MockRouteConfig{{Name: "Inline"}},
+		UseRoutes: []string{"Purchase*", "SignOn"},
+	})
+	require.NoError(t, err)
+	var names []string
+	for _, r := range routes {
+		names = append(names, r.Name)
+	}
+	assert.Equal(t, []string{"Inline", "Purchase Approved", "Purchase Declined", "SignOn"}, names)
+
+	_, err = tc.scenarioMockRoutes(&ScenarioMock{UseRoutes: []string{"Balance*"}})
+	assert.ErrorContains(t, err, "no mock route matches 'Balance*'")
+	_, err = tc.scenarioMockRoutes(&ScenarioMock{})
+	assert.ErrorContains(t, err, "mock has no routes")
+
+	tc.scenarios = map[string]*Scenario{
+		"Mocked": {Name: "Mocked", Mock: &ScenarioMock{UseRoutes: []string{"SignOn"}}},
+		"Target": {Name: "Target"},
+	}
+	assert.True(t, tc.ScenariosMocked([]string{"Mocked"}))
+	assert.False(t, tc.ScenariosMocked([]string{"Mocked", "Target"}))
+}
+
+func TestRunScenarioAgainstMock(t *testing.T) {
+	spec, err := utils.CreateSpecFromFile("../../specs/spec_bcp.json")
+	require.NoError(t, err)
+
+	signOn := ScenarioStep{
+		Name:     "Sign On",
+		Fields:   map[string]interface{}{"0": "0800", "11": "000001"},
+		Validate: []Assertion{{Field: "39", Expect: "00"}},
+	}
+	tc := &TransactionCollection{
+		spec: spec,
+		scenarios: map[string]*Scenario{"Hermetic": {
+			Name: "Hermetic",
+			Mock: &ScenarioMock{Header: "binary2", Routes: []cfg.MockRouteConfig{{
+				Name:           "SignOn",
+				MatchFields:    map[string]interface{}{"0": "0800"},
+				ResponseMTI:    "0810",
+				EchoFields:     []int{11},
+				ResponseFields: map[string]interface{}{"39": "00"},
+			}}},
+			Setup: []ScenarioStep{signOn},
+			Steps: []ScenarioStep{signOn},
+		}},
+	}
+
+	// No service of its own: the runner connects to the scenario's mock
+	sr := NewScenarioRunner(nil, tc)
+	report, err := sr.RunScenario("Hermetic")
+	require.NoError(t, err)
+	assert.True(t, report.Success, "%+v", report.Steps)
+	require.Len(t, report.Steps, 2)
+	assert.Equal(t, "00", report.Steps[1].ResponseCode)
+	assert.Nil(t, sr.svc)
+}
@@ -0,0 +1,142 @@
+package transactions
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"jiso/internal/service"
+)
+
+// SuiteReport is the outcome of a run of several scenarios
+type SuiteReport struct {
+	Name       string        `json:"name"`
+	Success    bool          `json:"success"`
+	StartTime  time.Time     `json:"start_time"`
+	EndTime    time.Time     `json:"end_time"`
+	DurationMs int64         `json:"duration_ms"`
+	Scenarios  []*TestReport `json:"scenarios"`
+}
+
+// Failed returns the number of scenarios that failed
+func (r *SuiteReport) Failed() int {
+	failed := 0
+	for _, s := range r.Scenarios {
+		if !s.Success {
+			failed++
+		}
+	}
+	return failed
+}
+
+// SelectScenarios returns the scenarios matching any of the patterns, which are names
+// or globs such as "Purchase*", and carrying any of the tags. Scenarios are listed in
+// the order of the patterns, those one glob matches by name. No patterns select every
+// scenario and no tags skip the tag filter. A pattern matching no scenario is an error.
+func (tc *TransactionCollection) SelectScenarios(patterns, tags []string) ([]string, error) {
+	all := tc.ListScenarios()
+	if len(patterns) == 0 {
+		patterns = []string{"*"}
+	}
+
+	var names []string
+	for _, pattern := range patterns {
+		matched := false
+		for _, name := range all {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid scenario pattern '%s': %w", pattern, err)
+			}
+			if !ok && name != pattern {
+				continue
+			}
+			matched = true
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no scenario matches '%s'", pattern)
+		}
+	}
+
+	if len(tags) == 0 {
+		return names, nil
+	}
+	selected := names[:0]
+	for _, name := range names {
+		if slices.ContainsFunc(tc.scenarios[name].Tags, func(tag string) bool { return slices.Contains(tags, tag) }) {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
+
+// SuiteParallelism returns how many of the named scenarios RunSuite runs at a time:
+// parallel, but no more than the pooled sockets of svc, since scenarios running side by
+// side on one socket would share its pending table and inbound messages. Scenarios that
+// all bring their own mock are not limited.
+func SuiteParallelism(svc *service.Service, tc *TransactionCollection, names []string, parallel int) int {
+	workers := min(max(parallel, 1), max(len(names), 1))
+	if svc != nil && !tc.ScenariosMocked(names) {
+		workers = min(workers, svc.PoolSize())
+	}
+	return workers
+}
+
+// RunSuite runs the named scenarios, up to SuiteParallelism of them at a time. Every
+// scenario gets a runner of its own, so context variables extracted by one scenario are
+// never seen by another, and each worker sends over a pooled socket of its own, so
+// fixed STANs or RRNs of concurrent scenarios do not collide. Reports are listed in the
+// order of names.
+func RunSuite(svc *service.Service, tc *TransactionCollection, suiteName string, names []string, parallel int) *SuiteReport {
+	suite := &SuiteReport{
+		Name:      suiteName,
+		StartTime: time.Now(),
+		Scenarios: make([]*TestReport, len(names)),
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < SuiteParallelism(svc, tc, names, parallel); w++ {
+		workerSvc := svc
+		if svc != nil && w < svc.PoolSize() {
+			workerSvc = svc.Socket(w)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				suite.Scenarios[i] = runSuiteScenario(workerSvc, tc, names[i])
+			}
+		}()
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	suite.EndTime = time.Now()
+	suite.DurationMs = suite.EndTime.Sub(suite.StartTime).Milliseconds()
+	suite.Success = suite.Failed() == 0
+	return suite
+}
+
+// runSuiteScenario runs one scenario of a suite, reporting a scenario that could not
+// be started as failed
+func runSuiteScenario(svc *service.Service, tc *TransactionCollection, name string) *TestReport {
+	report, err := NewScenarioRunner(svc, tc).RunScenario(name)
+	if err != nil {
+		now := time.Now()
+		return &TestReport{
+			ScenarioName: name,
+			StartTime:    now,
+			EndTime:      now,
+			Steps:        []StepResult{{StepName: name, Error: err.Error()}},
+		}
+	}
+	return report
+}
@@ -178,3 +178,120 @@ func (suite *TransactionCollectionSuite) TestValidateBinaryFieldHexLengthUsesByt
 	suite.NoError(err)
 	suite.NotNil(tc)
 }
+
+func (suite *TransactionCollectionSuite) TestValidateMockRouteExpressions() {
+	data := []map[string]interface{}{
+		{
+			"type":         "mock_route",
+			"name":         "Broken Expression",
+			"match_fields": map[string]interface{}{"0": "0200"},
+			"response_fields": map[string]interface{}{
+				"39": `{{req.4 > 100000 ? "05"}}`,
+			},
+		},
+	}
+	dataBytes, err := json.Marshal(data)
+	suite.Require().NoError(err)
+	file, err := os.CreateTemp("", "broken_route_expr.json")
+	suite.Require().NoError(err)
+	defer os.Remove(file.Name())
+	_, err = file.Write(dataBytes)
+	suite.Require().NoError(err)
+
+	tc, err := NewTransactionCollection(file.Name(), iso8583.Spec87)
+	suite.Error(err)
+	suite.Contains(err.Error(), "Broken Expression")
+	suite.Nil(tc)
+}
+
+func (suite *TransactionCollectionSuite) TestValidateMockRouteFaultMode() {
+	data := []map[string]interface{}{
+		{
+			"type":         "mock_route",
+			"name":         "Chaos Route",
+			"match_fields": map[string]interface{}{"0": "0200"},
+			"fault":        "explode",
+		},
+	}
+	dataBytes, err := json.Marshal(data)
+	suite.Require().NoError(err)
+	file, err := os.CreateTemp("", "bad_route_fault.json")
+	suite.Require().NoError(err)
+	defer os.Remove(file.Name())
+	_, err = file.Write(dataBytes)
+	suite.Require().NoError(err)
+
+	tc, err := NewTransactionCollection(file.Name(), iso8583.Spec87)
+	suite.Error(err)
+	suite.Contains(err.Error(), "unknown fault mode")
+	suite.Nil(tc)
+}
+
+func (suite *TransactionCollectionSuite) TestValidateMockOutbound() {
+	data := []map[string]interface{}{
+		{
+			"type":        "mock_outbound",
+			"name":        "Echo Test",
+			"fields":      map[string]interface{}{"70": "301"},
+			"interval_ms": 30000,
+		},
+	}
+	dataBytes, err := json.Marshal(data)
+	suite.Require().NoError(err)
+	file, err := os.CreateTemp("", "outbound_no_mti.json")
+	suite.Require().NoError(err)
+	defer os.Remove(file.Name())
+	_, err = file.Write(dataBytes)
+	suite.Require().NoError(err)
+
+	tc, err := NewTransactionCollection(file.Name(), iso8583.Spec87)
+	suite.Error(err)
+	suite.Contains(err.Error(), "MTI")
+	suite.Nil(tc)
+}
+
+func (suite *TransactionCollectionSuite) TestMockListeners() {
+	load := func(data []map[string]interface{}) (*TransactionCollection, error) {
+		dataBytes, err := json.Marshal(data)
+		suite.Require().NoError(err)
+		file, err := os.CreateTemp("", "mock_listeners.json")
+		suite.Require().NoError(err)
+		defer os.Remove(file.Name())
+		_, err = file.Write(dataBytes)
+		suite.Require().NoError(err)
+		return NewTransactionCollection(file.Name(), iso8583.Spec87)
+	}
+	listeners := []map[string]interface{}{
+		{"type": "mock_listener", "name": "visa", "port": "9101", "header": "visa", "spec": "specs/visa.json"},
+		{"type": "mock_listener", "name": "mastercard", "port": "9102", "header": "binary2"},
+	}
+
+	tc, err := load(append(listeners,
+		map[string]interface{}{"type": "mock_route", "name": "VISA Approve", "listener": "visa", "response_fields": map[string]interface{}{"39": "00"}},
+		map[string]interface{}{"type": "mock_route", "name": "Echo", "match_fields": map[string]interface{}{"0": "0800"}},
+	))
+	suite.Require().NoError(err)
+	suite.Require().Len(tc.GetMockListeners(), 2)
+	suite.Equal("visa", tc.GetMockListeners()[0].Header)
+	suite.Equal("specs/visa.json", tc.GetMockListeners()[0].Spec)
+	suite.Equal("visa", tc.GetMockRoutes()[0].Listener)
+
+	_, err = load(append(listeners,
+		map[string]interface{}{"type": "mock_route", "name": "Amex", "listener": "amex"},
+	))
+	suite.Error(err)
+	suite.Contains(err.Error(), "unknown listener 'amex'")
+
+	_, err = load([]map[string]interface{}{
+		{"type": "mock_listener", "name": "visa", "port": "9101"},
+		{"type": "mock_listener", "name": "naps", "port": "9101", "header": "NAPS"},
+	})
+	suite.Error(err)
+	suite.Contains(err.Error(), "both use port 9101")
+
+	_, err = load([]map[string]interface{}{
+		{"type": "mock_listener", "name": "visa", "port": "9101", "header": "binary3"},
+	})
+	suite.Error(err)
+	suite.Contains(err.Error(), "unknown header")
+}
@@ -4,8 +4,13 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
+	cfg "jiso/internal/config"
+	"jiso/internal/expr"
+	"jiso/internal/server"
 	"jiso/internal/utils"
 
 	isofield "github.com/moov-io/iso8583/field"
@@ -16,7 +21,7 @@ func (tc *TransactionCollection) Validate() error {
 		return fmt.Errorf("transaction collection is nil")
 	}
 
-	if len(tc.transactions) == 0 && len(tc.scenarios) == 0 && len(tc.mockRoutes) == 0 {
+	if len(tc.transactions) == 0 && len(tc.scenarios) == 0 && len(tc.mockRoutes) == 0 && len(tc.mockOutbound) == 0 && len(tc.mockListeners) == 0 && len(tc.framings) == 0 {
 		return fmt.Errorf("no transactions, scenarios, or mock routes found in collection")
 	}
 
@@ -71,19 +76,209 @@ func (tc *TransactionCollection) Validate() error {
 		if len(scenario.Steps) == 0 {
 			return fmt.Errorf("scenario '%s' has no steps", name)
 		}
-		for i, step := range scenario.Steps {
+		for i, step := range scenario.allSteps() {
 			if step.Name == "" {
 				return fmt.Errorf("scenario '%s' step %d has empty name", name, i)
 			}
-			if step.UseTransactionId == "" && len(step.Fields) == 0 {
+			if step.sends() && step.UseTransactionId == "" && len(step.Fields) == 0 {
 				return fmt.Errorf("scenario '%s' step '%s' must specify use_transaction_id or fields", name, step.Name)
 			}
+			if err := tc.validateStepFlow(step); err != nil {
+				return fmt.Errorf("scenario '%s' step '%s': %w", name, step.Name, err)
+			}
+			if err := validateAssertions(step); err != nil {
+				return fmt.Errorf("scenario '%s' step '%s': %w", name, step.Name, err)
+			}
+		}
+		if scenario.Mock != nil {
+			if err := tc.validateScenarioMock(scenario.Mock); err != nil {
+				return fmt.Errorf("scenario '%s' mock: %w", name, err)
+			}
+		}
+	}
+
+	// Validate mock route expressions and fault modes so mistakes surface at load time rather than per request
+	for _, route := range tc.mockRoutes {
+		if err := server.ValidateRoute(route); err != nil {
+			return fmt.Errorf("mock route '%s': %w", route.Name, err)
+		}
+		if err := tc.validateListenerRef(route.Listener); err != nil {
+			return fmt.Errorf("mock route '%s': %w", route.Name, err)
+		}
+	}
+
+	for _, o := range tc.mockOutbound {
+		if o.Name == "" {
+			return fmt.Errorf("mock outbound message has empty name")
+		}
+		if mti, _ := o.Fields["0"].(string); mti == "" {
+			return fmt.Errorf("mock outbound '%s' must set the MTI in field \"0\"", o.Name)
+		}
+		if o.IntervalMs < 0 || o.InitialDelayMs < 0 || o.Count < 0 {
+			return fmt.Errorf("mock outbound '%s' has a negative interval, delay or count", o.Name)
 		}
+		if err := tc.validateListenerRef(o.Listener); err != nil {
+			return fmt.Errorf("mock outbound '%s': %w", o.Name, err)
+		}
+	}
+
+	if err := tc.validateFramings(); err != nil {
+		return err
+	}
+
+	if err := tc.validateMockListeners(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// validateStepFlow checks a scenario step's when condition, loops, retry policy and kind
+func (tc *TransactionCollection) validateStepFlow(step ScenarioStep) error {
+	if step.When != "" {
+		src := step.When
+		if inner, ok := expr.Unwrap(src); ok {
+			src = inner
+		}
+		if _, err := expr.Compile(src); err != nil {
+			return fmt.Errorf("when: %w", err)
+		}
+	}
+	if step.Repeat < 0 {
+		return fmt.Errorf("repeat must not be negative, got %d", step.Repeat)
+	}
+	if step.Foreach != "" {
+		if _, ok := tc.datasets[step.Foreach]; !ok {
+			return fmt.Errorf("foreach dataset '%s' not found", step.Foreach)
+		}
+	}
+	if r := step.Retry; r != nil && (r.Attempts < 0 || r.BackoffMs < 0 || r.MaxBackoffMs < 0) {
+		return fmt.Errorf("retry attempts and backoff must not be negative")
+	}
+	switch step.Kind {
+	case "", StepSend, StepSendOnly, StepReceive, StepExpectNone:
+	default:
+		return fmt.Errorf("unknown kind '%s' (expected send, send_only, receive or expect_none)", step.Kind)
+	}
+	if step.TimeoutMs < 0 || step.Responses < 0 {
+		return fmt.Errorf("timeout_ms and responses must not be negative")
+	}
+	if step.Responses > 1 && step.Kind != "" && step.Kind != StepSend {
+		return fmt.Errorf("responses applies to send steps only")
+	}
+	for k, v := range step.Match {
+		if s, ok := v.(string); ok {
+			if err := expr.CheckTemplate(s); err != nil {
+				return fmt.Errorf("match field %s: %w", k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateAssertions checks a step's numeric assertions and latency limit. Operands
+// that reference variables are checked when the step runs.
+func validateAssertions(step ScenarioStep) error {
+	if step.MaxLatencyMs < 0 {
+		return fmt.Errorf("max_latency_ms must not be negative, got %d", step.MaxLatencyMs)
+	}
+	for _, a := range step.Validate {
+		if len(a.Between) != 0 && len(a.Between) != 2 {
+			return fmt.Errorf("field %s: between needs a minimum and a maximum, got %d values", a.Field, len(a.Between))
+		}
+		for _, o := range append([]Operand{a.Gt, a.Lt}, a.Between...) {
+			if o == "" || strings.Contains(string(o), "{{") {
+				continue
+			}
+			if _, err := parseNumber(string(o)); err != nil {
+				return fmt.Errorf("field %s: operand '%s' is not numeric", a.Field, o)
+			}
+		}
+	}
+	return nil
+}
+
+// validateMockListeners checks that every listener has a unique name and port and a known header format
+func (tc *TransactionCollection) validateMockListeners() error {
+	names := make(map[string]bool)
+	ports := make(map[string]string)
+	for _, l := range tc.mockListeners {
+		if l.Name == "" {
+			return fmt.Errorf("mock listener has empty name")
+		}
+		if names[l.Name] {
+			return fmt.Errorf("duplicate mock listener name: %s", l.Name)
+		}
+		names[l.Name] = true
+
+		if _, err := strconv.Atoi(l.Port); err != nil {
+			return fmt.Errorf("mock listener '%s' has invalid port %q", l.Name, l.Port)
+		}
+		if other, ok := ports[l.Port]; ok {
+			return fmt.Errorf("mock listeners '%s' and '%s' both use port %s", other, l.Name, l.Port)
+		}
+		ports[l.Port] = l.Name
+
+		if l.Header != "" && !slices.Contains(cfg.HeaderTypes, l.Header) && !tc.hasFraming(l.Header) {
+			return fmt.Errorf("mock listener '%s' has unknown header %q (valid: %s or a framing)", l.Name, l.Header, strings.Join(cfg.HeaderTypes, ", "))
+		}
+		if err := l.ValidateFrameLimits(); err != nil {
+			return fmt.Errorf("mock listener '%s': %w", l.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateFramings checks every custom framing and that its name is unique and not a built-in header type
+func (tc *TransactionCollection) validateFramings() error {
+	names := make(map[string]bool)
+	for _, f := range tc.framings {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		if slices.Contains(cfg.HeaderTypes, f.Name) {
+			return fmt.Errorf("framing '%s' shadows a built-in header type", f.Name)
+		}
+		if names[f.Name] {
+			return fmt.Errorf("duplicate framing name: %s", f.Name)
+		}
+		names[f.Name] = true
+	}
+	return nil
+}
+
+// hasFraming reports whether the file defines a framing with the given name
+func (tc *TransactionCollection) hasFraming(name string) bool {
+	return slices.ContainsFunc(tc.framings, func(f cfg.FramingConfig) bool { return f.Name == name })
+}
+
+// validateListenerRef checks that a route or outbound message only names a defined listener
+func (tc *TransactionCollection) validateListenerRef(listener string) error {
+	if listener == "" {
+		return nil
+	}
+	if !slices.ContainsFunc(tc.mockListeners, func(l cfg.MockListenerConfig) bool { return l.Name == listener }) {
+		return fmt.Errorf("unknown listener '%s'", listener)
+	}
+	return nil
+}
+
+// validateScenarioMock checks a scenario mock's header and routes, inline or referenced
+func (tc *TransactionCollection) validateScenarioMock(m *ScenarioMock) error {
+	if m.Header != "" {
+		if _, err := utils.SelectLength(m.Header); err != nil {
+			return fmt.Errorf("header: %w", err)
+		}
+	}
+	for _, route := range m.Routes {
+		if err := server.ValidateRoute(route); err != nil {
+			return fmt.Errorf("route '%s': %w", route.Name, err)
+		}
+	}
+	_, err := tc.scenarioMockRoutes(m)
+	return err
+}
+
 func (tc *TransactionCollection) validateTransactionFields(t Transaction) error {
 	fieldMap := make(map[int]interface{})
 	if err := json.Unmarshal(t.Fields, &fieldMap); err != nil {
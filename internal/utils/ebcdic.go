@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/moov-io/iso8583"
+	"github.com/moov-io/iso8583/encoding"
+	"github.com/moov-io/iso8583/field"
+	"github.com/moov-io/iso8583/specs"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// EBCDIC code pages accepted for text fields, length headers and hex dumps
+const (
+	CodePage037  = "cp037"  // IBM US/Canada, the iso8583 library's EBCDIC
+	CodePage1047 = "cp1047" // IBM Latin-1 open systems, used by z/OS hosts
+)
+
+// ebcdicEncoder encodes field values in an EBCDIC code page
+type ebcdicEncoder struct {
+	cm *charmap.Charmap
+}
+
+// EBCDIC1047 encodes field values in code page 1047. Code page 037 is the
+// library's own encoding.EBCDIC.
+var EBCDIC1047 encoding.Encoder = &ebcdicEncoder{cm: charmap.CodePage1047}
+
+func (e *ebcdicEncoder) Encode(src []byte) ([]byte, error) {
+	out, err := e.cm.NewEncoder().Bytes(src)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %q to EBCDIC: %w", src, err)
+	}
+	return out, nil
+}
+
+func (e *ebcdicEncoder) Decode(src []byte, length int) ([]byte, int, error) {
+	if length < 0 {
+		return nil, 0, fmt.Errorf("length should be positive, got %d", length)
+	}
+	if len(src) < length {
+		return nil, 0, fmt.Errorf("not enough data to decode. expected len %d, got %d", length, len(src))
+	}
+	out, err := e.cm.NewDecoder().Bytes(src[:length])
+	if err != nil {
+		return nil, 0, fmt.Errorf("decoding EBCDIC: %w", err)
+	}
+	return out, length, nil
+}
+
+// ebcdicCodePages maps the spelling accepted in specs and options to a code page
+var ebcdicCodePages = map[string]string{
+	"ebcdic":     CodePage037,
+	"ebcdic037":  CodePage037,
+	"cp037":      CodePage037,
+	"ebcdic1047": CodePage1047,
+	"cp1047":     CodePage1047,
+}
+
+// EBCDICCodePage returns the code page named by an encoding such as EBCDIC, CP037
+// or EBCDIC1047, ignoring case
+func EBCDICCodePage(name string) (string, bool) {
+	cp, ok := ebcdicCodePages[strings.ToLower(strings.TrimSpace(name))]
+	return cp, ok
+}
+
+func codePageMap(codePage string) *charmap.Charmap {
+	if codePage == CodePage1047 {
+		return charmap.CodePage1047
+	}
+	return charmap.CodePage037
+}
+
+// EncodeEBCDIC encodes text in the code page; an empty code page selects 037
+func EncodeEBCDIC(text string, codePage string) ([]byte, error) {
+	out, err := codePageMap(codePage).NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("encoding %q to EBCDIC %s: %w", text, codePage, err)
+	}
+	return out, nil
+}
+
+// DecodeEBCDIC decodes EBCDIC bytes in the code page; an empty code page selects 037
+func DecodeEBCDIC(b []byte, codePage string) string {
+	out, _ := codePageMap(codePage).NewDecoder().Bytes(b)
+	return string(out)
+}
+
+// SpecCodePage returns the EBCDIC code page of the spec's MTI, or empty for specs
+// whose MTI is not EBCDIC
+func SpecCodePage(spec *iso8583.MessageSpec) string {
+	if spec == nil {
+		return ""
+	}
+	mti, ok := spec.Fields[0]
+	if !ok || mti.Spec() == nil {
+		return ""
+	}
+	switch mti.Spec().Enc {
+	case encoding.EBCDIC:
+		return CodePage037
+	case EBCDIC1047:
+		return CodePage1047
+	}
+	return ""
+}
+
+// HexDump returns a hex dump of data in the layout of hex.Dump. With an EBCDIC
+// code page the text column shows the bytes decoded from that code page.
+func HexDump(data []byte, codePage string) string {
+	if codePage == "" {
+		return hex.Dump(data)
+	}
+	text := []rune(DecodeEBCDIC(data, codePage))
+
+	var buf strings.Builder
+	for i := 0; i < len(data); i += 16 {
+		fmt.Fprintf(&buf, "%08x  ", i)
+		for j := 0; j < 16; j++ {
+			if i+j < len(data) {
+				fmt.Fprintf(&buf, "%02x ", data[i+j])
+			} else {
+				buf.WriteString("   ")
+			}
+			if j == 7 {
+				buf.WriteString(" ")
+			}
+		}
+		buf.WriteString(" |")
+		for j := 0; j < 16 && i+j < len(data); j++ {
+			if r := text[i+j]; r >= 32 && r <= 126 {
+				buf.WriteRune(r)
+			} else {
+				buf.WriteByte('.')
+			}
+		}
+		buf.WriteString("|\n")
+	}
+	return buf.String()
+}
+
+// ImportSpecJSON imports a JSON spec like specs.ImportJSON, additionally accepting
+// the EBCDIC code pages by name: "enc" may be EBCDIC, EBCDIC037, CP037, EBCDIC1047
+// or CP1047, and "prefix" may use the same names (EBCDIC1047.LL). Length prefixes
+// are digits, which both code pages encode alike.
+func ImportSpecJSON(raw []byte) (*iso8583.MessageSpec, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return specs.ImportJSON(raw)
+	}
+	fields, _ := doc["fields"].(map[string]interface{})
+	var cp1047 [][]string
+	if !normalizeEBCDICFields(fields, nil, &cp1047) {
+		return specs.ImportJSON(raw)
+	}
+
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("normalizing EBCDIC encodings: %w", err)
+	}
+	spec, err := specs.ImportJSON(normalized)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range cp1047 {
+		if f := specField(spec, path); f != nil && f.Spec() != nil {
+			f.Spec().Enc = EBCDIC1047
+		}
+	}
+	return spec, nil
+}
+
+// normalizeEBCDICFields rewrites code page names to the library's EBCDIC, recording the
+// paths of code page 1047 fields. It reports whether anything was rewritten.
+func normalizeEBCDICFields(fields map[string]interface{}, path []string, cp1047 *[][]string) bool {
+	changed := false
+	for id, v := range fields {
+		def, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fieldPath := append(append([]string(nil), path...), id)
+		if enc, ok := def["enc"].(string); ok && !strings.EqualFold(enc, "EBCDIC") {
+			if cp, ok := EBCDICCodePage(enc); ok {
+				def["enc"] = "EBCDIC"
+				changed = true
+				if cp == CodePage1047 {
+					*cp1047 = append(*cp1047, fieldPath)
+				}
+			}
+		}
+		if prefix, ok := def["prefix"].(string); ok {
+			if name, kind, found := strings.Cut(prefix, "."); found && !strings.EqualFold(name, "EBCDIC") {
+				if _, ok := EBCDICCodePage(name); ok {
+					def["prefix"] = "EBCDIC." + kind
+					changed = true
+				}
+			}
+		}
+		if sub, ok := def["subfields"].(map[string]interface{}); ok {
+			if normalizeEBCDICFields(sub, fieldPath, cp1047) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// specField returns the field at path: a data field ID followed by subfield keys
+func specField(spec *iso8583.MessageSpec, path []string) field.Field {
+	id, err := strconv.Atoi(path[0])
+	if err != nil {
+		return nil
+	}
+	f := spec.Fields[id]
+	for _, key := range path[1:] {
+		if f == nil || f.Spec() == nil {
+			return nil
+		}
+		f = f.Spec().Subfields[key]
+	}
+	return f
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"jiso/internal/config"
+
+	"github.com/moov-io/iso8583"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ebcdicSpecJSON = `{
+	"name": "EBCDIC Test",
+	"fields": {
+		"0": {"type": "String", "length": 4, "description": "MTI", "enc": "CP1047", "prefix": "CP1047.Fixed"},
+		"1": {"type": "Bitmap", "length": 8, "description": "Bitmap", "enc": "Binary", "prefix": "Binary.Fixed"},
+		"2": {"type": "String", "length": 19, "description": "PAN", "enc": "EBCDIC037", "prefix": "EBCDIC037.LL"},
+		"43": {"type": "String", "length": 40, "description": "Card Acceptor Name", "enc": "EBCDIC1047", "prefix": "EBCDIC1047.LL"}
+	}
+}`
+
+func TestImportSpecJSONCodePages(t *testing.T) {
+	spec, err := ImportSpecJSON([]byte(ebcdicSpecJSON))
+	require.NoError(t, err)
+	assert.Equal(t, CodePage1047, SpecCodePage(spec))
+
+	msg := iso8583.NewMessage(spec)
+	msg.MTI("0100")
+	require.NoError(t, msg.Field(2, "4000"))
+	require.NoError(t, msg.Field(43, "[SHOP]"))
+	packed, err := msg.Pack()
+	require.NoError(t, err)
+
+	// MTI in cp1047, the bitmap, DE2 "04" + "4000" in cp037 and DE43 "06" + "[SHOP]" in cp1047
+	assert.Equal(t, []byte{0xF0, 0xF1, 0xF0, 0xF0}, packed[:4])
+	assert.Equal(t, []byte{0xF0, 0xF4, 0xF4, 0xF0, 0xF0, 0xF0}, packed[12:18])
+	assert.Equal(t, []byte{0xF0, 0xF6, 0xAD, 0xE2, 0xC8, 0xD6, 0xD7, 0xBD}, packed[18:])
+
+	read := iso8583.NewMessage(spec)
+	require.NoError(t, read.Unpack(packed))
+	name, err := read.GetString(43)
+	require.NoError(t, err)
+	assert.Equal(t, "[SHOP]", name)
+
+	var buf bytes.Buffer
+	require.NoError(t, Describe(read, &buf))
+	assert.Contains(t, buf.String(), "[SHOP]")
+}
+
+func TestHexDumpEBCDIC(t *testing.T) {
+	data := []byte{0xF0, 0xF1, 0xF0, 0xF0, 0x00}
+	assert.Contains(t, HexDump(data, CodePage037), "|0100.|")
+	assert.Contains(t, HexDump([]byte("0100"), ""), "|0100|")
+	assert.Equal(t, "", SpecCodePage(iso8583.Spec87))
+}
+
+func TestEBCDICHeaders(t *testing.T) {
+	header, err := SelectLength("ebcdic4")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	header.SetLength(300)
+	_, err = header.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xF0, 0xF3, 0xF0, 0xF0}, buf.Bytes())
+
+	framing, err := NewFramingHeader(config.FramingConfig{Name: "mainframe", LengthBytes: 4, LengthEncoding: config.LengthEBCDIC, Prefix: "ISO", PrefixEncoding: config.LengthEBCDIC})
+	require.NoError(t, err)
+	buf.Reset()
+	framing.SetLength(10)
+	_, err = framing.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xF0, 0xF0, 0xF1, 0xF3, 0xC9, 0xE2, 0xD6}, buf.Bytes())
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	connection "github.com/moov-io/iso8583-connection"
+	"github.com/moov-io/iso8583/network"
+)
+
+// Invalid frame policies
+const (
+	InvalidFrameClose = "close" // Fail the read, which closes the connection
+	InvalidFrameSkip  = "skip"  // Hex-dump the frame, discard it and read the next one
+)
+
+// maxFrameDump caps how many payload bytes of a skipped frame are hex-dumped
+const maxFrameDump = 256
+
+// FrameLimits bounds the message length accepted from a length header
+type FrameLimits struct {
+	MinSize int  // Smallest accepted message; 0 selects MinMessageSize
+	MaxSize int  // Largest accepted message; 0 selects MaxMessageSize
+	Skip    bool // Log and skip invalid frames instead of failing the read
+}
+
+// DefaultFrameLimits accepts MinMessageSize to MaxMessageSize bytes and fails on anything else
+var DefaultFrameLimits = FrameLimits{MinSize: MinMessageSize, MaxSize: MaxMessageSize}
+
+// NewFrameLimits builds frame limits from configuration values. Zero sizes select
+// the defaults and an empty policy selects InvalidFrameClose.
+func NewFrameLimits(minSize, maxSize int, onInvalid string) (FrameLimits, error) {
+	limits := FrameLimits{MinSize: minSize, MaxSize: maxSize}
+	switch onInvalid {
+	case "", InvalidFrameClose:
+	case InvalidFrameSkip:
+		limits.Skip = true
+	default:
+		return limits, fmt.Errorf("unknown invalid frame policy '%s' (want %s or %s)", onInvalid, InvalidFrameClose, InvalidFrameSkip)
+	}
+	if minSize < 0 || maxSize < 0 {
+		return limits, fmt.Errorf("message size limits must be non-negative, got min %d and max %d", minSize, maxSize)
+	}
+	limits = limits.withDefaults()
+	if limits.MinSize > limits.MaxSize {
+		return limits, fmt.Errorf("minimum message size %d exceeds maximum %d", limits.MinSize, limits.MaxSize)
+	}
+	return limits, nil
+}
+
+func (l FrameLimits) withDefaults() FrameLimits {
+	if l.MinSize == 0 {
+		l.MinSize = MinMessageSize
+	}
+	if l.MaxSize == 0 {
+		l.MaxSize = MaxMessageSize
+	}
+	return l
+}
+
+// Check validates a message length read from a header
+func (l FrameLimits) Check(length int) error {
+	l = l.withDefaults()
+	if length < 0 {
+		return fmt.Errorf("invalid message length: negative value %d", length)
+	}
+	if length > l.MaxSize {
+		return fmt.Errorf("message length %d exceeds maximum allowed size %d", length, l.MaxSize)
+	}
+	if length < l.MinSize {
+		return fmt.Errorf("message length %d is too small for a valid ISO8583 message (minimum %d)", length, l.MinSize)
+	}
+	return nil
+}
+
+// String describes the limits for status output
+func (l FrameLimits) String() string {
+	l = l.withDefaults()
+	policy := InvalidFrameClose
+	if l.Skip {
+		policy = InvalidFrameSkip
+	}
+	return fmt.Sprintf("%d-%d bytes, %s on invalid frame", l.MinSize, l.MaxSize, policy)
+}
+
+// ApplyFrameLimits lets headers that enforce their own length limit write
+// messages up to the configured maximum
+func ApplyFrameLimits(header network.Header, limits FrameLimits) {
+	if vh, ok := header.(*VisaHeader); ok {
+		// The VISA total length also counts the VisaNet header, 26 bytes with reject data
+		vh.SetMaxLength(min(limits.withDefaults().MaxSize+VisaRejectHeaderLength, 0xFFFF))
+	}
+}
+
+// ReadFrameLengthWrapper reads a length header and validates the length against
+// limits. With Skip set, an invalid frame is hex-dumped and discarded and the
+// next frame is read, so one bad frame does not tear down the connection.
+func ReadFrameLengthWrapper(header network.Header, limits FrameLimits) connection.MessageLengthReader {
+	limits = limits.withDefaults()
+	if vh, ok := header.(*VisaHeader); ok {
+		// Validate here rather than in the header so that oversized frames can be skipped
+		vh.SetMaxLength(0xFFFF)
+	}
+	return func(r io.Reader) (int, error) {
+		for {
+			var raw bytes.Buffer
+			n, err := header.ReadFrom(io.TeeReader(r, &raw))
+			if err != nil {
+				return n, err
+			}
+
+			length := header.Length()
+			err = limits.Check(length)
+			if err == nil {
+				return length, nil
+			}
+			if !limits.Skip || length < 0 {
+				return n, err
+			}
+			if th, ok := header.(TrailerHeader); ok {
+				length += th.TrailerLen()
+			}
+			if err := SkipFrame(r, raw.Bytes(), length, err); err != nil {
+				return n, err
+			}
+		}
+	}
+}
+
+// SkipFrame logs an invalid frame with a hex dump of its header and the start of
+// its payload, then discards the payload from r
+func SkipFrame(r io.Reader, header []byte, length int, reason error) error {
+	dump := make([]byte, min(length, maxFrameDump))
+	read, err := io.ReadFull(r, dump)
+	fmt.Printf("Skipping invalid frame: %v\n%s", reason, hex.Dump(append(append([]byte(nil), header...), dump[:read]...)))
+	if err != nil {
+		return fmt.Errorf("discarding invalid frame: %w", err)
+	}
+	if length > read {
+		if _, err := io.CopyN(io.Discard, r, int64(length-read)); err != nil {
+			return fmt.Errorf("discarding invalid frame: %w", err)
+		}
+		fmt.Printf("(%d more bytes discarded)\n", length-read)
+	}
+	return nil
+}
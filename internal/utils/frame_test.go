@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFrameLimits(t *testing.T) {
+	limits, err := NewFrameLimits(0, 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultFrameLimits, limits)
+
+	limits, err = NewFrameLimits(10, 4096, InvalidFrameSkip)
+	require.NoError(t, err)
+	assert.Equal(t, FrameLimits{MinSize: 10, MaxSize: 4096, Skip: true}, limits)
+
+	_, err = NewFrameLimits(0, 0, "ignore")
+	assert.Error(t, err)
+	_, err = NewFrameLimits(-1, 0, "")
+	assert.Error(t, err)
+	_, err = NewFrameLimits(100, 50, "")
+	assert.Error(t, err)
+}
+
+func TestFrameLimitsCheck(t *testing.T) {
+	limits := FrameLimits{MinSize: 20, MaxSize: 2048}
+	assert.NoError(t, limits.Check(20))
+	assert.NoError(t, limits.Check(2048))
+	assert.ErrorContains(t, limits.Check(2049), "exceeds maximum allowed size 2048")
+	assert.ErrorContains(t, limits.Check(19), "too small")
+	assert.ErrorContains(t, limits.Check(-1), "negative")
+}
+
+func TestReadFrameLengthWrapper(t *testing.T) {
+	// An oversized frame followed by a valid one
+	var stream bytes.Buffer
+	stream.Write([]byte{0x00, 0x40})
+	stream.Write(bytes.Repeat([]byte{0xAA}, 64))
+	stream.Write([]byte{0x00, 0x18})
+	valid := bytes.Repeat([]byte{0x30}, 24)
+	stream.Write(valid)
+
+	t.Run("close fails on the invalid frame", func(t *testing.T) {
+		r := bytes.NewReader(stream.Bytes())
+		_, err := ReadFrameLengthWrapper(NewBinary2BytesAdapter(), FrameLimits{MinSize: 20, MaxSize: 32})(r)
+		assert.ErrorContains(t, err, "exceeds maximum allowed size 32")
+	})
+
+	t.Run("skip discards the invalid frame", func(t *testing.T) {
+		r := bytes.NewReader(stream.Bytes())
+		length, err := ReadFrameLengthWrapper(NewBinary2BytesAdapter(), FrameLimits{MinSize: 20, MaxSize: 32, Skip: true})(r)
+		require.NoError(t, err)
+		assert.Equal(t, 24, length)
+
+		payload := make([]byte, length)
+		_, err = io.ReadFull(r, payload)
+		require.NoError(t, err)
+		assert.Equal(t, valid, payload)
+	})
+
+	t.Run("skip fails on a truncated frame", func(t *testing.T) {
+		r := bytes.NewReader([]byte{0x00, 0x40, 0xAA, 0xAA})
+		_, err := ReadFrameLengthWrapper(NewBinary2BytesAdapter(), FrameLimits{MinSize: 20, MaxSize: 32, Skip: true})(r)
+		assert.ErrorContains(t, err, "discarding invalid frame")
+	})
+}
+
+func TestVisaHeaderFrameLimits(t *testing.T) {
+	header, err := NewVisaHeader("000000")
+	require.NoError(t, err)
+
+	// Writing 4000 bytes exceeds the VISA default until the limits allow it
+	header.SetLength(4000)
+	_, err = header.WriteTo(io.Discard)
+	assert.Error(t, err)
+
+	ApplyFrameLimits(header, FrameLimits{MaxSize: 8192})
+	assert.Equal(t, 8192+VisaRejectHeaderLength, header.MaxLength())
+	_, err = header.WriteTo(io.Discard)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	"jiso/internal/config"
+
+	connection "github.com/moov-io/iso8583-connection"
+	"github.com/moov-io/iso8583/network"
+)
+
+const (
+	etx     = 0x03
+	tpduLen = 5
+)
+
+var (
+	framingsMu sync.RWMutex
+	framings   = make(map[string]config.FramingConfig)
+)
+
+// RegisterFraming makes a custom framing selectable by name wherever a header type is accepted
+func RegisterFraming(f config.FramingConfig) error {
+	if _, err := NewFramingHeader(f); err != nil {
+		return err
+	}
+	if slices.Contains(config.HeaderTypes, f.Name) {
+		return fmt.Errorf("framing '%s' shadows a built-in header type", f.Name)
+	}
+	framingsMu.Lock()
+	defer framingsMu.Unlock()
+	framings[f.Name] = f
+	return nil
+}
+
+// LookupFraming returns the registered custom framing with the given name
+func LookupFraming(name string) (config.FramingConfig, bool) {
+	framingsMu.RLock()
+	defer framingsMu.RUnlock()
+	f, ok := framings[name]
+	return f, ok
+}
+
+// FramingNames returns the names of the registered custom framings, sorted
+func FramingNames() []string {
+	framingsMu.RLock()
+	defer framingsMu.RUnlock()
+	names := make([]string, 0, len(framings))
+	for name := range framings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TrailerHeader is a header whose frames carry trailer bytes after the message
+type TrailerHeader interface {
+	network.Header
+	// Trailer returns the bytes written after message
+	Trailer(message []byte) []byte
+	// ReadTrailer reads the trailer that follows message and verifies it
+	ReadTrailer(r io.Reader, message []byte) error
+	// TrailerLen returns the number of trailer bytes per frame
+	TrailerLen() int
+}
+
+// FramingHeader reads and writes the length field, prefix and TPDU of a custom
+// framing. Length is the message length, without framing overhead.
+type FramingHeader struct {
+	mu       sync.RWMutex
+	framing  config.FramingConfig
+	prefix   []byte
+	tpdu     []byte // TPDU written before the next message
+	peerTPDU []byte // TPDU of the last frame read
+	length   int
+}
+
+// NewFramingHeader creates a header for the framing f
+func NewFramingHeader(f config.FramingConfig) (*FramingHeader, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	h := &FramingHeader{framing: f, prefix: []byte(f.Prefix)}
+	if f.PrefixHex != "" {
+		h.prefix, _ = hex.DecodeString(f.PrefixHex)
+	}
+	if f.Prefix != "" && f.PrefixEncoding == config.LengthEBCDIC {
+		prefix, err := EncodeEBCDIC(f.Prefix, CodePage037)
+		if err != nil {
+			return nil, fmt.Errorf("framing '%s' prefix: %w", f.Name, err)
+		}
+		h.prefix = prefix
+	}
+	if f.TPDU != "" {
+		h.tpdu, _ = hex.DecodeString(f.TPDU)
+	}
+	return h, nil
+}
+
+// Clone returns a header with the same framing and no frame state
+func (h *FramingHeader) Clone() *FramingHeader {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return &FramingHeader{
+		framing: h.framing,
+		prefix:  h.prefix,
+		tpdu:    slices.Clone(h.tpdu),
+	}
+}
+
+// Framing returns the framing definition
+func (h *FramingHeader) Framing() config.FramingConfig {
+	return h.framing
+}
+
+func (h *FramingHeader) SetLength(length int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.length = length
+}
+
+func (h *FramingHeader) Length() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.length
+}
+
+// SetTPDU sets the TPDU written before the next message
+func (h *FramingHeader) SetTPDU(tpdu []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tpdu = slices.Clone(tpdu)
+}
+
+// PeerTPDU returns the TPDU of the last frame read, or nil
+func (h *FramingHeader) PeerTPDU() []byte {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return slices.Clone(h.peerTPDU)
+}
+
+// ReplyTPDU returns the TPDU answering the last frame read: same ID with the
+// destination and source NII swapped
+func (h *FramingHeader) ReplyTPDU() []byte {
+	peer := h.PeerTPDU()
+	if len(peer) != tpduLen {
+		return nil
+	}
+	return []byte{peer[0], peer[3], peer[4], peer[1], peer[2]}
+}
+
+// overhead returns the bytes the length field counts besides the message
+func (h *FramingHeader) overhead() int {
+	n := len(h.prefix) + h.TrailerLen()
+	if h.framing.TPDU != "" {
+		n += tpduLen
+	}
+	if h.framing.LengthIncludesHeader {
+		n += h.framing.LengthBytes
+	}
+	return n
+}
+
+func (h *FramingHeader) WriteTo(w io.Writer) (int, error) {
+	h.mu.RLock()
+	value := h.length + h.overhead()
+	tpdu := h.tpdu
+	h.mu.RUnlock()
+
+	buf, err := h.encodeLength(value)
+	if err != nil {
+		return 0, err
+	}
+	buf = append(buf, h.prefix...)
+	if h.framing.TPDU != "" {
+		buf = append(buf, tpdu...)
+	}
+	return w.Write(buf)
+}
+
+func (h *FramingHeader) ReadFrom(r io.Reader) (int, error) {
+	buf := make([]byte, h.framing.LengthBytes+len(h.prefix))
+	if h.framing.TPDU != "" {
+		buf = append(buf, make([]byte, tpduLen)...)
+	}
+	n, err := io.ReadFull(r, buf)
+	if err != nil {
+		return n, fmt.Errorf("reading %s header: %w", h.framing.Name, err)
+	}
+
+	value, err := h.decodeLength(buf[:h.framing.LengthBytes])
+	if err != nil {
+		return n, err
+	}
+	rest := buf[h.framing.LengthBytes:]
+	if got := rest[:len(h.prefix)]; string(got) != string(h.prefix) {
+		return n, fmt.Errorf("%s prefix mismatch: expected %X, got %X", h.framing.Name, h.prefix, got)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.framing.TPDU != "" {
+		h.peerTPDU = slices.Clone(rest[len(h.prefix):])
+	}
+	h.length = value - h.overhead()
+	return n, nil
+}
+
+func (h *FramingHeader) encodeLength(value int) ([]byte, error) {
+	width := h.framing.LengthBytes
+	switch h.framing.LengthEncoding {
+	case "", config.LengthBinary:
+		if width < 4 && value >= 1<<(8*width) {
+			return nil, fmt.Errorf("length %d does not fit a %d-byte binary length", value, width)
+		}
+		buf := make([]byte, 4)
+		binary.BigEndian.PutUint32(buf, uint32(value))
+		return buf[4-width:], nil
+	case config.LengthBCD:
+		digits := fmt.Sprintf("%0*d", 2*width, value)
+		if len(digits) > 2*width {
+			return nil, fmt.Errorf("length %d does not fit a %d-byte BCD length", value, width)
+		}
+		return hex.DecodeString(digits)
+	default:
+		digits := []byte(fmt.Sprintf("%0*d", width, value))
+		if len(digits) > width {
+			return nil, fmt.Errorf("length %d does not fit a %d-digit length", value, width)
+		}
+		if h.framing.LengthEncoding == config.LengthEBCDIC {
+			for i := range digits {
+				digits[i] += 0xF0 - '0'
+			}
+		}
+		return digits, nil
+	}
+}
+
+func (h *FramingHeader) decodeLength(b []byte) (int, error) {
+	switch h.framing.LengthEncoding {
+	case "", config.LengthBinary:
+		buf := make([]byte, 4)
+		copy(buf[4-len(b):], b)
+		return int(binary.BigEndian.Uint32(buf)), nil
+	case config.LengthBCD:
+		value, err := strconv.Atoi(hex.EncodeToString(b))
+		if err != nil {
+			return 0, fmt.Errorf("invalid BCD length %X", b)
+		}
+		return value, nil
+	default:
+		digits := slices.Clone(b)
+		if h.framing.LengthEncoding == config.LengthEBCDIC {
+			for i, c := range digits {
+				if c < 0xF0 || c > 0xF9 {
+					return 0, fmt.Errorf("invalid EBCDIC length %X", b)
+				}
+				digits[i] = c - 0xF0 + '0'
+			}
+		}
+		value, err := strconv.Atoi(string(digits))
+		if err != nil {
+			return 0, fmt.Errorf("invalid length %q", b)
+		}
+		return value, nil
+	}
+}
+
+// TrailerLen returns the number of trailer bytes per frame
+func (h *FramingHeader) TrailerLen() int {
+	switch h.framing.Trailer {
+	case config.TrailerETX:
+		return 1
+	case config.TrailerETXLRC:
+		return 2
+	}
+	return 0
+}
+
+// Trailer returns the bytes written after message
+func (h *FramingHeader) Trailer(message []byte) []byte {
+	switch h.framing.Trailer {
+	case config.TrailerETX:
+		return []byte{etx}
+	case config.TrailerETXLRC:
+		return []byte{etx, lrc(message) ^ etx}
+	}
+	return nil
+}
+
+// ReadTrailer reads the trailer that follows message and verifies the ETX and LRC
+func (h *FramingHeader) ReadTrailer(r io.Reader, message []byte) error {
+	if h.TrailerLen() == 0 {
+		return nil
+	}
+	got := make([]byte, h.TrailerLen())
+	if _, err := io.ReadFull(r, got); err != nil {
+		return fmt.Errorf("reading %s trailer: %w", h.framing.Name, err)
+	}
+	if want := h.Trailer(message); string(got) != string(want) {
+		return fmt.Errorf("%s trailer mismatch: expected %X, got %X", h.framing.Name, want, got)
+	}
+	return nil
+}
+
+// lrc returns the XOR of b
+func lrc(b []byte) byte {
+	var x byte
+	for _, c := range b {
+		x ^= c
+	}
+	return x
+}
+
+// SkipTrailerWrapper wraps a length reader whose caller reads only the message
+// bytes of each frame (iso8583-connection): the trailer of a frame is consumed
+// before the header of the next one. Only its ETX is checked, which catches frames
+// out of sync; the LRC needs the message, so readers that see the message (the mock
+// server and the analyzer) verify the whole trailer with ReadTrailer instead.
+func SkipTrailerWrapper(header network.Header, read connection.MessageLengthReader) connection.MessageLengthReader {
+	th, ok := header.(TrailerHeader)
+	if !ok || th.TrailerLen() == 0 {
+		return read
+	}
+	pending := false
+	return func(r io.Reader) (int, error) {
+		if pending {
+			trailer := make([]byte, th.TrailerLen())
+			if _, err := io.ReadFull(r, trailer); err != nil {
+				return 0, fmt.Errorf("reading frame trailer: %w", err)
+			}
+			if trailer[0] != etx {
+				return 0, fmt.Errorf("frame trailer starts with %02X instead of ETX: frames out of sync", trailer[0])
+			}
+			pending = false
+		}
+		length, err := read(r)
+		if err == nil {
+			pending = true
+		}
+		return length, err
+	}
+}
@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"jiso/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFramingHeaderLengthEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		framing  config.FramingConfig
+		expected []byte
+	}{
+		{"binary", config.FramingConfig{LengthBytes: 3}, []byte{0x00, 0x01, 0x2C}},
+		{"bcd", config.FramingConfig{LengthBytes: 2, LengthEncoding: config.LengthBCD}, []byte{0x03, 0x00}},
+		{"ascii", config.FramingConfig{LengthBytes: 4, LengthEncoding: config.LengthASCII}, []byte("0300")},
+		{"ebcdic", config.FramingConfig{LengthBytes: 4, LengthEncoding: config.LengthEBCDIC}, []byte{0xF0, 0xF3, 0xF0, 0xF0}},
+		{"includes header", config.FramingConfig{LengthBytes: 4, LengthEncoding: config.LengthASCII, LengthIncludesHeader: true}, []byte("0304")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.framing.Name = tt.name
+			header, err := NewFramingHeader(tt.framing)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			header.SetLength(300)
+			_, err = header.WriteTo(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.Bytes())
+
+			read := header.Clone()
+			_, err = read.ReadFrom(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, 300, read.Length())
+		})
+	}
+
+	header, err := NewFramingHeader(config.FramingConfig{Name: "short", LengthBytes: 1})
+	require.NoError(t, err)
+	header.SetLength(256)
+	_, err = header.WriteTo(io.Discard)
+	assert.ErrorContains(t, err, "does not fit")
+}
+
+func TestFramingHeaderPrefixAndTPDU(t *testing.T) {
+	header, err := NewFramingHeader(config.FramingConfig{
+		Name:        "pos",
+		LengthBytes: 2,
+		Prefix:      "ISO",
+		TPDU:        "6000010002",
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	header.SetLength(10)
+	_, err = header.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x12, 'I', 'S', 'O', 0x60, 0x00, 0x01, 0x00, 0x02}, buf.Bytes())
+
+	read := header.Clone()
+	_, err = read.ReadFrom(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 10, read.Length())
+	assert.Equal(t, []byte{0x60, 0x00, 0x01, 0x00, 0x02}, read.PeerTPDU())
+	assert.Equal(t, []byte{0x60, 0x00, 0x02, 0x00, 0x01}, read.ReplyTPDU())
+
+	wrong := bytes.Clone(buf.Bytes())
+	wrong[2] = 'X'
+	_, err = header.Clone().ReadFrom(bytes.NewReader(wrong))
+	assert.ErrorContains(t, err, "prefix mismatch")
+}
+
+func TestFramingHeaderTrailer(t *testing.T) {
+	header, err := NewFramingHeader(config.FramingConfig{Name: "etx", LengthBytes: 2, Trailer: config.TrailerETXLRC})
+	require.NoError(t, err)
+
+	message := []byte{0x01, 0x02, 0x04}
+	trailer := header.Trailer(message)
+	assert.Equal(t, []byte{0x03, 0x07 ^ 0x03}, trailer)
+
+	assert.NoError(t, header.ReadTrailer(bytes.NewReader(trailer), message))
+	assert.ErrorContains(t, header.ReadTrailer(bytes.NewReader([]byte{0x03, 0x00}), message), "trailer mismatch")
+
+	// The length field counts the trailer
+	var buf bytes.Buffer
+	header.SetLength(len(message))
+	_, err = header.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x05}, buf.Bytes())
+}
+
+func TestSkipTrailerWrapper(t *testing.T) {
+	header, err := NewFramingHeader(config.FramingConfig{Name: "etx", LengthBytes: 2, Trailer: config.TrailerETX})
+	require.NoError(t, err)
+
+	var stream bytes.Buffer
+	for _, msg := range [][]byte{[]byte("first"), []byte("second")} {
+		header.SetLength(len(msg))
+		_, err := header.WriteTo(&stream)
+		require.NoError(t, err)
+		stream.Write(append(msg, header.Trailer(msg)...))
+	}
+
+	read := SkipTrailerWrapper(header, func(r io.Reader) (int, error) {
+		_, err := header.ReadFrom(r)
+		return header.Length(), err
+	})
+	for _, expected := range []string{"first", "second"} {
+		length, err := read(&stream)
+		require.NoError(t, err)
+		msg := make([]byte, length)
+		_, err = io.ReadFull(&stream, msg)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(msg))
+	}
+}
+
+func TestSkipTrailerWrapperOutOfSync(t *testing.T) {
+	header, err := NewFramingHeader(config.FramingConfig{Name: "etx_lrc", LengthBytes: 2, Trailer: config.TrailerETXLRC})
+	require.NoError(t, err)
+
+	// The first frame is sent without its trailer, as a length-only writer would
+	var stream bytes.Buffer
+	for _, msg := range [][]byte{[]byte("first"), []byte("second")} {
+		header.SetLength(len(msg))
+		_, err := header.WriteTo(&stream)
+		require.NoError(t, err)
+		stream.Write(msg)
+	}
+
+	read := SkipTrailerWrapper(header, func(r io.Reader) (int, error) {
+		_, err := header.ReadFrom(r)
+		return header.Length(), err
+	})
+	length, err := read(&stream)
+	require.NoError(t, err)
+	_, err = io.ReadFull(&stream, make([]byte, length))
+	require.NoError(t, err)
+
+	_, err = read(&stream)
+	assert.ErrorContains(t, err, "frames out of sync")
+}
+
+func TestReadFrameLengthWrapperSkipsTrailer(t *testing.T) {
+	for _, trailer := range []string{config.TrailerETX, config.TrailerETXLRC} {
+		t.Run(trailer, func(t *testing.T) {
+			header, err := NewFramingHeader(config.FramingConfig{Name: trailer, LengthBytes: 2, Trailer: trailer})
+			require.NoError(t, err)
+
+			// An oversized frame followed by a valid one, both with trailers
+			var stream bytes.Buffer
+			valid := bytes.Repeat([]byte{0x30}, 24)
+			for _, msg := range [][]byte{bytes.Repeat([]byte{0xAA}, 64), valid} {
+				header.SetLength(len(msg))
+				_, err := header.WriteTo(&stream)
+				require.NoError(t, err)
+				stream.Write(append(msg, header.Trailer(msg)...))
+			}
+
+			limits := FrameLimits{MinSize: 20, MaxSize: 32, Skip: true}
+			t.Run("server", func(t *testing.T) {
+				r := bytes.NewReader(stream.Bytes())
+				length, err := ReadFrameLengthWrapper(header, limits)(r)
+				require.NoError(t, err)
+				require.Equal(t, len(valid), length)
+
+				payload := make([]byte, length)
+				_, err = io.ReadFull(r, payload)
+				require.NoError(t, err)
+				assert.Equal(t, valid, payload)
+				assert.NoError(t, header.ReadTrailer(r, payload))
+				assert.Zero(t, r.Len())
+			})
+
+			t.Run("client", func(t *testing.T) {
+				r := bytes.NewReader(stream.Bytes())
+				read := SkipTrailerWrapper(header, ReadFrameLengthWrapper(header, limits))
+				length, err := read(r)
+				require.NoError(t, err)
+				require.Equal(t, len(valid), length)
+
+				payload := make([]byte, length)
+				_, err = io.ReadFull(r, payload)
+				require.NoError(t, err)
+				assert.Equal(t, valid, payload)
+
+				// The next read consumes the valid frame's trailer and reaches EOF
+				_, err = read(r)
+				assert.ErrorIs(t, err, io.EOF)
+			})
+		})
+	}
+}
+
+func TestRegisterFraming(t *testing.T) {
+	assert.Error(t, RegisterFraming(config.FramingConfig{Name: "binary2", LengthBytes: 2}))
+	assert.Error(t, RegisterFraming(config.FramingConfig{Name: "bad", LengthBytes: 5}))
+
+	require.NoError(t, RegisterFraming(config.FramingConfig{Name: "test_framing", LengthBytes: 2, LengthEncoding: config.LengthBCD}))
+	assert.Contains(t, FramingNames(), "test_framing")
+
+	header, err := SelectLength("test_framing")
+	require.NoError(t, err)
+	assert.IsType(t, &FramingHeader{}, header)
+
+	_, err = SelectLength("unknown_framing")
+	assert.Error(t, err)
+}
@@ -15,15 +15,24 @@ const (
 	NAPSPREFIXATM = "ISO016000070"
 	NAPSPREFIXPOS = "ISO026000070"
 
-	// MaxMessageSize defines the maximum allowed message size in bytes
-	// ISO8583 messages are typically small (a few KB)
-	MaxMessageSize = 1024
+	// MaxMessageSize is the default maximum message size in bytes. ISO8583
+	// messages are typically a few KB; EMV data in DE55 often pushes them past 1 KB
+	MaxMessageSize = 8192
+
+	// MinMessageSize is the default minimum message size in bytes:
+	// MTI (4) + bitmap (8-16) + at least some data
+	MinMessageSize = 20
 )
 
+// ebcdic4Framing is the 4-digit EBCDIC length header of mainframe hosts
+var ebcdic4Framing = config.FramingConfig{Name: "ebcdic4", LengthBytes: 4, LengthEncoding: config.LengthEBCDIC}
+
 func SelectLength(lenType string) (network.Header, error) {
 	switch lenType {
 	case "ascii4":
 		return network.NewASCII4BytesHeader(), nil
+	case "ebcdic4":
+		return NewFramingHeader(ebcdic4Framing)
 	case "binary2", "NAPS":
 		return NewBinary2BytesAdapter(), nil
 	case "binary4":
@@ -37,6 +46,9 @@ func SelectLength(lenType string) (network.Header, error) {
 		}
 		return NewVisaHeader(stationID)
 	default:
+		if f, ok := LookupFraming(lenType); ok {
+			return NewFramingHeader(f)
+		}
 		return nil, fmt.Errorf("unknown length type: %s", lenType)
 	}
 }
@@ -47,6 +59,8 @@ func SelectServerHeader(lenType string) (network.Header, error) {
 	switch lenType {
 	case "ascii4":
 		return network.NewASCII4BytesHeader(), nil
+	case "ebcdic4":
+		return NewFramingHeader(ebcdic4Framing)
 	case "binary2", "NAPS", "":
 		return NewBinary2BytesAdapter(), nil
 	case "binary4":
@@ -56,42 +70,16 @@ func SelectServerHeader(lenType string) (network.Header, error) {
 	case "visa":
 		return NewVisaHeader("000000")
 	default:
+		if f, ok := LookupFraming(lenType); ok {
+			return NewFramingHeader(f)
+		}
 		return nil, fmt.Errorf("unknown server length type: %s", lenType)
 	}
 }
 
+// ReadMessageLengthWrapper reads a length header and validates it against DefaultFrameLimits
 func ReadMessageLengthWrapper(header network.Header) connection.MessageLengthReader {
-	return func(r io.Reader) (int, error) {
-		n, err := header.ReadFrom(r)
-		if err != nil {
-			return n, err
-		}
-
-		messageLength := header.Length()
-
-		// Validate message size to prevent buffer overflow attacks
-		if messageLength < 0 {
-			return n, fmt.Errorf("invalid message length: negative value %d", messageLength)
-		}
-
-		if messageLength > MaxMessageSize {
-			return n, fmt.Errorf(
-				"message length %d exceeds maximum allowed size %d",
-				messageLength,
-				MaxMessageSize,
-			)
-		}
-
-		// ISO8583 messages should have a minimum reasonable size
-		if messageLength < 20 { // MTI (4) + bitmap (8-16) + at least some data
-			return n, fmt.Errorf(
-				"message length %d is too small for a valid ISO8583 message",
-				messageLength,
-			)
-		}
-
-		return messageLength, nil
-	}
+	return ReadFrameLengthWrapper(header, DefaultFrameLimits)
 }
 
 func WriteMessageLengthWrapper(header network.Header) connection.MessageLengthWriter {
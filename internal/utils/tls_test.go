@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jiso/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSConfigBuilders(t *testing.T) {
+	// Inactive settings mean plain TCP
+	clientCfg, err := ClientTLSConfig(config.TLSConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, clientCfg)
+	serverCfg, err := ServerTLSConfig(config.TLSConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, serverCfg)
+
+	// TLS without client certificate or CA uses the system roots
+	clientCfg, err = ClientTLSConfig(config.TLSConfig{Enabled: true, ServerName: "acquirer.example"})
+	require.NoError(t, err)
+	require.NotNil(t, clientCfg)
+	assert.Equal(t, "acquirer.example", clientCfg.ServerName)
+	assert.Nil(t, clientCfg.RootCAs)
+
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a certificate"), 0o600))
+
+	_, err = ClientTLSConfig(config.TLSConfig{CAFile: garbage})
+	assert.ErrorContains(t, err, "no certificates found")
+	_, err = ClientTLSConfig(config.TLSConfig{CertFile: garbage})
+	assert.ErrorContains(t, err, "certificate and key must be given together")
+	_, err = ClientTLSConfig(config.TLSConfig{CAFile: filepath.Join(dir, "missing.pem")})
+	assert.ErrorContains(t, err, "does not exist")
+
+	_, err = ServerTLSConfig(config.TLSConfig{Enabled: true})
+	assert.ErrorContains(t, err, "requires a certificate and key")
+	_, err = ServerTLSConfig(config.TLSConfig{CertFile: garbage, KeyFile: garbage, ClientAuth: true})
+	assert.ErrorContains(t, err, "requires a CA file")
+}
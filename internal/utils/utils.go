@@ -12,7 +12,6 @@ import (
 	"jiso/internal/command/templates"
 
 	"github.com/moov-io/iso8583"
-	"github.com/moov-io/iso8583/specs"
 )
 
 const letterBytes = "1234567890"
@@ -36,7 +35,7 @@ func CreateSpecFromFile(path string) (*iso8583.MessageSpec, error) {
 		return nil, fmt.Errorf("reading file %s: %w", path, err)
 	}
 
-	return specs.ImportJSON(raw)
+	return ImportSpecJSON(raw)
 }
 
 func ResolveSpec(specPath string, fallback *iso8583.MessageSpec) *iso8583.MessageSpec {
@@ -57,7 +56,7 @@ func ResolveSpec(specPath string, fallback *iso8583.MessageSpec) *iso8583.Messag
 
 func GetDefaultSpec() *iso8583.MessageSpec {
 	if len(templates.DefaultSpecJSON) > 0 {
-		if spec, err := specs.ImportJSON(templates.DefaultSpecJSON); err == nil && spec != nil {
+		if spec, err := ImportSpecJSON(templates.DefaultSpecJSON); err == nil && spec != nil {
 			return spec
 		}
 	}
@@ -5,35 +5,93 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"strconv"
 	"sync"
 
 	"github.com/moov-io/iso8583/encoding"
+	"github.com/moov-io/iso8583/network"
 )
 
 const (
 	sessionControlIndicator = byte('2')
 	MaxMessageLength        = 2048
+
+	// VisaHeaderLength is the length of the BASE I message header
+	VisaHeaderLength = 22
+	// VisaRejectHeaderLength is the length of a BASE I header carrying reject data
+	VisaRejectHeaderLength = 26
 )
 
+// FieldHeader is a header with named fields, exposed to scenarios and mock routes as header.<name>
+type FieldHeader interface {
+	network.Header
+	// HeaderFields returns the fields of the last header read
+	HeaderFields() map[string]string
+	// SetHeaderField sets a field of the headers written from now on
+	SetHeaderField(name, value string) error
+}
+
+// VisaHeaderFields are the fields of the BASE I message header that follows the TCP length
+type VisaHeaderFields struct {
+	HeaderLength       int     // Field 1: 22, or 26 with reject data
+	HeaderFlag         byte    // Field 2: header flag and format
+	TextFormat         byte    // Field 3
+	TotalLength        int     // Field 4: header plus message
+	DestinationStation string  // Field 5: 6 digits
+	SourceStation      string  // Field 6: 6 digits
+	RoundTrip          byte    // Field 7: round-trip control information
+	BaseIFlags         [2]byte // Field 8
+	StatusFlags        [3]byte // Field 9: message status flags
+	BatchNumber        byte    // Field 10
+	Reserved           [3]byte // Field 11
+	UserInfo           byte    // Field 12
+	Bitmap             [2]byte // Field 13: 26-byte header only
+	RejectCode         string  // Field 14: 4 digits, 26-byte header only
+}
+
+// VisaHeaderFieldNames lists the header.<name> keys of a VISA header, in header order
+var VisaHeaderFieldNames = []string{
+	"length", "flag", "text_format", "total_length", "destination_station", "source_station",
+	"round_trip", "base1_flags", "status_flags", "batch_number", "reserved", "user_info",
+	"bitmap", "reject_code",
+}
+
 type VisaHeader struct {
 	mu               sync.RWMutex
 	length           int
-	stationID        [3]byte
 	rawStationID     string
+	fields           VisaHeaderFields // Written with every message
+	peer             VisaHeaderFields // Read with the last message
 	isSessionControl bool
+	maxLength        int // Largest total length accepted; 0 selects MaxMessageLength
 }
 
 func NewVisaHeader(stationIDStr string) (*VisaHeader, error) {
-	parsedID, err := ParseStationID(stationIDStr)
-	if err != nil {
+	if _, err := ParseStationID(stationIDStr); err != nil {
 		return nil, err
 	}
 	return &VisaHeader{
-		stationID:    parsedID,
 		rawStationID: stationIDStr,
+		fields: VisaHeaderFields{
+			HeaderFlag:         0x01,
+			TextFormat:         0x02,
+			DestinationStation: "000000",
+			SourceStation:      stationIDStr,
+		},
 	}, nil
 }
 
+// Clone returns a header with the same station ID, written fields and max length
+func (h *VisaHeader) Clone() *VisaHeader {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return &VisaHeader{
+		rawStationID: h.rawStationID,
+		fields:       h.fields,
+		maxLength:    h.maxLength,
+	}
+}
+
 func ParseStationID(idStr string) ([3]byte, error) {
 	var bytes [3]byte
 	if len(idStr) != 6 {
@@ -64,12 +122,147 @@ func (h *VisaHeader) Length() int {
 	return h.length
 }
 
+// SetMaxLength sets the largest total length (VisaNet header included) the header reads or writes
+func (h *VisaHeader) SetMaxLength(length int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxLength = length
+}
+
+// MaxLength returns the largest total length the header reads or writes
+func (h *VisaHeader) MaxLength() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.maxLength > 0 {
+		return h.maxLength
+	}
+	return MaxMessageLength
+}
+
 func (h *VisaHeader) RawStationID() string {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	return h.rawStationID
 }
 
+// PeerStationID returns the source station ID of the last header read, as 6 digits
+func (h *VisaHeader) PeerStationID() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.peer.SourceStation
+}
+
+// Fields returns the fields written with every message
+func (h *VisaHeader) Fields() VisaHeaderFields {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.fields
+}
+
+// SetFields replaces the fields written with every message. Both length fields are computed on write.
+func (h *VisaHeader) SetFields(fields VisaHeaderFields) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fields = fields
+}
+
+// PeerFields returns the fields of the last header read
+func (h *VisaHeader) PeerFields() VisaHeaderFields {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.peer
+}
+
+// HeaderFields returns the fields of the last header read by header.<name> key, or nil
+// after a session control frame. Binary fields are hex, station IDs and the reject code digits.
+func (h *VisaHeader) HeaderFields() map[string]string {
+	f := h.PeerFields()
+	if f.HeaderLength == 0 {
+		return nil
+	}
+	values := map[string]string{
+		"length":              strconv.Itoa(f.HeaderLength),
+		"flag":                fmt.Sprintf("%02X", f.HeaderFlag),
+		"text_format":         fmt.Sprintf("%02X", f.TextFormat),
+		"total_length":        strconv.Itoa(f.TotalLength),
+		"destination_station": f.DestinationStation,
+		"source_station":      f.SourceStation,
+		"round_trip":          fmt.Sprintf("%02X", f.RoundTrip),
+		"base1_flags":         fmt.Sprintf("%X", f.BaseIFlags),
+		"status_flags":        fmt.Sprintf("%X", f.StatusFlags),
+		"batch_number":        fmt.Sprintf("%02X", f.BatchNumber),
+		"reserved":            fmt.Sprintf("%X", f.Reserved),
+		"user_info":           fmt.Sprintf("%02X", f.UserInfo),
+	}
+	if f.HeaderLength >= VisaRejectHeaderLength {
+		values["bitmap"] = fmt.Sprintf("%X", f.Bitmap)
+		values["reject_code"] = f.RejectCode
+	}
+	return values
+}
+
+// SetHeaderField sets a field of the headers written from now on. Binary fields take hex,
+// station IDs 6 digits. A non-empty reject_code selects the 26-byte header.
+func (h *VisaHeader) SetHeaderField(name, value string) error {
+	width := 0
+	switch name {
+	case "flag", "text_format", "round_trip", "batch_number", "user_info":
+		width = 1
+	case "base1_flags", "bitmap":
+		width = 2
+	case "status_flags", "reserved":
+		width = 3
+	}
+	var b []byte
+	if width > 0 {
+		var err error
+		if b, err = hex.DecodeString(value); err != nil || len(b) != width {
+			return fmt.Errorf("visa header field %s must be %d hex digits, got %q", name, 2*width, value)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f := &h.fields
+	switch name {
+	case "flag":
+		f.HeaderFlag = b[0]
+	case "text_format":
+		f.TextFormat = b[0]
+	case "round_trip":
+		f.RoundTrip = b[0]
+	case "batch_number":
+		f.BatchNumber = b[0]
+	case "user_info":
+		f.UserInfo = b[0]
+	case "base1_flags":
+		copy(f.BaseIFlags[:], b)
+	case "bitmap":
+		copy(f.Bitmap[:], b)
+	case "status_flags":
+		copy(f.StatusFlags[:], b)
+	case "reserved":
+		copy(f.Reserved[:], b)
+	case "destination_station", "source_station":
+		if _, err := ParseStationID(value); err != nil {
+			return fmt.Errorf("visa header field %s: %w", name, err)
+		}
+		if name == "source_station" {
+			f.SourceStation = value
+		} else {
+			f.DestinationStation = value
+		}
+	case "reject_code":
+		if _, err := strconv.Atoi(value); value != "" && (err != nil || len(value) != 4) {
+			return fmt.Errorf("visa header field reject_code must be 4 digits, got %q", value)
+		}
+		f.RejectCode = value
+	default:
+		return fmt.Errorf("unknown or read-only visa header field '%s'", name)
+	}
+	return nil
+}
+
 func (h *VisaHeader) IsSessionControl() bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -85,17 +278,21 @@ func (h *VisaHeader) SetSessionControl(isSessionControl bool) {
 func (h *VisaHeader) WriteTo(w io.Writer) (int, error) {
 	h.mu.RLock()
 	length := h.length
-	stationID := h.stationID
+	fields := h.fields
 	isSessionControl := h.isSessionControl
 	h.mu.RUnlock()
 
-	payloadLen := 22 + length
+	headerLen := VisaHeaderLength
+	if fields.RejectCode != "" {
+		headerLen = VisaRejectHeaderLength
+	}
+	payloadLen := headerLen + length
 	if isSessionControl && length < 22 {
 		payloadLen = length
 	}
 
-	if payloadLen > MaxMessageLength {
-		return 0, fmt.Errorf("length %d exceeds max length %d", payloadLen, MaxMessageLength)
+	if maxLength := h.MaxLength(); payloadLen > maxLength {
+		return 0, fmt.Errorf("length %d exceeds max length %d", payloadLen, maxLength)
 	}
 
 	if isSessionControl && length < 22 {
@@ -107,8 +304,8 @@ func (h *VisaHeader) WriteTo(w io.Writer) (int, error) {
 		return n, err
 	}
 
-	// 4 bytes TCP Header + 22 bytes VisaNet Header
-	buf := make([]byte, 26)
+	// 4 bytes TCP Header + 22 or 26 bytes VisaNet Header
+	buf := make([]byte, 4+headerLen)
 
 	// TCP Header
 	binary.BigEndian.PutUint16(buf[0:2], uint16(payloadLen))
@@ -120,12 +317,9 @@ func (h *VisaHeader) WriteTo(w io.Writer) (int, error) {
 	}
 
 	// VisaNet Header
-	buf[4] = 22                                              // Header Length
-	buf[5] = 0x01                                            // Header Flag
-	buf[6] = 0x02                                            // Text Format
-	binary.BigEndian.PutUint16(buf[7:9], uint16(payloadLen)) // Total Message Length
-
-	copy(buf[12:15], stationID[:]) // Source Station
+	fields.HeaderLength = headerLen
+	fields.TotalLength = payloadLen
+	fields.encode(buf[4:])
 
 	n, err := w.Write(buf)
 	return n, err
@@ -140,8 +334,8 @@ func (h *VisaHeader) ReadFrom(r io.Reader) (int, error) {
 	}
 
 	payloadLen := int(binary.BigEndian.Uint16(tcpHeader[0:2]))
-	if payloadLen > MaxMessageLength {
-		return n, fmt.Errorf("length %d exceeds max length %d", payloadLen, MaxMessageLength)
+	if maxLength := h.MaxLength(); payloadLen > maxLength {
+		return n, fmt.Errorf("length %d exceeds max length %d", payloadLen, maxLength)
 	}
 
 	// Decode message format and platform indicators
@@ -156,6 +350,7 @@ func (h *VisaHeader) ReadFrom(r io.Reader) (int, error) {
 			h.mu.Lock()
 			h.length = payloadLen
 			h.isSessionControl = isSessionCtrl
+			h.peer = VisaHeaderFields{}
 			h.mu.Unlock()
 			return n, nil
 		}
@@ -184,12 +379,60 @@ func (h *VisaHeader) ReadFrom(r io.Reader) (int, error) {
 		if err != nil {
 			return n, fmt.Errorf("reading extra VISA message header bytes: %w", err)
 		}
+		visaHeader = append(visaHeader, extraBuf...)
 	}
 
 	h.mu.Lock()
 	h.length = payloadLen - headerLength
 	h.isSessionControl = isSessionCtrl
+	h.peer = decodeVisaHeaderFields(visaHeader)
 	h.mu.Unlock()
 
 	return n, nil
 }
+
+// encode writes the fields to b, which holds HeaderLength bytes
+func (f VisaHeaderFields) encode(b []byte) {
+	b[0] = byte(f.HeaderLength)
+	b[1] = f.HeaderFlag
+	b[2] = f.TextFormat
+	binary.BigEndian.PutUint16(b[3:5], uint16(f.TotalLength))
+	destination, _ := hex.DecodeString(f.DestinationStation)
+	copy(b[5:8], destination)
+	source, _ := hex.DecodeString(f.SourceStation)
+	copy(b[8:11], source)
+	b[11] = f.RoundTrip
+	copy(b[12:14], f.BaseIFlags[:])
+	copy(b[14:17], f.StatusFlags[:])
+	b[17] = f.BatchNumber
+	copy(b[18:21], f.Reserved[:])
+	b[21] = f.UserInfo
+	if len(b) >= VisaRejectHeaderLength {
+		copy(b[22:24], f.Bitmap[:])
+		code, _ := hex.DecodeString(f.RejectCode)
+		copy(b[24:26], code)
+	}
+}
+
+// decodeVisaHeaderFields parses a VisaNet header of at least 22 bytes
+func decodeVisaHeaderFields(b []byte) VisaHeaderFields {
+	f := VisaHeaderFields{
+		HeaderLength:       int(b[0]),
+		HeaderFlag:         b[1],
+		TextFormat:         b[2],
+		TotalLength:        int(binary.BigEndian.Uint16(b[3:5])),
+		DestinationStation: hex.EncodeToString(b[5:8]),
+		SourceStation:      hex.EncodeToString(b[8:11]),
+		RoundTrip:          b[11],
+		BatchNumber:        b[17],
+		UserInfo:           b[21],
+	}
+	copy(f.BaseIFlags[:], b[12:14])
+	copy(f.StatusFlags[:], b[14:17])
+	copy(f.Reserved[:], b[18:21])
+	if len(b) >= VisaRejectHeaderLength {
+		copy(f.Bitmap[:], b[22:24])
+		f.RejectCode = hex.EncodeToString(b[24:26])
+	}
+	return f
+}
@@ -147,6 +147,10 @@ func TestVisaHeader_ReadFrom(t *testing.T) {
 	if vh.Length() != 15 {
 		t.Errorf("expected message length 15, got %d", vh.Length())
 	}
+
+	if vh.PeerStationID() != "123456" {
+		t.Errorf("expected peer station ID 123456, got %s", vh.PeerStationID())
+	}
 }
 
 func TestVisaHeader_ReadFrom_ExtraHeaderBytes(t *testing.T) {
@@ -259,3 +263,88 @@ func TestVisaHeader_ExceedMaxMessageLength(t *testing.T) {
 		t.Errorf("expected error when payloadLen exceeds MaxMessageLength (2048)")
 	}
 }
+
+func TestVisaHeader_FieldsRoundTrip(t *testing.T) {
+	vh, _ := NewVisaHeader("123456")
+	for name, value := range map[string]string{
+		"destination_station": "000100",
+		"round_trip":          "02",
+		"base1_flags":         "8000",
+		"status_flags":        "000001",
+		"batch_number":        "07",
+		"user_info":           "AA",
+		"reject_code":         "0511",
+	} {
+		if err := vh.SetHeaderField(name, value); err != nil {
+			t.Fatalf("SetHeaderField(%s): %v", name, err)
+		}
+	}
+	vh.SetLength(10)
+
+	var buf bytes.Buffer
+	n, err := vh.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if n != 30 {
+		t.Errorf("expected 30 bytes written for a reject header, got %d", n)
+	}
+
+	read, _ := NewVisaHeader("000000")
+	if _, err := read.ReadFrom(&buf); err != nil {
+		t.Fatalf("ReadFrom failed: %v", err)
+	}
+	if read.Length() != 10 {
+		t.Errorf("expected message length 10, got %d", read.Length())
+	}
+
+	expected := map[string]string{
+		"length":              "26",
+		"flag":                "01",
+		"text_format":         "02",
+		"total_length":        "36",
+		"destination_station": "000100",
+		"source_station":      "123456",
+		"round_trip":          "02",
+		"base1_flags":         "8000",
+		"status_flags":        "000001",
+		"batch_number":        "07",
+		"reserved":            "000000",
+		"user_info":           "AA",
+		"bitmap":              "0000",
+		"reject_code":         "0511",
+	}
+	fields := read.HeaderFields()
+	for name, want := range expected {
+		if fields[name] != want {
+			t.Errorf("header.%s: expected %q, got %q", name, want, fields[name])
+		}
+	}
+	if len(fields) != len(VisaHeaderFieldNames) {
+		t.Errorf("expected %d header fields, got %d", len(VisaHeaderFieldNames), len(fields))
+	}
+}
+
+func TestVisaHeader_SetHeaderFieldErrors(t *testing.T) {
+	vh, _ := NewVisaHeader("000000")
+	for name, value := range map[string]string{
+		"round_trip":     "2",
+		"status_flags":   "0000",
+		"source_station": "12345A",
+		"reject_code":    "51",
+		"total_length":   "40",
+		"unknown":        "00",
+	} {
+		if err := vh.SetHeaderField(name, value); err == nil {
+			t.Errorf("expected error setting %s=%q", name, value)
+		}
+	}
+
+	// Clearing the reject code returns to the 22-byte header
+	_ = vh.SetHeaderField("reject_code", "0511")
+	_ = vh.SetHeaderField("reject_code", "")
+	var buf bytes.Buffer
+	if n, _ := vh.WriteTo(&buf); n != 26 {
+		t.Errorf("expected 26 bytes written, got %d", n)
+	}
+}